@@ -39,12 +39,25 @@ type Invoker interface {
 	//
 	// DELETE /projects/{project}
 	DeleteProject(ctx context.Context, params DeleteProjectParams) error
+	// DeleteProjectAlias invokes deleteProjectAlias operation.
+	//
+	// Stop accepting previous project slug.
+	//
+	// DELETE /projects/{project}/aliases/{alias}
+	DeleteProjectAlias(ctx context.Context, params DeleteProjectAliasParams) error
 	// DeleteToken invokes deleteToken operation.
 	//
 	// Delete token for user.
 	//
 	// DELETE /tokens/{token}
 	DeleteToken(ctx context.Context, params DeleteTokenParams) error
+	// ExportDocument invokes exportDocument operation.
+	//
+	// Export projects and tokens of the user as a versioned document (for backups and cloning
+	// environments). Secrets are never exported.
+	//
+	// GET /export
+	ExportDocument(ctx context.Context, params ExportDocumentParams) (*Document, error)
 	// GetProject invokes getProject operation.
 	//
 	// Get project by ID.
@@ -57,6 +70,34 @@ type Invoker interface {
 	//
 	// GET /tokens/{token}
 	GetToken(ctx context.Context, params GetTokenParams) (*Token, error)
+	// GetTokenByKey invokes getTokenByKey operation.
+	//
+	// Get token by key ID and for the current user.
+	//
+	// GET /tokens/by-key/{keyID}
+	GetTokenByKey(ctx context.Context, params GetTokenByKeyParams) (*Token, error)
+	// IdentifyToken invokes identifyToken operation.
+	//
+	// Identify token by full raw key. The key is checked against the stored hash and only tokens of the
+	// current user are reported.
+	//
+	// POST /tokens/identify
+	IdentifyToken(ctx context.Context, request *KeyLookup) (*TokenIdentity, error)
+	// ImportDocument invokes importDocument operation.
+	//
+	// Import projects and tokens from the exported document. Owners in the document are replaced by the
+	// user. The document is validated as a whole before any change. Tokens are identified by key ID;
+	// tokens without hash get a new key with the same key ID.
+	//
+	// POST /import
+	ImportDocument(ctx context.Context, request *Document, params ImportDocumentParams) (*ImportReport, error)
+	// ImportTokens invokes importTokens operation.
+	//
+	// Import existing keys (for example, from another gateway) instead of generating new ones. Each key is
+	// imported independently; results are returned in the same order as the request items.
+	//
+	// POST /tokens/import
+	ImportTokens(ctx context.Context, request []ImportKey) ([]ImportResult, error)
 	// ListProjects invokes listProjects operation.
 	//
 	// List all projects.
@@ -68,10 +109,10 @@ type Invoker interface {
 	// List all tokens for user.
 	//
 	// GET /tokens
-	ListTokens(ctx context.Context, params ListTokensParams) ([]Token, error)
+	ListTokens(ctx context.Context, params ListTokensParams) (*ListTokensOKHeaders, error)
 	// RefreshToken invokes refreshToken operation.
 	//
-	// Regenerate token key.
+	// Regenerate token key. Enables disabled token.
 	//
 	// POST /tokens/{token}
 	RefreshToken(ctx context.Context, params RefreshTokenParams) (*Credential, error)
@@ -281,6 +322,86 @@ func (c *Client) sendDeleteProject(ctx context.Context, params DeleteProjectPara
 	return result, nil
 }
 
+// DeleteProjectAlias invokes deleteProjectAlias operation.
+//
+// Stop accepting previous project slug.
+//
+// DELETE /projects/{project}/aliases/{alias}
+func (c *Client) DeleteProjectAlias(ctx context.Context, params DeleteProjectAliasParams) error {
+	_, err := c.sendDeleteProjectAlias(ctx, params)
+	return err
+}
+
+func (c *Client) sendDeleteProjectAlias(ctx context.Context, params DeleteProjectAliasParams) (res *DeleteProjectAliasNoContent, err error) {
+
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [4]string
+	pathParts[0] = "/projects/"
+	{
+		// Encode "project" parameter.
+		e := uri.NewPathEncoder(uri.PathEncoderConfig{
+			Param:   "project",
+			Style:   uri.PathStyleSimple,
+			Explode: false,
+		})
+		if err := func() error {
+			return e.EncodeValue(conv.IntToString(params.Project))
+		}(); err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		encoded, err := e.Result()
+		if err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		pathParts[1] = encoded
+	}
+	pathParts[2] = "/aliases/"
+	{
+		// Encode "alias" parameter.
+		e := uri.NewPathEncoder(uri.PathEncoderConfig{
+			Param:   "alias",
+			Style:   uri.PathStyleSimple,
+			Explode: false,
+		})
+		if err := func() error {
+			return e.EncodeValue(conv.StringToString(params.Alias))
+		}(); err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		encoded, err := e.Result()
+		if err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		pathParts[3] = encoded
+	}
+	uri.AddPathParts(u, pathParts[:]...)
+
+	r, err := ht.NewRequest(ctx, "DELETE", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	result, err := decodeDeleteProjectAliasResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
 // DeleteToken invokes deleteToken operation.
 //
 // Delete token for user.
@@ -342,6 +463,70 @@ func (c *Client) sendDeleteToken(ctx context.Context, params DeleteTokenParams)
 	return result, nil
 }
 
+// ExportDocument invokes exportDocument operation.
+//
+// Export projects and tokens of the user as a versioned document (for backups and cloning
+// environments). Secrets are never exported.
+//
+// GET /export
+func (c *Client) ExportDocument(ctx context.Context, params ExportDocumentParams) (*Document, error) {
+	res, err := c.sendExportDocument(ctx, params)
+	return res, err
+}
+
+func (c *Client) sendExportDocument(ctx context.Context, params ExportDocumentParams) (res *Document, err error) {
+
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [1]string
+	pathParts[0] = "/export"
+	uri.AddPathParts(u, pathParts[:]...)
+
+	q := uri.NewQueryEncoder()
+	{
+		// Encode "hashes" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "hashes",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Hashes.Get(); ok {
+				return e.EncodeValue(conv.BoolToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	u.RawQuery = q.Values().Encode()
+
+	r, err := ht.NewRequest(ctx, "GET", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	result, err := decodeExportDocumentResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
 // GetProject invokes getProject operation.
 //
 // Get project by ID.
@@ -464,6 +649,246 @@ func (c *Client) sendGetToken(ctx context.Context, params GetTokenParams) (res *
 	return result, nil
 }
 
+// GetTokenByKey invokes getTokenByKey operation.
+//
+// Get token by key ID and for the current user.
+//
+// GET /tokens/by-key/{keyID}
+func (c *Client) GetTokenByKey(ctx context.Context, params GetTokenByKeyParams) (*Token, error) {
+	res, err := c.sendGetTokenByKey(ctx, params)
+	return res, err
+}
+
+func (c *Client) sendGetTokenByKey(ctx context.Context, params GetTokenByKeyParams) (res *Token, err error) {
+
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [2]string
+	pathParts[0] = "/tokens/by-key/"
+	{
+		// Encode "keyID" parameter.
+		e := uri.NewPathEncoder(uri.PathEncoderConfig{
+			Param:   "keyID",
+			Style:   uri.PathStyleSimple,
+			Explode: false,
+		})
+		if err := func() error {
+			return e.EncodeValue(conv.StringToString(params.KeyID))
+		}(); err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		encoded, err := e.Result()
+		if err != nil {
+			return res, errors.Wrap(err, "encode path")
+		}
+		pathParts[1] = encoded
+	}
+	uri.AddPathParts(u, pathParts[:]...)
+
+	r, err := ht.NewRequest(ctx, "GET", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	result, err := decodeGetTokenByKeyResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
+// IdentifyToken invokes identifyToken operation.
+//
+// Identify token by full raw key. The key is checked against the stored hash and only tokens of the
+// current user are reported.
+//
+// POST /tokens/identify
+func (c *Client) IdentifyToken(ctx context.Context, request *KeyLookup) (*TokenIdentity, error) {
+	res, err := c.sendIdentifyToken(ctx, request)
+	return res, err
+}
+
+func (c *Client) sendIdentifyToken(ctx context.Context, request *KeyLookup) (res *TokenIdentity, err error) {
+
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [1]string
+	pathParts[0] = "/tokens/identify"
+	uri.AddPathParts(u, pathParts[:]...)
+
+	r, err := ht.NewRequest(ctx, "POST", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+	if err := encodeIdentifyTokenRequest(request, r); err != nil {
+		return res, errors.Wrap(err, "encode request")
+	}
+
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	result, err := decodeIdentifyTokenResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
+// ImportDocument invokes importDocument operation.
+//
+// Import projects and tokens from the exported document. Owners in the document are replaced by the
+// user. The document is validated as a whole before any change. Tokens are identified by key ID;
+// tokens without hash get a new key with the same key ID.
+//
+// POST /import
+func (c *Client) ImportDocument(ctx context.Context, request *Document, params ImportDocumentParams) (*ImportReport, error) {
+	res, err := c.sendImportDocument(ctx, request, params)
+	return res, err
+}
+
+func (c *Client) sendImportDocument(ctx context.Context, request *Document, params ImportDocumentParams) (res *ImportReport, err error) {
+
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [1]string
+	pathParts[0] = "/import"
+	uri.AddPathParts(u, pathParts[:]...)
+
+	q := uri.NewQueryEncoder()
+	{
+		// Encode "dryRun" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "dryRun",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.DryRun.Get(); ok {
+				return e.EncodeValue(conv.BoolToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "conflict" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "conflict",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Conflict.Get(); ok {
+				return e.EncodeValue(conv.StringToString(string(val)))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	u.RawQuery = q.Values().Encode()
+
+	r, err := ht.NewRequest(ctx, "POST", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+	if err := encodeImportDocumentRequest(request, r); err != nil {
+		return res, errors.Wrap(err, "encode request")
+	}
+
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	result, err := decodeImportDocumentResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
+// ImportTokens invokes importTokens operation.
+//
+// Import existing keys (for example, from another gateway) instead of generating new ones. Each key is
+// imported independently; results are returned in the same order as the request items.
+//
+// POST /tokens/import
+func (c *Client) ImportTokens(ctx context.Context, request []ImportKey) ([]ImportResult, error) {
+	res, err := c.sendImportTokens(ctx, request)
+	return res, err
+}
+
+func (c *Client) sendImportTokens(ctx context.Context, request []ImportKey) (res []ImportResult, err error) {
+
+	u := uri.Clone(c.requestURL(ctx))
+	var pathParts [1]string
+	pathParts[0] = "/tokens/import"
+	uri.AddPathParts(u, pathParts[:]...)
+
+	r, err := ht.NewRequest(ctx, "POST", u)
+	if err != nil {
+		return res, errors.Wrap(err, "create request")
+	}
+	if err := encodeImportTokensRequest(request, r); err != nil {
+		return res, errors.Wrap(err, "encode request")
+	}
+
+	resp, err := c.cfg.Client.Do(r)
+	if err != nil {
+		return res, errors.Wrap(err, "do request")
+	}
+	body := resp.Body
+	defer func() {
+		// Drain the body to EOF before closing, so the underlying
+		// connection can be reused by the Transport regardless of the
+		// response status code. See https://github.com/ogen-go/ogen/issues/1670.
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}()
+
+	result, err := decodeImportTokensResponse(resp)
+	if err != nil {
+		return res, errors.Wrap(err, "decode response")
+	}
+
+	return result, nil
+}
+
 // ListProjects invokes listProjects operation.
 //
 // List all projects.
@@ -512,12 +937,12 @@ func (c *Client) sendListProjects(ctx context.Context) (res []Project, err error
 // List all tokens for user.
 //
 // GET /tokens
-func (c *Client) ListTokens(ctx context.Context, params ListTokensParams) ([]Token, error) {
+func (c *Client) ListTokens(ctx context.Context, params ListTokensParams) (*ListTokensOKHeaders, error) {
 	res, err := c.sendListTokens(ctx, params)
 	return res, err
 }
 
-func (c *Client) sendListTokens(ctx context.Context, params ListTokensParams) (res []Token, err error) {
+func (c *Client) sendListTokens(ctx context.Context, params ListTokensParams) (res *ListTokensOKHeaders, err error) {
 
 	u := uri.Clone(c.requestURL(ctx))
 	var pathParts [1]string
@@ -542,6 +967,202 @@ func (c *Client) sendListTokens(ctx context.Context, params ListTokensParams) (r
 			return res, errors.Wrap(err, "encode query")
 		}
 	}
+	{
+		// Encode "meta" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "meta",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if params.Meta != nil {
+				return e.EncodeArray(func(e uri.Encoder) error {
+					for i, item := range params.Meta {
+						if err := func() error {
+							return e.EncodeValue(conv.StringToString(item))
+						}(); err != nil {
+							return errors.Wrapf(err, "[%d]", i)
+						}
+					}
+					return nil
+				})
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "q" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "q",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Q.Get(); ok {
+				return e.EncodeValue(conv.StringToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "host" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "host",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Host.Get(); ok {
+				return e.EncodeValue(conv.StringToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "accessedAfter" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "accessedAfter",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.AccessedAfter.Get(); ok {
+				return e.EncodeValue(conv.DateTimeToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "accessedBefore" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "accessedBefore",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.AccessedBefore.Get(); ok {
+				return e.EncodeValue(conv.DateTimeToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "unusedSince" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "unusedSince",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.UnusedSince.Get(); ok {
+				return e.EncodeValue(conv.DateTimeToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "disabled" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "disabled",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Disabled.Get(); ok {
+				return e.EncodeValue(conv.BoolToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "sort" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "sort",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Sort.Get(); ok {
+				return e.EncodeValue(conv.StringToString(string(val)))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "order" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "order",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Order.Get(); ok {
+				return e.EncodeValue(conv.StringToString(string(val)))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "limit" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "limit",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Limit.Get(); ok {
+				return e.EncodeValue(conv.IntToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
+	{
+		// Encode "cursor" parameter.
+		cfg := uri.QueryParameterEncodingConfig{
+			Name:    "cursor",
+			Style:   uri.QueryStyleForm,
+			Explode: true,
+		}
+
+		if err := q.EncodeParam(cfg, func(e uri.Encoder) error {
+			if val, ok := params.Cursor.Get(); ok {
+				return e.EncodeValue(conv.StringToString(val))
+			}
+			return nil
+		}); err != nil {
+			return res, errors.Wrap(err, "encode query")
+		}
+	}
 	u.RawQuery = q.Values().Encode()
 
 	r, err := ht.NewRequest(ctx, "GET", u)
@@ -572,7 +1193,7 @@ func (c *Client) sendListTokens(ctx context.Context, params ListTokensParams) (r
 
 // RefreshToken invokes refreshToken operation.
 //
-// Regenerate token key.
+// Regenerate token key. Enables disabled token.
 //
 // POST /tokens/{token}
 func (c *Client) RefreshToken(ctx context.Context, params RefreshTokenParams) (*Credential, error) {
@@ -280,6 +280,94 @@ func (s *Server) handleDeleteProjectRequest(args [1]string, argsEscaped bool, w
 	}
 }
 
+// handleDeleteProjectAliasRequest handles deleteProjectAlias operation.
+//
+// Stop accepting previous project slug.
+//
+// DELETE /projects/{project}/aliases/{alias}
+func (s *Server) handleDeleteProjectAliasRequest(args [2]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
+	statusWriter := &codeRecorder{ResponseWriter: w}
+	w = statusWriter
+	ctx := r.Context()
+
+	var (
+		err          error
+		opErrContext = ogenerrors.OperationContext{
+			Name: DeleteProjectAliasOperation,
+			ID:   "deleteProjectAlias",
+		}
+	)
+	params, err := decodeDeleteProjectAliasParams(args, argsEscaped, r)
+	if err != nil {
+		err = &ogenerrors.DecodeParamsError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeParams", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	var rawBody []byte
+
+	var response *DeleteProjectAliasNoContent
+	if m := s.cfg.Middleware; m != nil {
+		mreq := middleware.Request{
+			Context:          ctx,
+			OperationName:    DeleteProjectAliasOperation,
+			OperationSummary: "",
+			OperationID:      "deleteProjectAlias",
+			Body:             nil,
+			RawBody:          rawBody,
+			Params: middleware.Parameters{
+				{
+					Name: "project",
+					In:   "path",
+				}: params.Project,
+				{
+					Name: "alias",
+					In:   "path",
+				}: params.Alias,
+			},
+			Raw: r,
+		}
+
+		type (
+			Request  = struct{}
+			Params   = DeleteProjectAliasParams
+			Response = *DeleteProjectAliasNoContent
+		)
+		response, err = middleware.HookMiddleware[
+			Request,
+			Params,
+			Response,
+		](
+			m,
+			mreq,
+			unpackDeleteProjectAliasParams,
+			func(ctx context.Context, request Request, params Params) (response Response, err error) {
+				err = s.h.DeleteProjectAlias(ctx, params)
+				return response, err
+			},
+		)
+	} else {
+		err = s.h.DeleteProjectAlias(ctx, params)
+	}
+	if err != nil {
+		defer recordError("Internal", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	if err := encodeDeleteProjectAliasResponse(response, w); err != nil {
+		defer recordError("EncodeResponse", err)
+		if !errors.Is(err, ht.ErrInternalServerErrorResponse) {
+			s.cfg.ErrorHandler(ctx, w, r, err)
+		}
+		return
+	}
+}
+
 // handleDeleteTokenRequest handles deleteToken operation.
 //
 // Delete token for user.
@@ -364,6 +452,91 @@ func (s *Server) handleDeleteTokenRequest(args [1]string, argsEscaped bool, w ht
 	}
 }
 
+// handleExportDocumentRequest handles exportDocument operation.
+//
+// Export projects and tokens of the user as a versioned document (for backups and cloning
+// environments). Secrets are never exported.
+//
+// GET /export
+func (s *Server) handleExportDocumentRequest(args [0]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
+	statusWriter := &codeRecorder{ResponseWriter: w}
+	w = statusWriter
+	ctx := r.Context()
+
+	var (
+		err          error
+		opErrContext = ogenerrors.OperationContext{
+			Name: ExportDocumentOperation,
+			ID:   "exportDocument",
+		}
+	)
+	params, err := decodeExportDocumentParams(args, argsEscaped, r)
+	if err != nil {
+		err = &ogenerrors.DecodeParamsError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeParams", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	var rawBody []byte
+
+	var response *Document
+	if m := s.cfg.Middleware; m != nil {
+		mreq := middleware.Request{
+			Context:          ctx,
+			OperationName:    ExportDocumentOperation,
+			OperationSummary: "",
+			OperationID:      "exportDocument",
+			Body:             nil,
+			RawBody:          rawBody,
+			Params: middleware.Parameters{
+				{
+					Name: "hashes",
+					In:   "query",
+				}: params.Hashes,
+			},
+			Raw: r,
+		}
+
+		type (
+			Request  = struct{}
+			Params   = ExportDocumentParams
+			Response = *Document
+		)
+		response, err = middleware.HookMiddleware[
+			Request,
+			Params,
+			Response,
+		](
+			m,
+			mreq,
+			unpackExportDocumentParams,
+			func(ctx context.Context, request Request, params Params) (response Response, err error) {
+				response, err = s.h.ExportDocument(ctx, params)
+				return response, err
+			},
+		)
+	} else {
+		response, err = s.h.ExportDocument(ctx, params)
+	}
+	if err != nil {
+		defer recordError("Internal", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	if err := encodeExportDocumentResponse(response, w); err != nil {
+		defer recordError("EncodeResponse", err)
+		if !errors.Is(err, ht.ErrInternalServerErrorResponse) {
+			s.cfg.ErrorHandler(ctx, w, r, err)
+		}
+		return
+	}
+}
+
 // handleGetProjectRequest handles getProject operation.
 //
 // Get project by ID.
@@ -532,6 +705,365 @@ func (s *Server) handleGetTokenRequest(args [1]string, argsEscaped bool, w http.
 	}
 }
 
+// handleGetTokenByKeyRequest handles getTokenByKey operation.
+//
+// Get token by key ID and for the current user.
+//
+// GET /tokens/by-key/{keyID}
+func (s *Server) handleGetTokenByKeyRequest(args [1]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
+	statusWriter := &codeRecorder{ResponseWriter: w}
+	w = statusWriter
+	ctx := r.Context()
+
+	var (
+		err          error
+		opErrContext = ogenerrors.OperationContext{
+			Name: GetTokenByKeyOperation,
+			ID:   "getTokenByKey",
+		}
+	)
+	params, err := decodeGetTokenByKeyParams(args, argsEscaped, r)
+	if err != nil {
+		err = &ogenerrors.DecodeParamsError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeParams", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	var rawBody []byte
+
+	var response *Token
+	if m := s.cfg.Middleware; m != nil {
+		mreq := middleware.Request{
+			Context:          ctx,
+			OperationName:    GetTokenByKeyOperation,
+			OperationSummary: "",
+			OperationID:      "getTokenByKey",
+			Body:             nil,
+			RawBody:          rawBody,
+			Params: middleware.Parameters{
+				{
+					Name: "keyID",
+					In:   "path",
+				}: params.KeyID,
+			},
+			Raw: r,
+		}
+
+		type (
+			Request  = struct{}
+			Params   = GetTokenByKeyParams
+			Response = *Token
+		)
+		response, err = middleware.HookMiddleware[
+			Request,
+			Params,
+			Response,
+		](
+			m,
+			mreq,
+			unpackGetTokenByKeyParams,
+			func(ctx context.Context, request Request, params Params) (response Response, err error) {
+				response, err = s.h.GetTokenByKey(ctx, params)
+				return response, err
+			},
+		)
+	} else {
+		response, err = s.h.GetTokenByKey(ctx, params)
+	}
+	if err != nil {
+		defer recordError("Internal", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	if err := encodeGetTokenByKeyResponse(response, w); err != nil {
+		defer recordError("EncodeResponse", err)
+		if !errors.Is(err, ht.ErrInternalServerErrorResponse) {
+			s.cfg.ErrorHandler(ctx, w, r, err)
+		}
+		return
+	}
+}
+
+// handleIdentifyTokenRequest handles identifyToken operation.
+//
+// Identify token by full raw key. The key is checked against the stored hash and only tokens of the
+// current user are reported.
+//
+// POST /tokens/identify
+func (s *Server) handleIdentifyTokenRequest(args [0]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
+	statusWriter := &codeRecorder{ResponseWriter: w}
+	w = statusWriter
+	ctx := r.Context()
+
+	var (
+		err          error
+		opErrContext = ogenerrors.OperationContext{
+			Name: IdentifyTokenOperation,
+			ID:   "identifyToken",
+		}
+	)
+
+	var rawBody []byte
+	request, rawBody, close, err := s.decodeIdentifyTokenRequest(r)
+	if err != nil {
+		err = &ogenerrors.DecodeRequestError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeRequest", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+	defer func() {
+		if err := close(); err != nil {
+			recordError("CloseRequest", err)
+		}
+	}()
+
+	var response *TokenIdentity
+	if m := s.cfg.Middleware; m != nil {
+		mreq := middleware.Request{
+			Context:          ctx,
+			OperationName:    IdentifyTokenOperation,
+			OperationSummary: "",
+			OperationID:      "identifyToken",
+			Body:             request,
+			RawBody:          rawBody,
+			Params:           middleware.Parameters{},
+			Raw:              r,
+		}
+
+		type (
+			Request  = *KeyLookup
+			Params   = struct{}
+			Response = *TokenIdentity
+		)
+		response, err = middleware.HookMiddleware[
+			Request,
+			Params,
+			Response,
+		](
+			m,
+			mreq,
+			nil,
+			func(ctx context.Context, request Request, params Params) (response Response, err error) {
+				response, err = s.h.IdentifyToken(ctx, request)
+				return response, err
+			},
+		)
+	} else {
+		response, err = s.h.IdentifyToken(ctx, request)
+	}
+	if err != nil {
+		defer recordError("Internal", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	if err := encodeIdentifyTokenResponse(response, w); err != nil {
+		defer recordError("EncodeResponse", err)
+		if !errors.Is(err, ht.ErrInternalServerErrorResponse) {
+			s.cfg.ErrorHandler(ctx, w, r, err)
+		}
+		return
+	}
+}
+
+// handleImportDocumentRequest handles importDocument operation.
+//
+// Import projects and tokens from the exported document. Owners in the document are replaced by the
+// user. The document is validated as a whole before any change. Tokens are identified by key ID;
+// tokens without hash get a new key with the same key ID.
+//
+// POST /import
+func (s *Server) handleImportDocumentRequest(args [0]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
+	statusWriter := &codeRecorder{ResponseWriter: w}
+	w = statusWriter
+	ctx := r.Context()
+
+	var (
+		err          error
+		opErrContext = ogenerrors.OperationContext{
+			Name: ImportDocumentOperation,
+			ID:   "importDocument",
+		}
+	)
+	params, err := decodeImportDocumentParams(args, argsEscaped, r)
+	if err != nil {
+		err = &ogenerrors.DecodeParamsError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeParams", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	var rawBody []byte
+	request, rawBody, close, err := s.decodeImportDocumentRequest(r)
+	if err != nil {
+		err = &ogenerrors.DecodeRequestError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeRequest", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+	defer func() {
+		if err := close(); err != nil {
+			recordError("CloseRequest", err)
+		}
+	}()
+
+	var response *ImportReport
+	if m := s.cfg.Middleware; m != nil {
+		mreq := middleware.Request{
+			Context:          ctx,
+			OperationName:    ImportDocumentOperation,
+			OperationSummary: "",
+			OperationID:      "importDocument",
+			Body:             request,
+			RawBody:          rawBody,
+			Params: middleware.Parameters{
+				{
+					Name: "dryRun",
+					In:   "query",
+				}: params.DryRun,
+				{
+					Name: "conflict",
+					In:   "query",
+				}: params.Conflict,
+			},
+			Raw: r,
+		}
+
+		type (
+			Request  = *Document
+			Params   = ImportDocumentParams
+			Response = *ImportReport
+		)
+		response, err = middleware.HookMiddleware[
+			Request,
+			Params,
+			Response,
+		](
+			m,
+			mreq,
+			unpackImportDocumentParams,
+			func(ctx context.Context, request Request, params Params) (response Response, err error) {
+				response, err = s.h.ImportDocument(ctx, request, params)
+				return response, err
+			},
+		)
+	} else {
+		response, err = s.h.ImportDocument(ctx, request, params)
+	}
+	if err != nil {
+		defer recordError("Internal", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	if err := encodeImportDocumentResponse(response, w); err != nil {
+		defer recordError("EncodeResponse", err)
+		if !errors.Is(err, ht.ErrInternalServerErrorResponse) {
+			s.cfg.ErrorHandler(ctx, w, r, err)
+		}
+		return
+	}
+}
+
+// handleImportTokensRequest handles importTokens operation.
+//
+// Import existing keys (for example, from another gateway) instead of generating new ones. Each key is
+// imported independently; results are returned in the same order as the request items.
+//
+// POST /tokens/import
+func (s *Server) handleImportTokensRequest(args [0]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
+	statusWriter := &codeRecorder{ResponseWriter: w}
+	w = statusWriter
+	ctx := r.Context()
+
+	var (
+		err          error
+		opErrContext = ogenerrors.OperationContext{
+			Name: ImportTokensOperation,
+			ID:   "importTokens",
+		}
+	)
+
+	var rawBody []byte
+	request, rawBody, close, err := s.decodeImportTokensRequest(r)
+	if err != nil {
+		err = &ogenerrors.DecodeRequestError{
+			OperationContext: opErrContext,
+			Err:              err,
+		}
+		defer recordError("DecodeRequest", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+	defer func() {
+		if err := close(); err != nil {
+			recordError("CloseRequest", err)
+		}
+	}()
+
+	var response []ImportResult
+	if m := s.cfg.Middleware; m != nil {
+		mreq := middleware.Request{
+			Context:          ctx,
+			OperationName:    ImportTokensOperation,
+			OperationSummary: "",
+			OperationID:      "importTokens",
+			Body:             request,
+			RawBody:          rawBody,
+			Params:           middleware.Parameters{},
+			Raw:              r,
+		}
+
+		type (
+			Request  = []ImportKey
+			Params   = struct{}
+			Response = []ImportResult
+		)
+		response, err = middleware.HookMiddleware[
+			Request,
+			Params,
+			Response,
+		](
+			m,
+			mreq,
+			nil,
+			func(ctx context.Context, request Request, params Params) (response Response, err error) {
+				response, err = s.h.ImportTokens(ctx, request)
+				return response, err
+			},
+		)
+	} else {
+		response, err = s.h.ImportTokens(ctx, request)
+	}
+	if err != nil {
+		defer recordError("Internal", err)
+		s.cfg.ErrorHandler(ctx, w, r, err)
+		return
+	}
+
+	if err := encodeImportTokensResponse(response, w); err != nil {
+		defer recordError("EncodeResponse", err)
+		if !errors.Is(err, ht.ErrInternalServerErrorResponse) {
+			s.cfg.ErrorHandler(ctx, w, r, err)
+		}
+		return
+	}
+}
+
 // handleListProjectsRequest handles listProjects operation.
 //
 // List all projects.
@@ -627,7 +1159,7 @@ func (s *Server) handleListTokensRequest(args [0]string, argsEscaped bool, w htt
 
 	var rawBody []byte
 
-	var response []Token
+	var response *ListTokensOKHeaders
 	if m := s.cfg.Middleware; m != nil {
 		mreq := middleware.Request{
 			Context:          ctx,
@@ -641,6 +1173,50 @@ func (s *Server) handleListTokensRequest(args [0]string, argsEscaped bool, w htt
 					Name: "project",
 					In:   "query",
 				}: params.Project,
+				{
+					Name: "meta",
+					In:   "query",
+				}: params.Meta,
+				{
+					Name: "q",
+					In:   "query",
+				}: params.Q,
+				{
+					Name: "host",
+					In:   "query",
+				}: params.Host,
+				{
+					Name: "accessedAfter",
+					In:   "query",
+				}: params.AccessedAfter,
+				{
+					Name: "accessedBefore",
+					In:   "query",
+				}: params.AccessedBefore,
+				{
+					Name: "unusedSince",
+					In:   "query",
+				}: params.UnusedSince,
+				{
+					Name: "disabled",
+					In:   "query",
+				}: params.Disabled,
+				{
+					Name: "sort",
+					In:   "query",
+				}: params.Sort,
+				{
+					Name: "order",
+					In:   "query",
+				}: params.Order,
+				{
+					Name: "limit",
+					In:   "query",
+				}: params.Limit,
+				{
+					Name: "cursor",
+					In:   "query",
+				}: params.Cursor,
 			},
 			Raw: r,
 		}
@@ -648,7 +1224,7 @@ func (s *Server) handleListTokensRequest(args [0]string, argsEscaped bool, w htt
 		type (
 			Request  = struct{}
 			Params   = ListTokensParams
-			Response = []Token
+			Response = *ListTokensOKHeaders
 		)
 		response, err = middleware.HookMiddleware[
 			Request,
@@ -683,7 +1259,7 @@ func (s *Server) handleListTokensRequest(args [0]string, argsEscaped bool, w htt
 
 // handleRefreshTokenRequest handles refreshToken operation.
 //
-// Regenerate token key.
+// Regenerate token key. Enables disabled token.
 //
 // POST /tokens/{token}
 func (s *Server) handleRefreshTokenRequest(args [1]string, argsEscaped bool, w http.ResponseWriter, r *http.Request) {
@@ -6,15 +6,21 @@ package api
 type OperationName = string
 
 const (
-	CreateProjectOperation OperationName = "CreateProject"
-	CreateTokenOperation   OperationName = "CreateToken"
-	DeleteProjectOperation OperationName = "DeleteProject"
-	DeleteTokenOperation   OperationName = "DeleteToken"
-	GetProjectOperation    OperationName = "GetProject"
-	GetTokenOperation      OperationName = "GetToken"
-	ListProjectsOperation  OperationName = "ListProjects"
-	ListTokensOperation    OperationName = "ListTokens"
-	RefreshTokenOperation  OperationName = "RefreshToken"
-	UpdateProjectOperation OperationName = "UpdateProject"
-	UpdateTokenOperation   OperationName = "UpdateToken"
+	CreateProjectOperation      OperationName = "CreateProject"
+	CreateTokenOperation        OperationName = "CreateToken"
+	DeleteProjectOperation      OperationName = "DeleteProject"
+	DeleteProjectAliasOperation OperationName = "DeleteProjectAlias"
+	DeleteTokenOperation        OperationName = "DeleteToken"
+	ExportDocumentOperation     OperationName = "ExportDocument"
+	GetProjectOperation         OperationName = "GetProject"
+	GetTokenOperation           OperationName = "GetToken"
+	GetTokenByKeyOperation      OperationName = "GetTokenByKey"
+	IdentifyTokenOperation      OperationName = "IdentifyToken"
+	ImportDocumentOperation     OperationName = "ImportDocument"
+	ImportTokensOperation       OperationName = "ImportTokens"
+	ListProjectsOperation       OperationName = "ListProjects"
+	ListTokensOperation         OperationName = "ListTokens"
+	RefreshTokenOperation       OperationName = "RefreshToken"
+	UpdateProjectOperation      OperationName = "UpdateProject"
+	UpdateTokenOperation        OperationName = "UpdateToken"
 )
@@ -4,6 +4,8 @@ package api
 
 import (
 	"time"
+
+	"github.com/go-faster/errors"
 )
 
 // Ref: #/components/schemas/Credential
@@ -34,70 +36,1423 @@ func (s *Credential) SetKey(val string) {
 	s.Key = val
 }
 
+// DeleteProjectAliasNoContent is response for DeleteProjectAlias operation.
+type DeleteProjectAliasNoContent struct{}
+
 // DeleteProjectNoContent is response for DeleteProject operation.
 type DeleteProjectNoContent struct{}
 
 // DeleteTokenNoContent is response for DeleteToken operation.
 type DeleteTokenNoContent struct{}
 
-// Ref: #/components/schemas/NameValue
-type NameValue struct {
-	Name  string `json:"name"`
+// Ref: #/components/schemas/Document
+type Document struct {
+	// Document format version.
+	Version    int               `json:"version"`
+	ExportedAt OptDateTime       `json:"exportedAt"`
+	Projects   []DocumentProject `json:"projects"`
+	Tokens     []DocumentToken   `json:"tokens"`
+}
+
+// GetVersion returns the value of Version.
+func (s *Document) GetVersion() int {
+	return s.Version
+}
+
+// GetExportedAt returns the value of ExportedAt.
+func (s *Document) GetExportedAt() OptDateTime {
+	return s.ExportedAt
+}
+
+// GetProjects returns the value of Projects.
+func (s *Document) GetProjects() []DocumentProject {
+	return s.Projects
+}
+
+// GetTokens returns the value of Tokens.
+func (s *Document) GetTokens() []DocumentToken {
+	return s.Tokens
+}
+
+// SetVersion sets the value of Version.
+func (s *Document) SetVersion(val int) {
+	s.Version = val
+}
+
+// SetExportedAt sets the value of ExportedAt.
+func (s *Document) SetExportedAt(val OptDateTime) {
+	s.ExportedAt = val
+}
+
+// SetProjects sets the value of Projects.
+func (s *Document) SetProjects(val []DocumentProject) {
+	s.Projects = val
+}
+
+// SetTokens sets the value of Tokens.
+func (s *Document) SetTokens(val []DocumentToken) {
+	s.Tokens = val
+}
+
+// Token is disabled (for example, reported as leaked). Stays disabled after import unless it gets a
+// new key.
+// Ref: #/components/schemas/DocumentDisabled
+type DocumentDisabled struct {
+	At     time.Time `json:"at"`
+	Reason OptString `json:"reason"`
+}
+
+// GetAt returns the value of At.
+func (s *DocumentDisabled) GetAt() time.Time {
+	return s.At
+}
+
+// GetReason returns the value of Reason.
+func (s *DocumentDisabled) GetReason() OptString {
+	return s.Reason
+}
+
+// SetAt sets the value of At.
+func (s *DocumentDisabled) SetAt(val time.Time) {
+	s.At = val
+}
+
+// SetReason sets the value of Reason.
+func (s *DocumentDisabled) SetReason(val OptString) {
+	s.Reason = val
+}
+
+// Ref: #/components/schemas/DocumentHash
+type DocumentHash struct {
+	Algorithm string    `json:"algorithm"`
+	PepperID  OptString `json:"pepperID"`
+	// Hex-encoded hash.
 	Value string `json:"value"`
 }
 
-// GetName returns the value of Name.
-func (s *NameValue) GetName() string {
-	return s.Name
+// GetAlgorithm returns the value of Algorithm.
+func (s *DocumentHash) GetAlgorithm() string {
+	return s.Algorithm
+}
+
+// GetPepperID returns the value of PepperID.
+func (s *DocumentHash) GetPepperID() OptString {
+	return s.PepperID
 }
 
 // GetValue returns the value of Value.
-func (s *NameValue) GetValue() string {
+func (s *DocumentHash) GetValue() string {
 	return s.Value
 }
 
-// SetName sets the value of Name.
-func (s *NameValue) SetName(val string) {
-	s.Name = val
+// SetAlgorithm sets the value of Algorithm.
+func (s *DocumentHash) SetAlgorithm(val string) {
+	s.Algorithm = val
+}
+
+// SetPepperID sets the value of PepperID.
+func (s *DocumentHash) SetPepperID(val OptString) {
+	s.PepperID = val
+}
+
+// SetValue sets the value of Value.
+func (s *DocumentHash) SetValue(val string) {
+	s.Value = val
+}
+
+// Ref: #/components/schemas/DocumentProject
+type DocumentProject struct {
+	User OptString `json:"user"`
+	// Project slug; empty for the default project.
+	Slug        string      `json:"slug"`
+	Description OptString   `json:"description"`
+	Aliases     []string    `json:"aliases"`
+	Hosts       []string    `json:"hosts"`
+	Paths       []string    `json:"paths"`
+	Headers     []NameValue `json:"headers"`
+}
+
+// GetUser returns the value of User.
+func (s *DocumentProject) GetUser() OptString {
+	return s.User
+}
+
+// GetSlug returns the value of Slug.
+func (s *DocumentProject) GetSlug() string {
+	return s.Slug
+}
+
+// GetDescription returns the value of Description.
+func (s *DocumentProject) GetDescription() OptString {
+	return s.Description
+}
+
+// GetAliases returns the value of Aliases.
+func (s *DocumentProject) GetAliases() []string {
+	return s.Aliases
+}
+
+// GetHosts returns the value of Hosts.
+func (s *DocumentProject) GetHosts() []string {
+	return s.Hosts
+}
+
+// GetPaths returns the value of Paths.
+func (s *DocumentProject) GetPaths() []string {
+	return s.Paths
+}
+
+// GetHeaders returns the value of Headers.
+func (s *DocumentProject) GetHeaders() []NameValue {
+	return s.Headers
+}
+
+// SetUser sets the value of User.
+func (s *DocumentProject) SetUser(val OptString) {
+	s.User = val
+}
+
+// SetSlug sets the value of Slug.
+func (s *DocumentProject) SetSlug(val string) {
+	s.Slug = val
+}
+
+// SetDescription sets the value of Description.
+func (s *DocumentProject) SetDescription(val OptString) {
+	s.Description = val
+}
+
+// SetAliases sets the value of Aliases.
+func (s *DocumentProject) SetAliases(val []string) {
+	s.Aliases = val
+}
+
+// SetHosts sets the value of Hosts.
+func (s *DocumentProject) SetHosts(val []string) {
+	s.Hosts = val
+}
+
+// SetPaths sets the value of Paths.
+func (s *DocumentProject) SetPaths(val []string) {
+	s.Paths = val
+}
+
+// SetHeaders sets the value of Headers.
+func (s *DocumentProject) SetHeaders(val []NameValue) {
+	s.Headers = val
+}
+
+// Ref: #/components/schemas/DocumentToken
+type DocumentToken struct {
+	User OptString `json:"user"`
+	// Public key ID, identifies the token.
+	KeyID     string                    `json:"keyID"`
+	KeyFormat OptDocumentTokenKeyFormat `json:"keyFormat"`
+	// Public prefix of the imported key.
+	KeyPrefix OptString `json:"keyPrefix"`
+	Label     OptString `json:"label"`
+	// Project slug (or alias).
+	Project string `json:"project"`
+	// Slugs of additional projects.
+	LinkedProjects []string            `json:"linkedProjects"`
+	Hosts          []string            `json:"hosts"`
+	Paths          []string            `json:"paths"`
+	Headers        []NameValue         `json:"headers"`
+	Meta           OptMeta             `json:"meta"`
+	Hash           OptDocumentHash     `json:"hash"`
+	Disabled       OptDocumentDisabled `json:"disabled"`
+}
+
+// GetUser returns the value of User.
+func (s *DocumentToken) GetUser() OptString {
+	return s.User
+}
+
+// GetKeyID returns the value of KeyID.
+func (s *DocumentToken) GetKeyID() string {
+	return s.KeyID
+}
+
+// GetKeyFormat returns the value of KeyFormat.
+func (s *DocumentToken) GetKeyFormat() OptDocumentTokenKeyFormat {
+	return s.KeyFormat
+}
+
+// GetKeyPrefix returns the value of KeyPrefix.
+func (s *DocumentToken) GetKeyPrefix() OptString {
+	return s.KeyPrefix
+}
+
+// GetLabel returns the value of Label.
+func (s *DocumentToken) GetLabel() OptString {
+	return s.Label
+}
+
+// GetProject returns the value of Project.
+func (s *DocumentToken) GetProject() string {
+	return s.Project
+}
+
+// GetLinkedProjects returns the value of LinkedProjects.
+func (s *DocumentToken) GetLinkedProjects() []string {
+	return s.LinkedProjects
+}
+
+// GetHosts returns the value of Hosts.
+func (s *DocumentToken) GetHosts() []string {
+	return s.Hosts
+}
+
+// GetPaths returns the value of Paths.
+func (s *DocumentToken) GetPaths() []string {
+	return s.Paths
+}
+
+// GetHeaders returns the value of Headers.
+func (s *DocumentToken) GetHeaders() []NameValue {
+	return s.Headers
+}
+
+// GetMeta returns the value of Meta.
+func (s *DocumentToken) GetMeta() OptMeta {
+	return s.Meta
+}
+
+// GetHash returns the value of Hash.
+func (s *DocumentToken) GetHash() OptDocumentHash {
+	return s.Hash
+}
+
+// GetDisabled returns the value of Disabled.
+func (s *DocumentToken) GetDisabled() OptDocumentDisabled {
+	return s.Disabled
+}
+
+// SetUser sets the value of User.
+func (s *DocumentToken) SetUser(val OptString) {
+	s.User = val
+}
+
+// SetKeyID sets the value of KeyID.
+func (s *DocumentToken) SetKeyID(val string) {
+	s.KeyID = val
+}
+
+// SetKeyFormat sets the value of KeyFormat.
+func (s *DocumentToken) SetKeyFormat(val OptDocumentTokenKeyFormat) {
+	s.KeyFormat = val
+}
+
+// SetKeyPrefix sets the value of KeyPrefix.
+func (s *DocumentToken) SetKeyPrefix(val OptString) {
+	s.KeyPrefix = val
+}
+
+// SetLabel sets the value of Label.
+func (s *DocumentToken) SetLabel(val OptString) {
+	s.Label = val
+}
+
+// SetProject sets the value of Project.
+func (s *DocumentToken) SetProject(val string) {
+	s.Project = val
+}
+
+// SetLinkedProjects sets the value of LinkedProjects.
+func (s *DocumentToken) SetLinkedProjects(val []string) {
+	s.LinkedProjects = val
+}
+
+// SetHosts sets the value of Hosts.
+func (s *DocumentToken) SetHosts(val []string) {
+	s.Hosts = val
+}
+
+// SetPaths sets the value of Paths.
+func (s *DocumentToken) SetPaths(val []string) {
+	s.Paths = val
+}
+
+// SetHeaders sets the value of Headers.
+func (s *DocumentToken) SetHeaders(val []NameValue) {
+	s.Headers = val
+}
+
+// SetMeta sets the value of Meta.
+func (s *DocumentToken) SetMeta(val OptMeta) {
+	s.Meta = val
+}
+
+// SetHash sets the value of Hash.
+func (s *DocumentToken) SetHash(val OptDocumentHash) {
+	s.Hash = val
+}
+
+// SetDisabled sets the value of Disabled.
+func (s *DocumentToken) SetDisabled(val OptDocumentDisabled) {
+	s.Disabled = val
+}
+
+type DocumentTokenKeyFormat string
+
+const (
+	DocumentTokenKeyFormatNative   DocumentTokenKeyFormat = "native"
+	DocumentTokenKeyFormatImported DocumentTokenKeyFormat = "imported"
+)
+
+// AllValues returns all DocumentTokenKeyFormat values.
+func (DocumentTokenKeyFormat) AllValues() []DocumentTokenKeyFormat {
+	return []DocumentTokenKeyFormat{
+		DocumentTokenKeyFormatNative,
+		DocumentTokenKeyFormatImported,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s DocumentTokenKeyFormat) MarshalText() ([]byte, error) {
+	switch s {
+	case DocumentTokenKeyFormatNative:
+		return []byte(s), nil
+	case DocumentTokenKeyFormatImported:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *DocumentTokenKeyFormat) UnmarshalText(data []byte) error {
+	switch DocumentTokenKeyFormat(data) {
+	case DocumentTokenKeyFormatNative:
+		*s = DocumentTokenKeyFormatNative
+		return nil
+	case DocumentTokenKeyFormatImported:
+		*s = DocumentTokenKeyFormatImported
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Token rules after applying project defaults.
+// Ref: #/components/schemas/EffectiveRules
+type EffectiveRules struct {
+	// Allowed hosts. Supports globs.
+	Hosts []string `json:"hosts"`
+	// Allowed paths. Supports globs.
+	Paths []string `json:"paths"`
+	// Headers added after successful authorization.
+	Headers []NameValue `json:"headers"`
+}
+
+// GetHosts returns the value of Hosts.
+func (s *EffectiveRules) GetHosts() []string {
+	return s.Hosts
+}
+
+// GetPaths returns the value of Paths.
+func (s *EffectiveRules) GetPaths() []string {
+	return s.Paths
+}
+
+// GetHeaders returns the value of Headers.
+func (s *EffectiveRules) GetHeaders() []NameValue {
+	return s.Headers
+}
+
+// SetHosts sets the value of Hosts.
+func (s *EffectiveRules) SetHosts(val []string) {
+	s.Hosts = val
+}
+
+// SetPaths sets the value of Paths.
+func (s *EffectiveRules) SetPaths(val []string) {
+	s.Paths = val
+}
+
+// SetHeaders sets the value of Headers.
+func (s *EffectiveRules) SetHeaders(val []NameValue) {
+	s.Headers = val
+}
+
+// Ref: #/components/schemas/ImportChange
+type ImportChange struct {
+	Op   ImportChangeOp `json:"op"`
+	User string         `json:"user"`
+	// Slug, for project changes.
+	Project OptString `json:"project"`
+	// Key ID, for token changes.
+	KeyID OptString `json:"keyID"`
+	// ID of the project or token; unknown for new rows in dry-run.
+	ID OptInt `json:"id"`
+	// New key of the token imported without hash. It's shown only once.
+	Key OptString `json:"key"`
+}
+
+// GetOp returns the value of Op.
+func (s *ImportChange) GetOp() ImportChangeOp {
+	return s.Op
+}
+
+// GetUser returns the value of User.
+func (s *ImportChange) GetUser() string {
+	return s.User
+}
+
+// GetProject returns the value of Project.
+func (s *ImportChange) GetProject() OptString {
+	return s.Project
+}
+
+// GetKeyID returns the value of KeyID.
+func (s *ImportChange) GetKeyID() OptString {
+	return s.KeyID
+}
+
+// GetID returns the value of ID.
+func (s *ImportChange) GetID() OptInt {
+	return s.ID
+}
+
+// GetKey returns the value of Key.
+func (s *ImportChange) GetKey() OptString {
+	return s.Key
+}
+
+// SetOp sets the value of Op.
+func (s *ImportChange) SetOp(val ImportChangeOp) {
+	s.Op = val
+}
+
+// SetUser sets the value of User.
+func (s *ImportChange) SetUser(val string) {
+	s.User = val
+}
+
+// SetProject sets the value of Project.
+func (s *ImportChange) SetProject(val OptString) {
+	s.Project = val
+}
+
+// SetKeyID sets the value of KeyID.
+func (s *ImportChange) SetKeyID(val OptString) {
+	s.KeyID = val
+}
+
+// SetID sets the value of ID.
+func (s *ImportChange) SetID(val OptInt) {
+	s.ID = val
+}
+
+// SetKey sets the value of Key.
+func (s *ImportChange) SetKey(val OptString) {
+	s.Key = val
+}
+
+type ImportChangeOp string
+
+const (
+	ImportChangeOpCreate ImportChangeOp = "create"
+	ImportChangeOpUpdate ImportChangeOp = "update"
+	ImportChangeOpSkip   ImportChangeOp = "skip"
+)
+
+// AllValues returns all ImportChangeOp values.
+func (ImportChangeOp) AllValues() []ImportChangeOp {
+	return []ImportChangeOp{
+		ImportChangeOpCreate,
+		ImportChangeOpUpdate,
+		ImportChangeOpSkip,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ImportChangeOp) MarshalText() ([]byte, error) {
+	switch s {
+	case ImportChangeOpCreate:
+		return []byte(s), nil
+	case ImportChangeOpUpdate:
+		return []byte(s), nil
+	case ImportChangeOpSkip:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ImportChangeOp) UnmarshalText(data []byte) error {
+	switch ImportChangeOp(data) {
+	case ImportChangeOpCreate:
+		*s = ImportChangeOpCreate
+		return nil
+	case ImportChangeOpUpdate:
+		*s = ImportChangeOpUpdate
+		return nil
+	case ImportChangeOpSkip:
+		*s = ImportChangeOpSkip
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ImportDocumentConflict string
+
+const (
+	ImportDocumentConflictFail      ImportDocumentConflict = "fail"
+	ImportDocumentConflictSkip      ImportDocumentConflict = "skip"
+	ImportDocumentConflictOverwrite ImportDocumentConflict = "overwrite"
+)
+
+// AllValues returns all ImportDocumentConflict values.
+func (ImportDocumentConflict) AllValues() []ImportDocumentConflict {
+	return []ImportDocumentConflict{
+		ImportDocumentConflictFail,
+		ImportDocumentConflictSkip,
+		ImportDocumentConflictOverwrite,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ImportDocumentConflict) MarshalText() ([]byte, error) {
+	switch s {
+	case ImportDocumentConflictFail:
+		return []byte(s), nil
+	case ImportDocumentConflictSkip:
+		return []byte(s), nil
+	case ImportDocumentConflictOverwrite:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ImportDocumentConflict) UnmarshalText(data []byte) error {
+	switch ImportDocumentConflict(data) {
+	case ImportDocumentConflictFail:
+		*s = ImportDocumentConflictFail
+		return nil
+	case ImportDocumentConflictSkip:
+		*s = ImportDocumentConflictSkip
+		return nil
+	case ImportDocumentConflictOverwrite:
+		*s = ImportDocumentConflictOverwrite
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Existing key with its config. Either the raw key or its pre-computed hash must be set. Native keys
+// (generated by this service) are imported as is; any other key needs its public prefix, which
+// identifies the key and must be unique. The hash is made over the whole key, including the prefix.
+// Ref: #/components/schemas/ImportKey
+type ImportKey struct {
+	Config TokenConfig `json:"config"`
+	// Raw key.
+	Key OptString `json:"key"`
+	// Public prefix of the non-native key, 4-64 bytes.
+	KeyPrefix OptString `json:"keyPrefix"`
+	// Hex-encoded hash of the whole key (if raw key is unknown).
+	Hash OptString `json:"hash"`
+	// Algorithm of the pre-computed hash.
+	HashAlgorithm OptImportKeyHashAlgorithm `json:"hashAlgorithm"`
+}
+
+// GetConfig returns the value of Config.
+func (s *ImportKey) GetConfig() TokenConfig {
+	return s.Config
+}
+
+// GetKey returns the value of Key.
+func (s *ImportKey) GetKey() OptString {
+	return s.Key
+}
+
+// GetKeyPrefix returns the value of KeyPrefix.
+func (s *ImportKey) GetKeyPrefix() OptString {
+	return s.KeyPrefix
+}
+
+// GetHash returns the value of Hash.
+func (s *ImportKey) GetHash() OptString {
+	return s.Hash
+}
+
+// GetHashAlgorithm returns the value of HashAlgorithm.
+func (s *ImportKey) GetHashAlgorithm() OptImportKeyHashAlgorithm {
+	return s.HashAlgorithm
+}
+
+// SetConfig sets the value of Config.
+func (s *ImportKey) SetConfig(val TokenConfig) {
+	s.Config = val
+}
+
+// SetKey sets the value of Key.
+func (s *ImportKey) SetKey(val OptString) {
+	s.Key = val
+}
+
+// SetKeyPrefix sets the value of KeyPrefix.
+func (s *ImportKey) SetKeyPrefix(val OptString) {
+	s.KeyPrefix = val
+}
+
+// SetHash sets the value of Hash.
+func (s *ImportKey) SetHash(val OptString) {
+	s.Hash = val
+}
+
+// SetHashAlgorithm sets the value of HashAlgorithm.
+func (s *ImportKey) SetHashAlgorithm(val OptImportKeyHashAlgorithm) {
+	s.HashAlgorithm = val
+}
+
+// Algorithm of the pre-computed hash.
+type ImportKeyHashAlgorithm string
+
+const (
+	ImportKeyHashAlgorithmSha3384 ImportKeyHashAlgorithm = "sha3-384"
+	ImportKeyHashAlgorithmSHA256  ImportKeyHashAlgorithm = "sha256"
+)
+
+// AllValues returns all ImportKeyHashAlgorithm values.
+func (ImportKeyHashAlgorithm) AllValues() []ImportKeyHashAlgorithm {
+	return []ImportKeyHashAlgorithm{
+		ImportKeyHashAlgorithmSha3384,
+		ImportKeyHashAlgorithmSHA256,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ImportKeyHashAlgorithm) MarshalText() ([]byte, error) {
+	switch s {
+	case ImportKeyHashAlgorithmSha3384:
+		return []byte(s), nil
+	case ImportKeyHashAlgorithmSHA256:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ImportKeyHashAlgorithm) UnmarshalText(data []byte) error {
+	switch ImportKeyHashAlgorithm(data) {
+	case ImportKeyHashAlgorithmSha3384:
+		*s = ImportKeyHashAlgorithmSha3384
+		return nil
+	case ImportKeyHashAlgorithmSHA256:
+		*s = ImportKeyHashAlgorithmSHA256
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/ImportReport
+type ImportReport struct {
+	DryRun   bool           `json:"dryRun"`
+	Projects []ImportChange `json:"projects"`
+	Tokens   []ImportChange `json:"tokens"`
+}
+
+// GetDryRun returns the value of DryRun.
+func (s *ImportReport) GetDryRun() bool {
+	return s.DryRun
+}
+
+// GetProjects returns the value of Projects.
+func (s *ImportReport) GetProjects() []ImportChange {
+	return s.Projects
+}
+
+// GetTokens returns the value of Tokens.
+func (s *ImportReport) GetTokens() []ImportChange {
+	return s.Tokens
+}
+
+// SetDryRun sets the value of DryRun.
+func (s *ImportReport) SetDryRun(val bool) {
+	s.DryRun = val
+}
+
+// SetProjects sets the value of Projects.
+func (s *ImportReport) SetProjects(val []ImportChange) {
+	s.Projects = val
+}
+
+// SetTokens sets the value of Tokens.
+func (s *ImportReport) SetTokens(val []ImportChange) {
+	s.Tokens = val
+}
+
+// Ref: #/components/schemas/ImportResult
+type ImportResult struct {
+	// ID of the created token.
+	ID OptInt `json:"id"`
+	// Public key ID.
+	KeyID string `json:"keyID"`
+	// Why the key was not imported.
+	Error OptString `json:"error"`
+}
+
+// GetID returns the value of ID.
+func (s *ImportResult) GetID() OptInt {
+	return s.ID
+}
+
+// GetKeyID returns the value of KeyID.
+func (s *ImportResult) GetKeyID() string {
+	return s.KeyID
+}
+
+// GetError returns the value of Error.
+func (s *ImportResult) GetError() OptString {
+	return s.Error
+}
+
+// SetID sets the value of ID.
+func (s *ImportResult) SetID(val OptInt) {
+	s.ID = val
+}
+
+// SetKeyID sets the value of KeyID.
+func (s *ImportResult) SetKeyID(val string) {
+	s.KeyID = val
+}
+
+// SetError sets the value of Error.
+func (s *ImportResult) SetError(val OptString) {
+	s.Error = val
+}
+
+// Ref: #/components/schemas/KeyLookup
+type KeyLookup struct {
+	// Raw token key.
+	Key string `json:"key"`
+}
+
+// GetKey returns the value of Key.
+func (s *KeyLookup) GetKey() string {
+	return s.Key
+}
+
+// SetKey sets the value of Key.
+func (s *KeyLookup) SetKey(val string) {
+	s.Key = val
+}
+
+// ListTokensOKHeaders wraps []Token with response headers.
+type ListTokensOKHeaders struct {
+	XNextCursor OptString
+	Response    []Token
+}
+
+// GetXNextCursor returns the value of XNextCursor.
+func (s *ListTokensOKHeaders) GetXNextCursor() OptString {
+	return s.XNextCursor
+}
+
+// GetResponse returns the value of Response.
+func (s *ListTokensOKHeaders) GetResponse() []Token {
+	return s.Response
+}
+
+// SetXNextCursor sets the value of XNextCursor.
+func (s *ListTokensOKHeaders) SetXNextCursor(val OptString) {
+	s.XNextCursor = val
+}
+
+// SetResponse sets the value of Response.
+func (s *ListTokensOKHeaders) SetResponse(val []Token) {
+	s.Response = val
+}
+
+type ListTokensOrder string
+
+const (
+	ListTokensOrderAsc  ListTokensOrder = "asc"
+	ListTokensOrderDesc ListTokensOrder = "desc"
+)
+
+// AllValues returns all ListTokensOrder values.
+func (ListTokensOrder) AllValues() []ListTokensOrder {
+	return []ListTokensOrder{
+		ListTokensOrderAsc,
+		ListTokensOrderDesc,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ListTokensOrder) MarshalText() ([]byte, error) {
+	switch s {
+	case ListTokensOrderAsc:
+		return []byte(s), nil
+	case ListTokensOrderDesc:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ListTokensOrder) UnmarshalText(data []byte) error {
+	switch ListTokensOrder(data) {
+	case ListTokensOrderAsc:
+		*s = ListTokensOrderAsc
+		return nil
+	case ListTokensOrderDesc:
+		*s = ListTokensOrderDesc
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type ListTokensSort string
+
+const (
+	ListTokensSortCreated    ListTokensSort = "created"
+	ListTokensSortLabel      ListTokensSort = "label"
+	ListTokensSortLastAccess ListTokensSort = "lastAccess"
+	ListTokensSortRequests   ListTokensSort = "requests"
+)
+
+// AllValues returns all ListTokensSort values.
+func (ListTokensSort) AllValues() []ListTokensSort {
+	return []ListTokensSort{
+		ListTokensSortCreated,
+		ListTokensSortLabel,
+		ListTokensSortLastAccess,
+		ListTokensSortRequests,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ListTokensSort) MarshalText() ([]byte, error) {
+	switch s {
+	case ListTokensSortCreated:
+		return []byte(s), nil
+	case ListTokensSortLabel:
+		return []byte(s), nil
+	case ListTokensSortLastAccess:
+		return []byte(s), nil
+	case ListTokensSortRequests:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ListTokensSort) UnmarshalText(data []byte) error {
+	switch ListTokensSort(data) {
+	case ListTokensSortCreated:
+		*s = ListTokensSortCreated
+		return nil
+	case ListTokensSortLabel:
+		*s = ListTokensSortLabel
+		return nil
+	case ListTokensSortLastAccess:
+		*s = ListTokensSortLastAccess
+		return nil
+	case ListTokensSortRequests:
+		*s = ListTokensSortRequests
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Arbitrary key/value metadata. Available in header templates as `{{.Token.Meta.key}}`.
+// Ref: #/components/schemas/Meta
+type Meta map[string]string
+
+func (s *Meta) init() Meta {
+	m := *s
+	if m == nil {
+		m = map[string]string{}
+		*s = m
+	}
+	return m
+}
+
+// Ref: #/components/schemas/NameValue
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// GetName returns the value of Name.
+func (s *NameValue) GetName() string {
+	return s.Name
+}
+
+// GetValue returns the value of Value.
+func (s *NameValue) GetValue() string {
+	return s.Value
+}
+
+// SetName sets the value of Name.
+func (s *NameValue) SetName(val string) {
+	s.Name = val
+}
+
+// SetValue sets the value of Value.
+func (s *NameValue) SetValue(val string) {
+	s.Value = val
+}
+
+// NewOptBool returns new OptBool with value set to v.
+func NewOptBool(v bool) OptBool {
+	return OptBool{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptBool is optional bool.
+type OptBool struct {
+	Value bool
+	Set   bool
+}
+
+// IsSet returns true if OptBool was set.
+func (o OptBool) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptBool) Reset() {
+	var v bool
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptBool) SetTo(v bool) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptBool) Get() (v bool, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptBool) Or(d bool) bool {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptDateTime returns new OptDateTime with value set to v.
+func NewOptDateTime(v time.Time) OptDateTime {
+	return OptDateTime{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDateTime is optional time.Time.
+type OptDateTime struct {
+	Value time.Time
+	Set   bool
+}
+
+// IsSet returns true if OptDateTime was set.
+func (o OptDateTime) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDateTime) Reset() {
+	var v time.Time
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDateTime) SetTo(v time.Time) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDateTime) Get() (v time.Time, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDateTime) Or(d time.Time) time.Time {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptDocumentDisabled returns new OptDocumentDisabled with value set to v.
+func NewOptDocumentDisabled(v DocumentDisabled) OptDocumentDisabled {
+	return OptDocumentDisabled{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDocumentDisabled is optional DocumentDisabled.
+type OptDocumentDisabled struct {
+	Value DocumentDisabled
+	Set   bool
+}
+
+// IsSet returns true if OptDocumentDisabled was set.
+func (o OptDocumentDisabled) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDocumentDisabled) Reset() {
+	var v DocumentDisabled
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDocumentDisabled) SetTo(v DocumentDisabled) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDocumentDisabled) Get() (v DocumentDisabled, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDocumentDisabled) Or(d DocumentDisabled) DocumentDisabled {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptDocumentHash returns new OptDocumentHash with value set to v.
+func NewOptDocumentHash(v DocumentHash) OptDocumentHash {
+	return OptDocumentHash{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDocumentHash is optional DocumentHash.
+type OptDocumentHash struct {
+	Value DocumentHash
+	Set   bool
+}
+
+// IsSet returns true if OptDocumentHash was set.
+func (o OptDocumentHash) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDocumentHash) Reset() {
+	var v DocumentHash
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDocumentHash) SetTo(v DocumentHash) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDocumentHash) Get() (v DocumentHash, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDocumentHash) Or(d DocumentHash) DocumentHash {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptDocumentTokenKeyFormat returns new OptDocumentTokenKeyFormat with value set to v.
+func NewOptDocumentTokenKeyFormat(v DocumentTokenKeyFormat) OptDocumentTokenKeyFormat {
+	return OptDocumentTokenKeyFormat{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDocumentTokenKeyFormat is optional DocumentTokenKeyFormat.
+type OptDocumentTokenKeyFormat struct {
+	Value DocumentTokenKeyFormat
+	Set   bool
+}
+
+// IsSet returns true if OptDocumentTokenKeyFormat was set.
+func (o OptDocumentTokenKeyFormat) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDocumentTokenKeyFormat) Reset() {
+	var v DocumentTokenKeyFormat
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDocumentTokenKeyFormat) SetTo(v DocumentTokenKeyFormat) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDocumentTokenKeyFormat) Get() (v DocumentTokenKeyFormat, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDocumentTokenKeyFormat) Or(d DocumentTokenKeyFormat) DocumentTokenKeyFormat {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptImportDocumentConflict returns new OptImportDocumentConflict with value set to v.
+func NewOptImportDocumentConflict(v ImportDocumentConflict) OptImportDocumentConflict {
+	return OptImportDocumentConflict{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptImportDocumentConflict is optional ImportDocumentConflict.
+type OptImportDocumentConflict struct {
+	Value ImportDocumentConflict
+	Set   bool
+}
+
+// IsSet returns true if OptImportDocumentConflict was set.
+func (o OptImportDocumentConflict) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptImportDocumentConflict) Reset() {
+	var v ImportDocumentConflict
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptImportDocumentConflict) SetTo(v ImportDocumentConflict) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptImportDocumentConflict) Get() (v ImportDocumentConflict, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptImportDocumentConflict) Or(d ImportDocumentConflict) ImportDocumentConflict {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptImportKeyHashAlgorithm returns new OptImportKeyHashAlgorithm with value set to v.
+func NewOptImportKeyHashAlgorithm(v ImportKeyHashAlgorithm) OptImportKeyHashAlgorithm {
+	return OptImportKeyHashAlgorithm{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptImportKeyHashAlgorithm is optional ImportKeyHashAlgorithm.
+type OptImportKeyHashAlgorithm struct {
+	Value ImportKeyHashAlgorithm
+	Set   bool
+}
+
+// IsSet returns true if OptImportKeyHashAlgorithm was set.
+func (o OptImportKeyHashAlgorithm) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptImportKeyHashAlgorithm) Reset() {
+	var v ImportKeyHashAlgorithm
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptImportKeyHashAlgorithm) SetTo(v ImportKeyHashAlgorithm) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptImportKeyHashAlgorithm) Get() (v ImportKeyHashAlgorithm, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptImportKeyHashAlgorithm) Or(d ImportKeyHashAlgorithm) ImportKeyHashAlgorithm {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptInt returns new OptInt with value set to v.
+func NewOptInt(v int) OptInt {
+	return OptInt{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt is optional int.
+type OptInt struct {
+	Value int
+	Set   bool
+}
+
+// IsSet returns true if OptInt was set.
+func (o OptInt) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt) Reset() {
+	var v int
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt) SetTo(v int) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt) Get() (v int, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt) Or(d int) int {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptListTokensOrder returns new OptListTokensOrder with value set to v.
+func NewOptListTokensOrder(v ListTokensOrder) OptListTokensOrder {
+	return OptListTokensOrder{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptListTokensOrder is optional ListTokensOrder.
+type OptListTokensOrder struct {
+	Value ListTokensOrder
+	Set   bool
+}
+
+// IsSet returns true if OptListTokensOrder was set.
+func (o OptListTokensOrder) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptListTokensOrder) Reset() {
+	var v ListTokensOrder
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptListTokensOrder) SetTo(v ListTokensOrder) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptListTokensOrder) Get() (v ListTokensOrder, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
 }
 
-// SetValue sets the value of Value.
-func (s *NameValue) SetValue(val string) {
-	s.Value = val
+// Or returns value if set, or given parameter if does not.
+func (o OptListTokensOrder) Or(d ListTokensOrder) ListTokensOrder {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
 }
 
-// NewOptDateTime returns new OptDateTime with value set to v.
-func NewOptDateTime(v time.Time) OptDateTime {
-	return OptDateTime{
+// NewOptListTokensSort returns new OptListTokensSort with value set to v.
+func NewOptListTokensSort(v ListTokensSort) OptListTokensSort {
+	return OptListTokensSort{
 		Value: v,
 		Set:   true,
 	}
 }
 
-// OptDateTime is optional time.Time.
-type OptDateTime struct {
-	Value time.Time
+// OptListTokensSort is optional ListTokensSort.
+type OptListTokensSort struct {
+	Value ListTokensSort
 	Set   bool
 }
 
-// IsSet returns true if OptDateTime was set.
-func (o OptDateTime) IsSet() bool { return o.Set }
+// IsSet returns true if OptListTokensSort was set.
+func (o OptListTokensSort) IsSet() bool { return o.Set }
 
 // Reset unsets value.
-func (o *OptDateTime) Reset() {
-	var v time.Time
+func (o *OptListTokensSort) Reset() {
+	var v ListTokensSort
 	o.Value = v
 	o.Set = false
 }
 
 // SetTo sets value to v.
-func (o *OptDateTime) SetTo(v time.Time) {
+func (o *OptListTokensSort) SetTo(v ListTokensSort) {
 	o.Set = true
 	o.Value = v
 }
 
 // Get returns value and boolean that denotes whether value was set.
-func (o OptDateTime) Get() (v time.Time, ok bool) {
+func (o OptListTokensSort) Get() (v ListTokensSort, ok bool) {
 	if !o.Set {
 		return v, false
 	}
@@ -105,45 +1460,45 @@ func (o OptDateTime) Get() (v time.Time, ok bool) {
 }
 
 // Or returns value if set, or given parameter if does not.
-func (o OptDateTime) Or(d time.Time) time.Time {
+func (o OptListTokensSort) Or(d ListTokensSort) ListTokensSort {
 	if v, ok := o.Get(); ok {
 		return v
 	}
 	return d
 }
 
-// NewOptInt returns new OptInt with value set to v.
-func NewOptInt(v int) OptInt {
-	return OptInt{
+// NewOptMeta returns new OptMeta with value set to v.
+func NewOptMeta(v Meta) OptMeta {
+	return OptMeta{
 		Value: v,
 		Set:   true,
 	}
 }
 
-// OptInt is optional int.
-type OptInt struct {
-	Value int
+// OptMeta is optional Meta.
+type OptMeta struct {
+	Value Meta
 	Set   bool
 }
 
-// IsSet returns true if OptInt was set.
-func (o OptInt) IsSet() bool { return o.Set }
+// IsSet returns true if OptMeta was set.
+func (o OptMeta) IsSet() bool { return o.Set }
 
 // Reset unsets value.
-func (o *OptInt) Reset() {
-	var v int
+func (o *OptMeta) Reset() {
+	var v Meta
 	o.Value = v
 	o.Set = false
 }
 
 // SetTo sets value to v.
-func (o *OptInt) SetTo(v int) {
+func (o *OptMeta) SetTo(v Meta) {
 	o.Set = true
 	o.Value = v
 }
 
 // Get returns value and boolean that denotes whether value was set.
-func (o OptInt) Get() (v int, ok bool) {
+func (o OptMeta) Get() (v Meta, ok bool) {
 	if !o.Set {
 		return v, false
 	}
@@ -151,7 +1506,7 @@ func (o OptInt) Get() (v int, ok bool) {
 }
 
 // Or returns value if set, or given parameter if does not.
-func (o OptInt) Or(d int) int {
+func (o OptMeta) Or(d Meta) Meta {
 	if v, ok := o.Get(); ok {
 		return v
 	}
@@ -216,6 +1571,14 @@ type Project struct {
 	Slug string `json:"slug"`
 	// Project description.
 	Description string `json:"description"`
+	// Previous project slugs which are still accepted until removed.
+	Aliases []string `json:"aliases"`
+	// Default allowed hosts for tokens without own hosts. Supports globs.
+	Hosts []string `json:"hosts"`
+	// Default allowed paths for tokens without own paths. Supports globs.
+	Paths []string `json:"paths"`
+	// Default headers added for tokens of the project. Token headers with the same name take precedence.
+	Headers []NameValue `json:"headers"`
 }
 
 // GetID returns the value of ID.
@@ -243,6 +1606,26 @@ func (s *Project) GetDescription() string {
 	return s.Description
 }
 
+// GetAliases returns the value of Aliases.
+func (s *Project) GetAliases() []string {
+	return s.Aliases
+}
+
+// GetHosts returns the value of Hosts.
+func (s *Project) GetHosts() []string {
+	return s.Hosts
+}
+
+// GetPaths returns the value of Paths.
+func (s *Project) GetPaths() []string {
+	return s.Paths
+}
+
+// GetHeaders returns the value of Headers.
+func (s *Project) GetHeaders() []NameValue {
+	return s.Headers
+}
+
 // SetID sets the value of ID.
 func (s *Project) SetID(val int) {
 	s.ID = val
@@ -268,12 +1651,38 @@ func (s *Project) SetDescription(val string) {
 	s.Description = val
 }
 
+// SetAliases sets the value of Aliases.
+func (s *Project) SetAliases(val []string) {
+	s.Aliases = val
+}
+
+// SetHosts sets the value of Hosts.
+func (s *Project) SetHosts(val []string) {
+	s.Hosts = val
+}
+
+// SetPaths sets the value of Paths.
+func (s *Project) SetPaths(val []string) {
+	s.Paths = val
+}
+
+// SetHeaders sets the value of Headers.
+func (s *Project) SetHeaders(val []NameValue) {
+	s.Headers = val
+}
+
 // Ref: #/components/schemas/ProjectConfig
 type ProjectConfig struct {
 	// Unique project slug (path and query friendly).
 	Slug string `json:"slug"`
 	// Project description.
 	Description OptString `json:"description"`
+	// Default allowed hosts for tokens without own hosts. Supports globs.
+	Hosts []string `json:"hosts"`
+	// Default allowed paths for tokens without own paths. Supports globs.
+	Paths []string `json:"paths"`
+	// Default headers added for tokens of the project. Token headers with the same name take precedence.
+	Headers []NameValue `json:"headers"`
 }
 
 // GetSlug returns the value of Slug.
@@ -286,6 +1695,21 @@ func (s *ProjectConfig) GetDescription() OptString {
 	return s.Description
 }
 
+// GetHosts returns the value of Hosts.
+func (s *ProjectConfig) GetHosts() []string {
+	return s.Hosts
+}
+
+// GetPaths returns the value of Paths.
+func (s *ProjectConfig) GetPaths() []string {
+	return s.Paths
+}
+
+// GetHeaders returns the value of Headers.
+func (s *ProjectConfig) GetHeaders() []NameValue {
+	return s.Headers
+}
+
 // SetSlug sets the value of Slug.
 func (s *ProjectConfig) SetSlug(val string) {
 	s.Slug = val
@@ -296,10 +1720,38 @@ func (s *ProjectConfig) SetDescription(val OptString) {
 	s.Description = val
 }
 
+// SetHosts sets the value of Hosts.
+func (s *ProjectConfig) SetHosts(val []string) {
+	s.Hosts = val
+}
+
+// SetPaths sets the value of Paths.
+func (s *ProjectConfig) SetPaths(val []string) {
+	s.Paths = val
+}
+
+// SetHeaders sets the value of Headers.
+func (s *ProjectConfig) SetHeaders(val []NameValue) {
+	s.Headers = val
+}
+
 // Ref: #/components/schemas/ProjectPatch
 type ProjectPatch struct {
+	// New project slug. Previous slug is kept as an alias.
+	Slug OptString `json:"slug"`
 	// Project description.
 	Description OptString `json:"description"`
+	// Default allowed hosts for tokens without own hosts. Supports globs.
+	Hosts []string `json:"hosts"`
+	// Default allowed paths for tokens without own paths. Supports globs.
+	Paths []string `json:"paths"`
+	// Default headers added for tokens of the project. Token headers with the same name take precedence.
+	Headers []NameValue `json:"headers"`
+}
+
+// GetSlug returns the value of Slug.
+func (s *ProjectPatch) GetSlug() OptString {
+	return s.Slug
 }
 
 // GetDescription returns the value of Description.
@@ -307,11 +1759,74 @@ func (s *ProjectPatch) GetDescription() OptString {
 	return s.Description
 }
 
+// GetHosts returns the value of Hosts.
+func (s *ProjectPatch) GetHosts() []string {
+	return s.Hosts
+}
+
+// GetPaths returns the value of Paths.
+func (s *ProjectPatch) GetPaths() []string {
+	return s.Paths
+}
+
+// GetHeaders returns the value of Headers.
+func (s *ProjectPatch) GetHeaders() []NameValue {
+	return s.Headers
+}
+
+// SetSlug sets the value of Slug.
+func (s *ProjectPatch) SetSlug(val OptString) {
+	s.Slug = val
+}
+
 // SetDescription sets the value of Description.
 func (s *ProjectPatch) SetDescription(val OptString) {
 	s.Description = val
 }
 
+// SetHosts sets the value of Hosts.
+func (s *ProjectPatch) SetHosts(val []string) {
+	s.Hosts = val
+}
+
+// SetPaths sets the value of Paths.
+func (s *ProjectPatch) SetPaths(val []string) {
+	s.Paths = val
+}
+
+// SetHeaders sets the value of Headers.
+func (s *ProjectPatch) SetHeaders(val []NameValue) {
+	s.Headers = val
+}
+
+// Ref: #/components/schemas/ProjectRef
+type ProjectRef struct {
+	// Project ID.
+	ID int `json:"id"`
+	// Project slug.
+	Slug string `json:"slug"`
+}
+
+// GetID returns the value of ID.
+func (s *ProjectRef) GetID() int {
+	return s.ID
+}
+
+// GetSlug returns the value of Slug.
+func (s *ProjectRef) GetSlug() string {
+	return s.Slug
+}
+
+// SetID sets the value of ID.
+func (s *ProjectRef) SetID(val int) {
+	s.ID = val
+}
+
+// SetSlug sets the value of Slug.
+func (s *ProjectRef) SetSlug(val string) {
+	s.Slug = val
+}
+
 // Ref: #/components/schemas/Token
 type Token struct {
 	// Unique token ID.
@@ -336,10 +1851,21 @@ type Token struct {
 	ProjectId int `json:"projectId"`
 	// Slug of the project this token belongs to.
 	ProjectSlug string `json:"projectSlug"`
+	// Additional projects the token is valid for.
+	LinkedProjects []ProjectRef   `json:"linkedProjects"`
+	Effective      EffectiveRules `json:"effective"`
+	Meta           Meta           `json:"meta"`
 	// Custom headers which will be added after successfull authorization.
 	Headers []NameValue `json:"headers"`
 	// Tentative number of requests used this token.
 	Requests int64 `json:"requests"`
+	// Time when token was disabled (for example, reported as leaked). Refresh the token to enable it
+	// again.
+	DisabledAt OptDateTime `json:"disabledAt"`
+	// Why token was disabled.
+	DisabledReason OptString `json:"disabledReason"`
+	// Public prefix of the imported key; empty for keys generated by the service.
+	KeyPrefix OptString `json:"keyPrefix"`
 }
 
 // GetID returns the value of ID.
@@ -397,6 +1923,21 @@ func (s *Token) GetProjectSlug() string {
 	return s.ProjectSlug
 }
 
+// GetLinkedProjects returns the value of LinkedProjects.
+func (s *Token) GetLinkedProjects() []ProjectRef {
+	return s.LinkedProjects
+}
+
+// GetEffective returns the value of Effective.
+func (s *Token) GetEffective() EffectiveRules {
+	return s.Effective
+}
+
+// GetMeta returns the value of Meta.
+func (s *Token) GetMeta() Meta {
+	return s.Meta
+}
+
 // GetHeaders returns the value of Headers.
 func (s *Token) GetHeaders() []NameValue {
 	return s.Headers
@@ -407,6 +1948,21 @@ func (s *Token) GetRequests() int64 {
 	return s.Requests
 }
 
+// GetDisabledAt returns the value of DisabledAt.
+func (s *Token) GetDisabledAt() OptDateTime {
+	return s.DisabledAt
+}
+
+// GetDisabledReason returns the value of DisabledReason.
+func (s *Token) GetDisabledReason() OptString {
+	return s.DisabledReason
+}
+
+// GetKeyPrefix returns the value of KeyPrefix.
+func (s *Token) GetKeyPrefix() OptString {
+	return s.KeyPrefix
+}
+
 // SetID sets the value of ID.
 func (s *Token) SetID(val int) {
 	s.ID = val
@@ -462,6 +2018,21 @@ func (s *Token) SetProjectSlug(val string) {
 	s.ProjectSlug = val
 }
 
+// SetLinkedProjects sets the value of LinkedProjects.
+func (s *Token) SetLinkedProjects(val []ProjectRef) {
+	s.LinkedProjects = val
+}
+
+// SetEffective sets the value of Effective.
+func (s *Token) SetEffective(val EffectiveRules) {
+	s.Effective = val
+}
+
+// SetMeta sets the value of Meta.
+func (s *Token) SetMeta(val Meta) {
+	s.Meta = val
+}
+
 // SetHeaders sets the value of Headers.
 func (s *Token) SetHeaders(val []NameValue) {
 	s.Headers = val
@@ -472,6 +2043,21 @@ func (s *Token) SetRequests(val int64) {
 	s.Requests = val
 }
 
+// SetDisabledAt sets the value of DisabledAt.
+func (s *Token) SetDisabledAt(val OptDateTime) {
+	s.DisabledAt = val
+}
+
+// SetDisabledReason sets the value of DisabledReason.
+func (s *Token) SetDisabledReason(val OptString) {
+	s.DisabledReason = val
+}
+
+// SetKeyPrefix sets the value of KeyPrefix.
+func (s *Token) SetKeyPrefix(val OptString) {
+	s.KeyPrefix = val
+}
+
 // Ref: #/components/schemas/TokenConfig
 type TokenConfig struct {
 	// Custom token description.
@@ -484,6 +2070,9 @@ type TokenConfig struct {
 	Headers []NameValue `json:"headers"`
 	// Project ID this token belongs to.
 	ProjectId int `json:"projectId"`
+	// Additional projects (owned by the same user) the token is valid for.
+	LinkedProjectIds []int   `json:"linkedProjectIds"`
+	Meta             OptMeta `json:"meta"`
 }
 
 // GetLabel returns the value of Label.
@@ -511,6 +2100,16 @@ func (s *TokenConfig) GetProjectId() int {
 	return s.ProjectId
 }
 
+// GetLinkedProjectIds returns the value of LinkedProjectIds.
+func (s *TokenConfig) GetLinkedProjectIds() []int {
+	return s.LinkedProjectIds
+}
+
+// GetMeta returns the value of Meta.
+func (s *TokenConfig) GetMeta() OptMeta {
+	return s.Meta
+}
+
 // SetLabel sets the value of Label.
 func (s *TokenConfig) SetLabel(val OptString) {
 	s.Label = val
@@ -536,6 +2135,80 @@ func (s *TokenConfig) SetProjectId(val int) {
 	s.ProjectId = val
 }
 
+// SetLinkedProjectIds sets the value of LinkedProjectIds.
+func (s *TokenConfig) SetLinkedProjectIds(val []int) {
+	s.LinkedProjectIds = val
+}
+
+// SetMeta sets the value of Meta.
+func (s *TokenConfig) SetMeta(val OptMeta) {
+	s.Meta = val
+}
+
+// Ref: #/components/schemas/TokenIdentity
+type TokenIdentity struct {
+	// Token ID.
+	ID int `json:"id"`
+	// Public key ID.
+	KeyID string `json:"keyID"`
+	// Custom token description.
+	Label string `json:"label"`
+	// ID of the project this token belongs to.
+	ProjectId int `json:"projectId"`
+	// Slug of the project this token belongs to.
+	ProjectSlug string `json:"projectSlug"`
+}
+
+// GetID returns the value of ID.
+func (s *TokenIdentity) GetID() int {
+	return s.ID
+}
+
+// GetKeyID returns the value of KeyID.
+func (s *TokenIdentity) GetKeyID() string {
+	return s.KeyID
+}
+
+// GetLabel returns the value of Label.
+func (s *TokenIdentity) GetLabel() string {
+	return s.Label
+}
+
+// GetProjectId returns the value of ProjectId.
+func (s *TokenIdentity) GetProjectId() int {
+	return s.ProjectId
+}
+
+// GetProjectSlug returns the value of ProjectSlug.
+func (s *TokenIdentity) GetProjectSlug() string {
+	return s.ProjectSlug
+}
+
+// SetID sets the value of ID.
+func (s *TokenIdentity) SetID(val int) {
+	s.ID = val
+}
+
+// SetKeyID sets the value of KeyID.
+func (s *TokenIdentity) SetKeyID(val string) {
+	s.KeyID = val
+}
+
+// SetLabel sets the value of Label.
+func (s *TokenIdentity) SetLabel(val string) {
+	s.Label = val
+}
+
+// SetProjectId sets the value of ProjectId.
+func (s *TokenIdentity) SetProjectId(val int) {
+	s.ProjectId = val
+}
+
+// SetProjectSlug sets the value of ProjectSlug.
+func (s *TokenIdentity) SetProjectSlug(val string) {
+	s.ProjectSlug = val
+}
+
 // Ref: #/components/schemas/TokenPatch
 type TokenPatch struct {
 	// Custom token description.
@@ -546,6 +2219,11 @@ type TokenPatch struct {
 	Paths []string `json:"paths"`
 	// Custom headers which will be added after successfull authorization.
 	Headers []NameValue `json:"headers"`
+	// Move token to another project owned by the same user.
+	ProjectId OptInt `json:"projectId"`
+	// Replace additional projects (owned by the same user) the token is valid for.
+	LinkedProjectIds []int   `json:"linkedProjectIds"`
+	Meta             OptMeta `json:"meta"`
 }
 
 // GetLabel returns the value of Label.
@@ -568,6 +2246,21 @@ func (s *TokenPatch) GetHeaders() []NameValue {
 	return s.Headers
 }
 
+// GetProjectId returns the value of ProjectId.
+func (s *TokenPatch) GetProjectId() OptInt {
+	return s.ProjectId
+}
+
+// GetLinkedProjectIds returns the value of LinkedProjectIds.
+func (s *TokenPatch) GetLinkedProjectIds() []int {
+	return s.LinkedProjectIds
+}
+
+// GetMeta returns the value of Meta.
+func (s *TokenPatch) GetMeta() OptMeta {
+	return s.Meta
+}
+
 // SetLabel sets the value of Label.
 func (s *TokenPatch) SetLabel(val OptString) {
 	s.Label = val
@@ -588,6 +2281,21 @@ func (s *TokenPatch) SetHeaders(val []NameValue) {
 	s.Headers = val
 }
 
+// SetProjectId sets the value of ProjectId.
+func (s *TokenPatch) SetProjectId(val OptInt) {
+	s.ProjectId = val
+}
+
+// SetLinkedProjectIds sets the value of LinkedProjectIds.
+func (s *TokenPatch) SetLinkedProjectIds(val []int) {
+	s.LinkedProjectIds = val
+}
+
+// SetMeta sets the value of Meta.
+func (s *TokenPatch) SetMeta(val OptMeta) {
+	s.Meta = val
+}
+
 // UpdateProjectNoContent is response for UpdateProject operation.
 type UpdateProjectNoContent struct{}
 
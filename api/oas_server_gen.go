@@ -26,12 +26,25 @@ type Handler interface {
 	//
 	// DELETE /projects/{project}
 	DeleteProject(ctx context.Context, params DeleteProjectParams) error
+	// DeleteProjectAlias implements deleteProjectAlias operation.
+	//
+	// Stop accepting previous project slug.
+	//
+	// DELETE /projects/{project}/aliases/{alias}
+	DeleteProjectAlias(ctx context.Context, params DeleteProjectAliasParams) error
 	// DeleteToken implements deleteToken operation.
 	//
 	// Delete token for user.
 	//
 	// DELETE /tokens/{token}
 	DeleteToken(ctx context.Context, params DeleteTokenParams) error
+	// ExportDocument implements exportDocument operation.
+	//
+	// Export projects and tokens of the user as a versioned document (for backups and cloning
+	// environments). Secrets are never exported.
+	//
+	// GET /export
+	ExportDocument(ctx context.Context, params ExportDocumentParams) (*Document, error)
 	// GetProject implements getProject operation.
 	//
 	// Get project by ID.
@@ -44,6 +57,34 @@ type Handler interface {
 	//
 	// GET /tokens/{token}
 	GetToken(ctx context.Context, params GetTokenParams) (*Token, error)
+	// GetTokenByKey implements getTokenByKey operation.
+	//
+	// Get token by key ID and for the current user.
+	//
+	// GET /tokens/by-key/{keyID}
+	GetTokenByKey(ctx context.Context, params GetTokenByKeyParams) (*Token, error)
+	// IdentifyToken implements identifyToken operation.
+	//
+	// Identify token by full raw key. The key is checked against the stored hash and only tokens of the
+	// current user are reported.
+	//
+	// POST /tokens/identify
+	IdentifyToken(ctx context.Context, req *KeyLookup) (*TokenIdentity, error)
+	// ImportDocument implements importDocument operation.
+	//
+	// Import projects and tokens from the exported document. Owners in the document are replaced by the
+	// user. The document is validated as a whole before any change. Tokens are identified by key ID;
+	// tokens without hash get a new key with the same key ID.
+	//
+	// POST /import
+	ImportDocument(ctx context.Context, req *Document, params ImportDocumentParams) (*ImportReport, error)
+	// ImportTokens implements importTokens operation.
+	//
+	// Import existing keys (for example, from another gateway) instead of generating new ones. Each key is
+	// imported independently; results are returned in the same order as the request items.
+	//
+	// POST /tokens/import
+	ImportTokens(ctx context.Context, req []ImportKey) ([]ImportResult, error)
 	// ListProjects implements listProjects operation.
 	//
 	// List all projects.
@@ -55,10 +96,10 @@ type Handler interface {
 	// List all tokens for user.
 	//
 	// GET /tokens
-	ListTokens(ctx context.Context, params ListTokensParams) ([]Token, error)
+	ListTokens(ctx context.Context, params ListTokensParams) (*ListTokensOKHeaders, error)
 	// RefreshToken implements refreshToken operation.
 	//
-	// Regenerate token key.
+	// Regenerate token key. Enables disabled token.
 	//
 	// POST /tokens/{token}
 	RefreshToken(ctx context.Context, params RefreshTokenParams) (*Credential, error)
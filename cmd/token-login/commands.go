@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/reddec/token-login/api"
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/open"
+	"github.com/reddec/token-login/internal/reconcile"
+	"github.com/reddec/token-login/internal/server"
+	"github.com/reddec/token-login/internal/transfer"
+	"github.com/reddec/token-login/internal/types"
+	"github.com/reddec/token-login/internal/utils"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errImportFailed   = errors.New("some keys were not imported")
+)
+
+// runCommand runs sub-command instead of the server.
+func (cfg Config) runCommand(ctx context.Context, name string) error {
+	switch name {
+	case "import-keys":
+		return cfg.ImportKeys.Run(ctx, cfg)
+	case "export":
+		return cfg.Export.Run(ctx, cfg)
+	case "import":
+		return cfg.Import.Run(ctx, cfg)
+	case "apply":
+		return cfg.Apply.Run(ctx, cfg)
+	case "migrate-db":
+		return cfg.MigrateDB.Run(ctx, cfg)
+	case "backup-db":
+		return cfg.BackupDB.Run(ctx, cfg)
+	default:
+		return fmt.Errorf("%q: %w", name, errUnknownCommand)
+	}
+}
+
+// openStore opens database and creates hasher, the same way as the server does.
+func (cfg Config) openStore(ctx context.Context) (dbo.Store, *types.Hasher, error) {
+	hasher, err := cfg.hasher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("create hasher: %w", err)
+	}
+	store, err := open.Open(ctx, cfg.DB.URL, cfg.configureDatabase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create store: %w", err)
+	}
+	return store, hasher, nil
+}
+
+type ImportKeysCommand struct {
+	User  string `long:"user" required:"true" description:"Owner of imported tokens"`
+	Input string `short:"i" long:"input" description:"JSON file with array of keys to import (same as POST /tokens/import), - for stdin" default:"-"`
+}
+
+// Run imports keys directly to the database and prints per-key results as JSON.
+// Running servers pick up imported tokens on the next cache sync.
+func (cmd *ImportKeysCommand) Run(ctx context.Context, cfg Config) error {
+	items, err := cmd.read()
+	if err != nil {
+		return err
+	}
+
+	store, hasher, err := cfg.openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	srv := server.New(store, server.WithKeyPrefix(cfg.Keys.Prefix), server.WithHasher(hasher))
+	results, err := srv.ImportTokens(utils.WithUser(ctx, cmd.User), items)
+	if err != nil {
+		return fmt.Errorf("import keys: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(results); err != nil {
+		return fmt.Errorf("write results: %w", err)
+	}
+	var failed int
+	for _, res := range results {
+		if res.Error.Set {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d: %w", failed, len(results), errImportFailed)
+	}
+	return nil
+}
+
+func (cmd *ImportKeysCommand) read() ([]api.ImportKey, error) {
+	input, err := openInput(cmd.Input)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	var items []api.ImportKey
+	if err := json.NewDecoder(input).Decode(&items); err != nil {
+		return nil, fmt.Errorf("decode input: %w", err)
+	}
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			return nil, fmt.Errorf("validate key #%d: %w", i, err)
+		}
+	}
+	return items, nil
+}
+
+type ExportCommand struct {
+	User   string `long:"user" description:"Export only projects and tokens of the user"`
+	Hashes bool   `long:"hashes" description:"Include key hashes, so restored tokens keep their keys"`
+	Format string `long:"format" description:"Document format" default:"json" choice:"json" choice:"yaml"`
+	Output string `short:"o" long:"output" description:"Output file, - for stdout" default:"-"`
+}
+
+func (cmd *ExportCommand) Run(ctx context.Context, cfg Config) error {
+	store, _, err := cfg.openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	doc, err := transfer.Export(ctx, store, transfer.ExportOptions{User: cmd.User, Hashes: cmd.Hashes})
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	output, err := openOutput(cmd.Output)
+	if err != nil {
+		return err
+	}
+	if err := doc.Encode(output, transfer.Format(cmd.Format)); err != nil {
+		_ = output.Close()
+		return err //nolint:wrapcheck // already wrapped by transfer
+	}
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("close output: %w", err)
+	}
+	return nil
+}
+
+type ImportCommand struct {
+	User     string `long:"user" description:"Override owner of all projects and tokens"`
+	Conflict string `long:"conflict" description:"What to do with existing projects and tokens" default:"fail" choice:"fail" choice:"skip" choice:"overwrite"`
+	DryRun   bool   `long:"dry-run" description:"Only print planned changes"`
+	Format   string `long:"format" description:"Document format" default:"json" choice:"json" choice:"yaml"`
+	Input    string `short:"i" long:"input" description:"Document file, - for stdin" default:"-"`
+}
+
+// Run imports document and prints report as JSON. New keys of tokens restored without hashes are in the report.
+func (cmd *ImportCommand) Run(ctx context.Context, cfg Config) error {
+	input, err := openInput(cmd.Input)
+	if err != nil {
+		return err
+	}
+	doc, err := transfer.Decode(input, transfer.Format(cmd.Format))
+	_ = input.Close()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped by transfer
+	}
+
+	store, hasher, err := cfg.openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := transfer.Import(ctx, store, doc, transfer.ImportOptions{
+		User:      cmd.User,
+		Conflict:  transfer.Strategy(cmd.Conflict),
+		DryRun:    cmd.DryRun,
+		Hasher:    hasher,
+		KeyPrefix: cfg.Keys.Prefix,
+	})
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+type ApplyCommand struct {
+	Plan  bool   `long:"plan" description:"Only print planned changes"`
+	Input string `short:"i" long:"input" description:"Config file (YAML or JSON), - for stdin" default:"-"`
+}
+
+// Run prints the plan and applies it, unless only the plan is requested.
+func (cmd *ApplyCommand) Run(ctx context.Context, cfg Config) error {
+	input, err := openInput(cmd.Input)
+	if err != nil {
+		return err
+	}
+	config, err := reconcile.Load(input)
+	_ = input.Close()
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped by reconcile
+	}
+
+	store, hasher, err := cfg.openStore(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts := reconcile.Options{Hasher: hasher}
+	plan, err := reconcile.Diff(ctx, store, config, opts)
+	if err != nil {
+		return fmt.Errorf("plan: %w", err)
+	}
+	_, _ = fmt.Fprint(os.Stdout, plan)
+	if cmd.Plan || plan.Empty() {
+		return nil
+	}
+	if err := reconcile.Apply(ctx, store, plan, opts); err != nil {
+		return fmt.Errorf("apply: %w", err)
+	}
+	return nil
+}
+
+type MigrateDBCommand struct {
+	From string `long:"from" required:"true" description:"Source database URL"`
+	To   string `long:"to" required:"true" description:"Target database URL, migrated to the latest schema"`
+}
+
+// Run copies all rows and prints row counts as JSON.
+func (cmd *MigrateDBCommand) Run(ctx context.Context, cfg Config) error {
+	from, err := open.Open(ctx, cmd.From, cfg.configureDatabase)
+	if err != nil {
+		return fmt.Errorf("open source: %w", err)
+	}
+	defer from.Close()
+	to, err := open.Open(ctx, cmd.To, cfg.configureDatabase)
+	if err != nil {
+		return fmt.Errorf("open target: %w", err)
+	}
+	defer to.Close()
+
+	counts, err := dbo.Copy(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(counts); err != nil {
+		return fmt.Errorf("write counts: %w", err)
+	}
+	return nil
+}
+
+type BackupDBCommand struct {
+	Output string `short:"o" long:"output" description:"Backup file (must not exist), - for stdout" default:"-"`
+}
+
+// Run writes consistent copy of the database configured by --db.url. It's safe to run next to the server.
+func (cmd *BackupDBCommand) Run(ctx context.Context, cfg Config) error {
+	store, err := open.Open(ctx, cfg.DB.URL, cfg.configureDatabase)
+	if err != nil {
+		return fmt.Errorf("create store: %w", err)
+	}
+	defer store.Close()
+
+	if cmd.Output != "-" {
+		if err := dbo.Backup(ctx, store, cmd.Output); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "token-login-backup-")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.sqlite")
+	if err := dbo.Backup(ctx, store, path); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(os.Stdout, f); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	return nil
+}
+
+func openInput(file string) (io.ReadCloser, error) {
+	if file == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open input: %w", err)
+	}
+	return f, nil
+}
+
+func openOutput(file string) (io.WriteCloser, error) {
+	if file == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, fmt.Errorf("create output: %w", err)
+	}
+	return f, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -12,7 +12,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -24,10 +26,15 @@ import (
 	oidclogin "github.com/reddec/oidc-login"
 	"github.com/reddec/token-login/api"
 	"github.com/reddec/token-login/internal/cache"
+	"github.com/reddec/token-login/internal/dbo"
 	"github.com/reddec/token-login/internal/dbo/open"
 	"github.com/reddec/token-login/internal/plumbing"
+	"github.com/reddec/token-login/internal/redisbus"
+	"github.com/reddec/token-login/internal/redisleader"
+	"github.com/reddec/token-login/internal/redisstats"
 	"github.com/reddec/token-login/internal/redisstore"
 	"github.com/reddec/token-login/internal/server"
+	"github.com/reddec/token-login/internal/types"
 	"github.com/reddec/token-login/internal/utils"
 	"github.com/reddec/token-login/web"
 	"golang.org/x/crypto/bcrypt"
@@ -52,22 +59,55 @@ type Config struct {
 	Proxy ProxyAuth `group:"Proxy login config" namespace:"proxy" env-namespace:"PROXY"`
 	DB    struct {
 		URL          string        `long:"url" env:"URL" description:"Database URL" default:"sqlite://data.sqlite"`
+		ReadURL      string        `long:"read-url" env:"READ_URL" description:"Database URL of read replica for cache sync and listings (postgres and mysql only)"`
 		MaxConn      int           `long:"max-conn" env:"MAX_CONN" description:"Maximum number of opened connections to database" default:"10"`
 		IdleConn     int           `long:"idle-conn" env:"IDLE_CONN" description:"Maximum number of idle connections to database" default:"1"`
 		IdleTimeout  time.Duration `long:"idle-timeout" env:"IDLE_TIMEOUT" description:"Maximum amount of time a connection may be idle" default:"0"`
 		ConnLifeTime time.Duration `long:"conn-life-time" env:"CONN_LIFE_TIME" description:"Maximum amount of time a connection may be reused" default:"0"`
 	} `group:"Database configuration" namespace:"db" env-namespace:"DB"`
 	Cache struct {
-		TTL time.Duration `long:"ttl" env:"TTL" description:"Maximum live time of token in cache. Also forceful reload time" default:"15s"`
+		TTL      time.Duration `long:"ttl" env:"TTL" description:"Interval of loading changed tokens into cache" default:"15s"`
+		FullSync time.Duration `long:"full-sync" env:"FULL_SYNC" description:"Interval of reloading all tokens into cache. Zero reloads all tokens every TTL" default:"10m"`
+		Redis    bool          `long:"redis" env:"REDIS" description:"Publish token changes to all instances over Redis (see redis.url) and apply them to cache at once"`
 	} `group:"Cache configuration" namespace:"cache" env-namespace:"CACHE"`
+	Keys struct {
+		Prefix     string `long:"prefix" env:"PREFIX" description:"Prefix of issued keys (lowercase letters and digits). Keys with any prefix are accepted" default:"tl"`
+		Hash       string `long:"hash" env:"HASH" description:"Hash algorithm for new keys. Existing keys are upgraded on next use" default:"sha3-384" choice:"sha3-384" choice:"sha256" choice:"hmac-sha256"`
+		Pepper     string `long:"pepper" env:"PEPPER" description:"Current server-side secret for hmac-sha256 hashes"`
+		PepperFile string `long:"pepper-file" env:"PEPPER_FILE" description:"File with current server-side secret for hmac-sha256 hashes"`
+		PepperID   string `long:"pepper-id" env:"PEPPER_ID" description:"ID of current pepper, stored with every hash. Change it when rotating the pepper"`
+		PeppersDir string `long:"peppers-dir" env:"PEPPERS_DIR" description:"Directory with peppers (file name is ID) to verify existing hashes. Current pepper is taken from it by ID if not set otherwise"`
+	} `group:"Keys configuration" namespace:"keys" env-namespace:"KEYS"`
 	Stats struct {
 		Buffer   int           `long:"buffer" env:"BUFFER" description:"Buffer size for hits" default:"2048"`
 		Interval time.Duration `long:"interval" env:"INTERVAL" description:"Statistics interval" default:"5s"`
+		Redis    bool          `long:"redis" env:"REDIS" description:"Aggregate stats of all instances in Redis (see redis.url), written to database by one elected instance"`
 	} `group:"Stats configuration" namespace:"stats" env-namespace:"STATS"`
+	Redis struct {
+		Redis
+		Prefix string `long:"prefix" env:"PREFIX" description:"Prefix of keys and channels, to share Redis between deployments" default:"token-login:"`
+	} `group:"Redis configuration (shared between instances)" namespace:"redis" env-namespace:"REDIS"`
+	Backup struct {
+		Dir      string        `long:"dir" env:"DIR" description:"Directory for periodic SQLite backups" default:"backups"`
+		Interval time.Duration `long:"interval" env:"INTERVAL" description:"Interval of periodic SQLite backups, disabled if zero" default:"0"`
+		Keep     int           `long:"keep" env:"KEEP" description:"Number of periodic backups to keep, all if zero" default:"7"`
+		Users    []string      `long:"users" env:"USERS" description:"Users allowed to download backups from API (disabled if none set)" env-delim:","`
+	} `group:"Backup configuration" namespace:"backup" env-namespace:"BACKUP"`
+	Leaks struct {
+		Enable  bool   `long:"enable" env:"ENABLE" description:"Accept unauthenticated reports of leaked keys at /leaks and disable matched tokens"`
+		Webhook string `long:"webhook" env:"WEBHOOK" description:"URL to post JSON events about disabled tokens, for example to notify owners"`
+	} `group:"Leak reports configuration" namespace:"leaks" env-namespace:"LEAKS"`
 	Debug struct {
 		Enable      bool   `long:"enable" env:"ENABLE" description:"Enable debug mode"`
 		Impersonate string `long:"impersonate" env:"IMPERSONATE" description:"Disable normal auth and use static user name"`
 	} `group:"Debug" namespace:"debug" env-namespace:"DEBUG"`
+
+	ImportKeys ImportKeysCommand `command:"import-keys" description:"Import existing keys from another system instead of running the server"`
+	Export     ExportCommand     `command:"export" description:"Export projects and tokens (without secrets) as JSON or YAML document"`
+	Import     ImportCommand     `command:"import" description:"Import projects and tokens from exported document"`
+	Apply      ApplyCommand      `command:"apply" description:"Converge projects and tokens to declarative config"`
+	MigrateDB  MigrateDBCommand  `command:"migrate-db" description:"Copy all data to another database, for example from SQLite to Postgres"`
+	BackupDB   BackupDBCommand   `command:"backup-db" description:"Write consistent copy of SQLite database without stopping the server"`
 }
 
 type Server struct {
@@ -83,23 +123,25 @@ type Server struct {
 }
 
 type OIDC struct {
-	ClientID     string `long:"client-id" env:"CLIENT_ID" description:"Client ID"`
-	ClientSecret string `long:"client-secret" env:"CLIENT_SECRET" description:"Client secret"`
-	Issuer       string `long:"issuer" env:"ISSUER" description:"OIDC issuer URL"`
-	Session      string `long:"session" env:"SESSION" description:"Session storage" default:"local" choice:"local" choice:"redis"`
-	Redis        struct {
-		URL         string        `long:"url" env:"URL" description:"Redis URL" default:"redis://redis"`
-		KeepAlive   time.Duration `long:"keep-alive" env:"KEEP_ALIVE" description:"Keep-alive interval" default:"30s"`
-		Timeout     time.Duration `long:"timeout" env:"TIMEOUT" description:"Read/Write/Connect timeout" default:"5s"`
-		MaxConn     int           `long:"max-conn" env:"MAX_CONN" description:"Maximum number of active connections" default:"10"`
-		MaxIdle     int           `long:"max-idle" env:"MAX_IDLE" description:"Maximum number of idle connections" default:"1"`
-		IdleTimeout time.Duration `long:"idle-timeout" env:"IDLE_TIMEOUT" description:"Close connections after remaining idle for this duration" default:"30s"`
-	} `group:"OIDC Redis session configuration" namespace:"redis" env-namespace:"REDIS"`
-	ServerURL  string        `long:"server-url" env:"SERVER_URL" description:"(optional) public server URL for redirects"`
-	Emails     []string      `long:"emails" env:"EMAILS" description:"Allowed emails (enabled if at least one set)" env-delim:","`
-	Scopes     []string      `long:"scopes" env:"SCOPES" description:"Additional OAuth scopes (default: openid profile email)" env-delim:","`
-	SessionTTL time.Duration `long:"session-ttl" env:"SESSION_TTL" description:"Session TTL" default:"168h"`
-	TrustProxy bool          `long:"trust-proxy" env:"TRUST_PROXY" description:"Trust X-Forwarded-* headers for redirect URL detection"`
+	ClientID     string        `long:"client-id" env:"CLIENT_ID" description:"Client ID"`
+	ClientSecret string        `long:"client-secret" env:"CLIENT_SECRET" description:"Client secret"`
+	Issuer       string        `long:"issuer" env:"ISSUER" description:"OIDC issuer URL"`
+	Session      string        `long:"session" env:"SESSION" description:"Session storage" default:"local" choice:"local" choice:"redis"`
+	Redis        Redis         `group:"OIDC Redis session configuration" namespace:"redis" env-namespace:"REDIS"`
+	ServerURL    string        `long:"server-url" env:"SERVER_URL" description:"(optional) public server URL for redirects"`
+	Emails       []string      `long:"emails" env:"EMAILS" description:"Allowed emails (enabled if at least one set)" env-delim:","`
+	Scopes       []string      `long:"scopes" env:"SCOPES" description:"Additional OAuth scopes (default: openid profile email)" env-delim:","`
+	SessionTTL   time.Duration `long:"session-ttl" env:"SESSION_TTL" description:"Session TTL" default:"168h"`
+	TrustProxy   bool          `long:"trust-proxy" env:"TRUST_PROXY" description:"Trust X-Forwarded-* headers for redirect URL detection"`
+}
+
+type Redis struct {
+	URL         string        `long:"url" env:"URL" description:"Redis URL" default:"redis://redis"`
+	KeepAlive   time.Duration `long:"keep-alive" env:"KEEP_ALIVE" description:"Keep-alive interval" default:"30s"`
+	Timeout     time.Duration `long:"timeout" env:"TIMEOUT" description:"Read/Write/Connect timeout" default:"5s"`
+	MaxConn     int           `long:"max-conn" env:"MAX_CONN" description:"Maximum number of active connections" default:"10"`
+	MaxIdle     int           `long:"max-idle" env:"MAX_IDLE" description:"Maximum number of idle connections" default:"1"`
+	IdleTimeout time.Duration `long:"idle-timeout" env:"IDLE_TIMEOUT" description:"Close connections after remaining idle for this duration" default:"30s"`
 }
 
 type Basic struct {
@@ -113,6 +155,7 @@ func main() {
 	config.HTTP.Bind = ":8080"
 
 	parser := flags.NewParser(&config, flags.Default)
+	parser.SubcommandsOptional = true
 	parser.ShortDescription = "token-login"
 	parser.LongDescription = fmt.Sprintf("Forward-auth server for tokens\ntoken-login %s, commit %s, built at %s by %s\nAuthor: Aleksandr Baryshnikov <owner@reddec.net>", version, commit, date, builtBy)
 
@@ -120,60 +163,167 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	if parser.Active != nil {
+		config.setupLogging()
+		if err := config.runCommand(ctx, parser.Active.Name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			cancel()
+			os.Exit(1) //nolint:gocritic // cancel is called explicitly
+		}
+		return
+	}
+
 	if err := run(ctx, cancel, config); err != nil {
 		panic(err)
 	}
 }
 
+// statsLeaderTTL is the longest time without writing shared stats after the leader has gone.
+const statsLeaderTTL = 30 * time.Second
+
 func run(ctx context.Context, cancel context.CancelFunc, config Config) error {
 	config.setupLogging()
 
+	if err := types.ValidateKeyPrefix(config.Keys.Prefix); err != nil {
+		return fmt.Errorf("validate key prefix: %w", err)
+	}
+	hasher, err := config.hasher()
+	if err != nil {
+		return fmt.Errorf("create hasher: %w", err)
+	}
+
 	// setup db
 	store, err := open.Open(ctx, config.DB.URL, config.configureDatabase)
 	if err != nil {
 		return fmt.Errorf("create store: %w", err)
 	}
-	defer store.Close()
+	defer func() {
+		if err := store.Close(); err != nil {
+			slog.Error("failed to close store", "error", err)
+		}
+	}()
+
+	// listings and cache sync may lag behind the primary by replication delay
+	reader := store
+	if config.DB.ReadURL != "" {
+		reader, err = open.OpenReplica(ctx, config.DB.ReadURL, config.configureDatabase)
+		if err != nil {
+			return fmt.Errorf("create replica store: %w", err)
+		}
+		defer reader.Close()
+	}
 
 	hitsCache := make(chan web.Hit, config.Stats.Buffer)
-	keysCache := cache.New(store)
+	keysCache := cache.New(store, cache.WithHasher(hasher), cache.WithReader(reader))
 
 	if err := keysCache.SyncKeys(ctx); err != nil {
 		// initial sync
 		return fmt.Errorf("sync keys: %w", err)
 	}
-	srv := server.New(store)
+	if _, ok := store.(dbo.Backuper); !ok && config.Backup.Interval > 0 {
+		return fmt.Errorf("periodic backups: %w", dbo.ErrBackupUnsupported)
+	}
+	srv := server.New(store, server.WithKeyPrefix(config.Keys.Prefix), server.WithHasher(hasher), server.WithReader(reader))
 	apiServer, err := api.NewServer(srv)
 	if err != nil {
 		return fmt.Errorf("create api server: %w", err)
 	}
 	srv.OnRemove(keysCache.Drop)
+	srv.OnDisable(func(token *dbo.Token, reason string) {
+		slog.Warn("token disabled", "id", token.ID, "user", token.User, "key", token.KeyID, "label", token.Label, "reason", reason)
+	})
+	if config.Leaks.Webhook != "" {
+		srv.OnDisable(plumbing.DisabledWebhook(ctx, config.Leaks.Webhook))
+	}
 	srv.OnUpdate(func(id int) {
 		if err := keysCache.SyncKey(ctx, id); err != nil {
 			slog.Error("sync key failed", "id", id, "err", err)
 		}
 	})
 
+	// shared Redis is connected only if it's used
+	var redisPool *redis.Pool
+	if config.Cache.Redis || config.Stats.Redis {
+		redisPool = config.Redis.pool()
+		defer redisPool.Close()
+	}
+
+	var bus *redisbus.Bus
+	if config.Cache.Redis {
+		bus = redisbus.New(redisPool, config.Redis.Prefix+"tokens")
+		publish := func(event dbo.TokenEvent) {
+			if err := bus.Publish(ctx, event); err != nil {
+				slog.Error("failed publish token change", "id", event.ID, "error", err)
+			}
+		}
+		srv.OnUpdate(func(id int) {
+			publish(dbo.TokenEvent{ID: int64(id)})
+		})
+		srv.OnRemove(func(id int) {
+			publish(dbo.TokenEvent{ID: int64(id), Removed: true})
+		})
+	}
+
 	// setup runners
 	var wg multierror.Group
 
 	// setup db->cache key sync
 	wg.Go(func() error {
 		defer cancel()
-		keysCache.PollKeys(ctx, config.Cache.TTL)
+		keysCache.PollKeys(ctx, config.Cache.TTL, config.Cache.FullSync)
+		return nil
+	})
+
+	// setup upgrades of outdated key hashes, out of auth requests
+	wg.Go(func() error {
+		keysCache.RunRehash(ctx)
 		return nil
 	})
 
-	// setup stats->db sync
+	// setup instant cache invalidation by changes of all instances (PostgreSQL)
+	if notifier, ok := store.(dbo.Notifier); ok {
+		wg.Go(func() error {
+			plumbing.ListenChanges(ctx, notifier, keysCache)
+			return nil
+		})
+	}
+	if bus != nil {
+		wg.Go(func() error {
+			plumbing.ListenChanges(ctx, bus, keysCache)
+			return nil
+		})
+	}
+
+	// setup stats->db sync, directly or through Redis buffer flushed by the leader
+	var statsWriter plumbing.StatsWriter = store
+	if config.Stats.Redis {
+		stats := redisstats.New(redisPool, config.Redis.Prefix+"stats:")
+		statsWriter = stats
+		election := redisleader.New(redisPool, config.Redis.Prefix+"stats:leader", statsLeaderTTL)
+		wg.Go(func() error {
+			election.Run(ctx, func(ctx context.Context) {
+				stats.RunFlush(ctx, store, config.Stats.Interval)
+			})
+			return nil
+		})
+	}
 	wg.Go(func() error {
 		defer cancel()
-		plumbing.SyncStats(ctx, store, hitsCache, config.Stats.Interval)
+		plumbing.SyncStats(ctx, statsWriter, hitsCache, config.Stats.Interval)
 		return nil
 	})
 
+	// setup periodic backups
+	if config.Backup.Interval > 0 {
+		wg.Go(func() error {
+			plumbing.RunBackups(ctx, store, config.Backup.Dir, config.Backup.Interval, config.Backup.Keep)
+			return nil
+		})
+	}
+
 	// setup HTTP server
 	router := chi.NewRouter()
 	if config.Debug.Enable {
@@ -194,10 +344,16 @@ func run(ctx context.Context, cancel context.CancelFunc, config Config) error {
 		writer.WriteHeader(http.StatusNoContent)
 	})
 	router.Mount("/auth", web.AuthHandler(keysCache, hitsCache))
+	if config.Leaks.Enable {
+		router.Mount("/leaks", web.LeakReportHandler(keysCache, srv))
+	}
 
 	authMW := config.authMiddleware(ctx, router)
 
 	router.With(authMW).Route("/", func(r chi.Router) {
+		if backuper, ok := store.(dbo.Backuper); ok && len(config.Backup.Users) > 0 {
+			r.Get(api.Prefix+"/backup", web.BackupHandler(backuper, config.Backup.Users).ServeHTTP)
+		}
 		r.Mount(api.Prefix+"/", http.StripPrefix(api.Prefix, apiServer))
 		r.Mount("/", http.FileServerFS(web.Assets()))
 	})
@@ -213,6 +369,34 @@ func run(ctx context.Context, cancel context.CancelFunc, config Config) error {
 	return wg.Wait().ErrorOrNil()
 }
 
+func (cfg Config) hasher() (*types.Hasher, error) {
+	var previous []types.Pepper
+	if cfg.Keys.PeppersDir != "" {
+		list, err := types.ReadPeppers(cfg.Keys.PeppersDir)
+		if err != nil {
+			return nil, fmt.Errorf("load peppers: %w", err)
+		}
+		previous = list
+	}
+	var current *types.Pepper
+	switch {
+	case cfg.Keys.Pepper != "":
+		current = &types.Pepper{ID: cfg.Keys.PepperID, Secret: []byte(cfg.Keys.Pepper)}
+	case cfg.Keys.PepperFile != "":
+		p, err := types.ReadPepper(cfg.Keys.PepperID, cfg.Keys.PepperFile)
+		if err != nil {
+			return nil, fmt.Errorf("load current pepper: %w", err)
+		}
+		current = &p
+	default:
+		if i := slices.IndexFunc(previous, func(p types.Pepper) bool { return p.ID == cfg.Keys.PepperID }); i >= 0 {
+			current = &previous[i]
+		}
+	}
+	//nolint:wrapcheck // the caller adds context
+	return types.NewHasher(types.HashAlgorithm(cfg.Keys.Hash), current, previous...)
+}
+
 func (cfg Config) configureDatabase(db *sql.DB) {
 	db.SetMaxIdleConns(cfg.DB.IdleConn)
 	db.SetMaxOpenConns(cfg.DB.MaxConn)
@@ -405,21 +589,7 @@ func (cfg *OIDC) createMiddleware(ctx context.Context, router chi.Router) func(h
 		},
 	}
 	if cfg.Session == "redis" {
-		pool := &redis.Pool{
-			Dial: func() (redis.Conn, error) {
-				return redis.DialURL(cfg.Redis.URL,
-					redis.DialKeepAlive(cfg.Redis.KeepAlive),
-					redis.DialWriteTimeout(cfg.Redis.Timeout),
-					redis.DialReadTimeout(cfg.Redis.Timeout),
-					redis.DialConnectTimeout(cfg.Redis.Timeout),
-				)
-			},
-			MaxIdle:     cfg.Redis.MaxIdle,
-			MaxActive:   cfg.Redis.MaxConn,
-			IdleTimeout: cfg.Redis.IdleTimeout,
-			Wait:        true,
-		}
-		oidcCfg.SessionStore = redisstore.New(pool)
+		oidcCfg.SessionStore = redisstore.New(cfg.Redis.pool())
 	}
 	login, err := oidclogin.New(ctx, oidcCfg)
 	if err != nil {
@@ -434,6 +604,23 @@ func (cfg *OIDC) createMiddleware(ctx context.Context, router chi.Router) func(h
 	}
 }
 
+func (cfg Redis) pool() *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(cfg.URL,
+				redis.DialKeepAlive(cfg.KeepAlive),
+				redis.DialWriteTimeout(cfg.Timeout),
+				redis.DialReadTimeout(cfg.Timeout),
+				redis.DialConnectTimeout(cfg.Timeout),
+			)
+		},
+		MaxIdle:     cfg.MaxIdle,
+		MaxActive:   cfg.MaxConn,
+		IdleTimeout: cfg.IdleTimeout,
+		Wait:        true,
+	}
+}
+
 func (cfg *Basic) createMiddleware(router chi.Router) func(http.Handler) http.Handler {
 	const flash = "_unauth"
 	// mimic behaviour
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/transfer"
+)
+
+func TestRunSavesMemoryStore(t *testing.T) {
+	save := filepath.Join(t.TempDir(), "tokens.json")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	var config Config
+	parser := flags.NewParser(&config, flags.Default)
+	parser.SubcommandsOptional = true
+	_, err = parser.ParseArgs([]string{
+		"--db.url", "memory://?save=" + save,
+		"--http.bind", addr,
+		"--debug.impersonate", "alice",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, cancel, config)
+	}()
+
+	base := "http://" + addr
+	require.Eventually(t, func() bool {
+		res, err := http.Get(base + "/health") //nolint:noctx
+		if err != nil {
+			return false
+		}
+		_ = res.Body.Close()
+		return res.StatusCode == http.StatusNoContent
+	}, 5*time.Second, 50*time.Millisecond)
+
+	post := func(path string, body, out any) {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		res, err := http.Post(base+"/api/v1"+path, "application/json", bytes.NewReader(data)) //nolint:noctx
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+	}
+	var project struct {
+		ID int `json:"id"`
+	}
+	post("/projects", map[string]any{"slug": "ci"}, &project)
+	var credential struct {
+		ID int `json:"id"`
+	}
+	post("/tokens", map[string]any{"projectId": project.ID, "label": "runtime"}, &credential)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "run is not stopped")
+	}
+
+	f, err := os.Open(save)
+	require.NoError(t, err)
+	defer f.Close()
+	doc, err := transfer.Decode(f, transfer.JSON)
+	require.NoError(t, err)
+	require.Len(t, doc.Tokens, 1)
+	assert.Equal(t, "alice", doc.Tokens[0].User)
+	assert.Equal(t, "runtime", doc.Tokens[0].Label)
+	assert.Equal(t, "ci", doc.Tokens[0].Project)
+	assert.NotNil(t, doc.Tokens[0].Hash)
+}
+
+func TestRunRejectsKeyPrefixBeforeDatabase(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "data.sqlite")
+	var config Config
+	parser := flags.NewParser(&config, flags.Default)
+	parser.SubcommandsOptional = true
+	_, err := parser.ParseArgs([]string{"--db.url", "sqlite://" + dbFile, "--keys.prefix", "Bad_Prefix"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.Error(t, run(ctx, cancel, config))
+	assert.NoFileExists(t, dbFile, "database is not touched")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -11,24 +12,105 @@ import (
 	"github.com/reddec/token-login/internal/types"
 )
 
-type State map[types.KeyID]*Token
+const (
+	// rehashQueue is the number of pending hash upgrades. Upgrades over it are dropped and retried on the next use.
+	rehashQueue = 128
+	// rehashTimeout limits a single hash upgrade, so a locked database doesn't stall the queue.
+	rehashTimeout = 5 * time.Second
+)
+
+// changesOverlap is how far back each incremental sync looks before the previous one. Changes become
+// visible on commit (or replication) a bit later than they are timed, so windows overlap to not miss them.
+const changesOverlap = 30 * time.Second
+
+// State holds tokens by key ID. Several tokens may share key ID (collision); they are told apart by hash.
+type State map[types.KeyID][]*Token
 
 type Token struct {
 	AccessKey *types.AccessKey
+	Headers   types.HeaderTemplates
 	DBToken   *dbo.Token
 }
 
+// NewToken prepares token for authorization: compiles access rules and
+// header templates using effective (project-inherited) values.
+func NewToken(t *dbo.Token, hasher *types.Hasher) (*Token, error) {
+	ak, err := types.NewHashedAccessKey(hasher, t.HashSpec(), t.Hash, t.EffectiveHosts(), t.EffectivePaths())
+	if err != nil {
+		return nil, fmt.Errorf("create access key: %w", err)
+	}
+	headers, err := types.CompileHeaders(t.EffectiveHeaders())
+	if err != nil {
+		return nil, fmt.Errorf("compile headers: %w", err)
+	}
+	return &Token{
+		AccessKey: ak,
+		Headers:   headers,
+		DBToken:   t,
+	}, nil
+}
+
+// Option customizes Cache.
+type Option func(v *Cache)
+
+// WithHasher sets hasher to verify keys (types.DefaultHasher by default).
+func WithHasher(hasher *types.Hasher) Option {
+	return func(v *Cache) {
+		v.hasher = hasher
+	}
+}
+
+// WithReader sets store for full syncs, for example a read replica (the main store by default).
+// Single tokens are synced from the main store, so changes are visible right after they are made.
+func WithReader(store dbo.Store) Option {
+	return func(v *Cache) {
+		v.reader = store
+	}
+}
+
 type Cache struct {
-	store dbo.Store
-	state struct {
+	store  dbo.Store
+	reader dbo.Store
+	hasher *types.Hasher
+	state  struct {
 		data State
-		lock sync.RWMutex
+		// ids are key IDs of cached tokens, to find tokens by ID.
+		ids map[int64]types.KeyID
+		// prefixes are distinct lengths of imported key prefixes, ascending.
+		prefixes []int
+		lock     sync.RWMutex
+	}
+	sync struct {
+		// watermark is the database time of the last sync, fullAt and syncedAt - local times
+		// of the last full and any sync.
+		watermark time.Time
+		fullAt    time.Time
+		syncedAt  time.Time
+		lock      sync.Mutex
+	}
+	rehash struct {
+		queue chan rehashJob
+		// tried are hashes which were queued for upgrade by token ID. The hash is not upgraded again
+		// until the cached token gets another one, even if it's stale (for example, synced from replica).
+		tried map[int64]string
+		lock  sync.Mutex
 	}
 }
 
-func New(store dbo.Store) *Cache {
-	v := &Cache{store: store}
+type rehashJob struct {
+	token   *Token
+	payload []byte
+}
+
+func New(store dbo.Store, opts ...Option) *Cache {
+	v := &Cache{store: store, reader: store, hasher: types.DefaultHasher}
+	for _, opt := range opts {
+		opt(v)
+	}
 	v.state.data = make(State)
+	v.state.ids = make(map[int64]types.KeyID)
+	v.rehash.queue = make(chan rehashJob, rehashQueue)
+	v.rehash.tried = make(map[int64]string)
 	return v
 }
 
@@ -36,43 +118,132 @@ func (v *Cache) Set(state State) {
 	v.state.lock.Lock()
 	defer v.state.lock.Unlock()
 	v.state.data = state
+	v.state.ids = make(map[int64]types.KeyID)
+	v.state.prefixes = v.state.prefixes[:0]
+	for kid, list := range state {
+		for _, t := range list {
+			v.state.ids[t.DBToken.ID] = kid
+			v.addPrefix(t)
+		}
+	}
 }
 
-// Patch updates state in-place.
+// Patch updates state in-place: replaces cached token with the same ID under the key ID or adds a new one.
 // DO NOT use it for mass update - it locks the whole workflow.
 func (v *Cache) Patch(kid types.KeyID, key *Token) {
 	v.state.lock.Lock()
 	defer v.state.lock.Unlock()
-	v.state.data[kid] = key
+	if old, ok := v.state.ids[key.DBToken.ID]; ok && old != kid {
+		v.drop(key.DBToken.ID)
+	}
+	v.state.data[kid] = withToken(v.state.data[kid], key)
+	v.state.ids[key.DBToken.ID] = kid
+	v.addPrefix(key)
 }
 
 func (v *Cache) Drop(id int) {
-	// note: for huge (thousands) keys we may want to create secondary index (O(1)) instead of linear search (O(N))
 	v.state.lock.Lock()
 	defer v.state.lock.Unlock()
-	for k, a := range v.state.data {
-		if a.DBToken.ID == int64(id) {
-			delete(v.state.data, k)
-			break
+	v.drop(int64(id))
+}
+
+// FindByKey returns token of the key. Tokens sharing the key ID are told apart by hash.
+func (v *Cache) FindByKey(key types.Key) (*Token, bool) {
+	v.state.lock.RLock()
+	defer v.state.lock.RUnlock()
+	for _, t := range v.state.data[key.ID()] {
+		if t.DBToken.KeyFormat != types.KeyImported && t.AccessKey.Matches(key.Payload()) {
+			return t, true
 		}
 	}
+	return nil, false
 }
 
-func (v *Cache) FindByKey(kid types.KeyID) (*Token, bool) {
-	// note: support multiple KID (collisions)
+// FindImported returns token of the imported key. The key is tried against every known prefix length.
+func (v *Cache) FindImported(raw string) (*Token, bool) {
 	v.state.lock.RLock()
 	defer v.state.lock.RUnlock()
-	t, ok := v.state.data[kid]
-	return t, ok
+	for _, n := range v.state.prefixes {
+		if len(raw) <= n {
+			break
+		}
+		prefix := raw[:n]
+		for _, t := range v.state.data[types.ImportedKeyID(prefix)] {
+			if t.DBToken.KeyFormat == types.KeyImported && t.DBToken.KeyPrefix == prefix && t.AccessKey.Matches([]byte(raw)) {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// replace token everywhere, since key ID changes after refresh.
+func (v *Cache) replace(kid types.KeyID, key *Token) {
+	v.state.lock.Lock()
+	defer v.state.lock.Unlock()
+	v.add(kid, key)
+}
+
+func (v *Cache) add(kid types.KeyID, key *Token) {
+	v.drop(key.DBToken.ID)
+	v.state.data[kid] = append(v.state.data[kid], key)
+	v.state.ids[key.DBToken.ID] = kid
+	v.addPrefix(key)
 }
 
-func (v *Cache) PollKeys(ctx context.Context, interval time.Duration) {
+// addPrefix remembers prefix length of the imported token. Stale lengths are kept until the next full sync.
+func (v *Cache) addPrefix(key *Token) {
+	if key.DBToken.KeyFormat != types.KeyImported {
+		return
+	}
+	n := len(key.DBToken.KeyPrefix)
+	if i, found := slices.BinarySearch(v.state.prefixes, n); !found {
+		v.state.prefixes = slices.Insert(v.state.prefixes, i, n)
+	}
+}
+
+func (v *Cache) drop(id int64) {
+	kid, ok := v.state.ids[id]
+	if !ok {
+		return
+	}
+	delete(v.state.ids, id)
+	list := slices.DeleteFunc(v.state.data[kid], func(t *Token) bool {
+		return t.DBToken.ID == id
+	})
+	if len(list) == 0 {
+		delete(v.state.data, kid)
+	} else {
+		v.state.data[kid] = list
+	}
+}
+
+func withToken(list []*Token, key *Token) []*Token {
+	for i, t := range list {
+		if t.DBToken.ID == key.DBToken.ID {
+			list[i] = key
+			return list
+		}
+	}
+	return append(list, key)
+}
+
+// PollKeys loads changed tokens every interval and reloads all tokens every fullInterval,
+// as a safety net. All tokens are reloaded every interval if fullInterval is not positive.
+func (v *Cache) PollKeys(ctx context.Context, interval, fullInterval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		if err := v.SyncKeys(ctx); err != nil {
-			slog.Error("failed sync keys", "error", err)
+		v.sync.lock.Lock()
+		full := fullInterval <= 0 || time.Since(v.sync.fullAt) >= fullInterval
+		v.sync.lock.Unlock()
+		if full {
+			if err := v.SyncKeys(ctx); err != nil {
+				slog.Error("failed sync keys", "error", err)
+			}
+		} else if err := v.SyncChanges(ctx); err != nil {
+			slog.Error("failed sync changed keys", "error", err)
 		}
 		select {
 		case <-ctx.Done():
@@ -82,28 +253,86 @@ func (v *Cache) PollKeys(ctx context.Context, interval time.Duration) {
 	}
 }
 
+// SyncKeys reloads all tokens.
 func (v *Cache) SyncKeys(ctx context.Context) error {
-	all, err := v.store.ListAllTokens(ctx)
+	v.sync.lock.Lock()
+	defer v.sync.lock.Unlock()
+	return v.syncKeys(ctx)
+}
+
+func (v *Cache) syncKeys(ctx context.Context) error {
+	changes, err := v.reader.ListTokenChanges(ctx, time.Time{})
 	if err != nil {
 		return fmt.Errorf("query all tokens: %w", err)
 	}
 
+	all := changes.Tokens
 	state := make(State, len(all))
 
 	for _, t := range all {
-		ak, err := types.NewAccessKey(t.Hash, t.Hosts, t.Paths)
+		if t.Disabled() {
+			continue
+		}
+		token, err := NewToken(t, v.hasher)
 		if err != nil {
-			slog.Warn("failed to create access key", "id", t.ID, "user", t.User, "error", err)
+			slog.Warn("failed to prepare token", "id", t.ID, "user", t.User, "error", err)
 			continue
 		}
 
-		state[*t.KeyID] = &Token{
-			AccessKey: ak,
-			DBToken:   t,
+		if len(state[*t.KeyID]) > 0 {
+			slog.Warn("key ID collision", "key", t.KeyID, "id", t.ID)
 		}
+		state[*t.KeyID] = append(state[*t.KeyID], token)
 	}
 
 	v.Set(state)
+	now := time.Now()
+	v.sync.watermark = changes.At
+	v.sync.fullAt = now
+	v.sync.syncedAt = now
+	return nil
+}
+
+// SyncChanges loads tokens changed or deleted since the previous sync. All tokens are reloaded if
+// there was no sync before or it was too long ago to know deleted tokens (see dbo.TombstoneRetention).
+func (v *Cache) SyncChanges(ctx context.Context) error {
+	v.sync.lock.Lock()
+	defer v.sync.lock.Unlock()
+	if v.sync.watermark.IsZero() || time.Since(v.sync.syncedAt) > dbo.TombstoneRetention-changesOverlap {
+		return v.syncKeys(ctx)
+	}
+	changes, err := v.reader.ListTokenChanges(ctx, v.sync.watermark.Add(-changesOverlap))
+	if err != nil {
+		return fmt.Errorf("query changed tokens: %w", err)
+	}
+
+	// access rules are compiled before locking the state
+	tokens := make([]*Token, 0, len(changes.Tokens))
+	for _, t := range changes.Tokens {
+		if t.Disabled() {
+			changes.Deleted = append(changes.Deleted, t.ID)
+			continue
+		}
+		token, err := NewToken(t, v.hasher)
+		if err != nil {
+			slog.Warn("failed to prepare token", "id", t.ID, "user", t.User, "error", err)
+			changes.Deleted = append(changes.Deleted, t.ID)
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	v.state.lock.Lock()
+	for _, id := range changes.Deleted {
+		v.drop(id)
+	}
+	for _, token := range tokens {
+		v.add(*token.DBToken.KeyID, token)
+	}
+	v.state.lock.Unlock()
+
+	v.sync.watermark = changes.At
+	v.sync.syncedAt = time.Now()
 	return nil
 }
 
@@ -112,15 +341,95 @@ func (v *Cache) SyncKey(ctx context.Context, id int) error {
 	if err != nil {
 		return fmt.Errorf("get token %v: %w", id, err)
 	}
+	if t.Disabled() {
+		v.Drop(id)
+		return nil
+	}
 
-	aKey, err := types.NewAccessKey(t.Hash, t.Hosts, t.Paths)
+	token, err := NewToken(t, v.hasher)
 	if err != nil {
-		return fmt.Errorf("create access key %v: %w", id, err)
+		return fmt.Errorf("prepare token %v: %w", id, err)
 	}
 
-	v.Patch(*t.KeyID, &Token{
-		AccessKey: aKey,
-		DBToken:   t,
-	})
+	v.replace(*t.KeyID, token)
 	return nil
 }
+
+// Rehash upgrades hash of the token to the preferred algorithm. The payload is the hashed part of the key,
+// and it must be already verified. It's no-op if the hash is up to date or was changed concurrently.
+func (v *Cache) Rehash(ctx context.Context, token *Token, payload []byte) error {
+	_, err := v.rehashToken(ctx, token, payload)
+	return err
+}
+
+// ScheduleRehash queues upgrade of outdated hash of the token for RunRehash, without waiting for the database.
+// The payload is the hashed part of the key, and it must be already verified. Every hash is queued once,
+// and it's dropped if the queue is full.
+func (v *Cache) ScheduleRehash(token *Token, payload []byte) {
+	if !v.hasher.Outdated(token.DBToken.HashSpec()) {
+		return
+	}
+	id, hash := token.DBToken.ID, string(token.DBToken.Hash)
+	v.rehash.lock.Lock()
+	defer v.rehash.lock.Unlock()
+	if v.rehash.tried[id] == hash {
+		return
+	}
+	select {
+	case v.rehash.queue <- rehashJob{token: token, payload: slices.Clone(payload)}:
+		v.rehash.tried[id] = hash
+	default:
+	}
+}
+
+// RunRehash upgrades hashes queued by ScheduleRehash until the context is canceled.
+func (v *Cache) RunRehash(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-v.rehash.queue:
+			jobCtx, cancel := context.WithTimeout(ctx, rehashTimeout)
+			changed, err := v.rehashToken(jobCtx, job.token, job.payload)
+			cancel()
+			if err != nil {
+				slog.Warn("failed upgrade key hash", "id", job.token.DBToken.ID, "error", err)
+			}
+			if err != nil || changed {
+				// failed upgrade is retried on the next use, and the upgraded token has a new hash
+				v.rehash.lock.Lock()
+				delete(v.rehash.tried, job.token.DBToken.ID)
+				v.rehash.lock.Unlock()
+			}
+		}
+	}
+}
+
+// rehashToken upgrades hash of the token and reports whether it was changed.
+func (v *Cache) rehashToken(ctx context.Context, token *Token, payload []byte) (bool, error) {
+	if !v.hasher.Outdated(token.DBToken.HashSpec()) {
+		return false, nil
+	}
+	spec := v.hasher.Preferred()
+	hash, err := v.hasher.Hash(spec, payload)
+	if err != nil {
+		return false, fmt.Errorf("hash key: %w", err)
+	}
+	changed, err := v.store.RehashToken(ctx, token.DBToken.ID, token.DBToken.Hash, hash, spec)
+	if err != nil {
+		return false, fmt.Errorf("rehash token %v: %w", token.DBToken.ID, err)
+	}
+	if changed == 0 {
+		return false, nil
+	}
+	updated := *token.DBToken
+	updated.Hash = hash
+	updated.HashAlg = spec.Algorithm
+	updated.PepperID = spec.PepperID
+	fresh, err := NewToken(&updated, v.hasher)
+	if err != nil {
+		return true, fmt.Errorf("prepare token %v: %w", updated.ID, err)
+	}
+	v.Patch(*updated.KeyID, fresh)
+	return true, nil
+}
@@ -0,0 +1,37 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrRowCount is returned by Copy if the target doesn't have the same number of rows as the source.
+var ErrRowCount = errors.New("row counts differ")
+
+// Copy all rows from one store to another, keeping IDs, hashes and stats, and verify row counts.
+// Rows with the same IDs in the target are replaced, so copy is safe to re-run. Rows which exist
+// only in the target are kept and reported as a count mismatch.
+//
+// The source should not be modified during the copy.
+func Copy(ctx context.Context, from, to Store) (RowCounts, error) {
+	want, err := from.CountRows(ctx)
+	if err != nil {
+		return want, fmt.Errorf("count source rows: %w", err)
+	}
+	snapshot, err := from.Snapshot(ctx)
+	if err != nil {
+		return want, fmt.Errorf("read source: %w", err)
+	}
+	if err := to.Restore(ctx, snapshot); err != nil {
+		return want, fmt.Errorf("write target: %w", err)
+	}
+	got, err := to.CountRows(ctx)
+	if err != nil {
+		return want, fmt.Errorf("count target rows: %w", err)
+	}
+	if got != want {
+		return want, fmt.Errorf("source %+v, target %+v: %w", want, got, ErrRowCount)
+	}
+	return want, nil
+}
@@ -0,0 +1,146 @@
+package memory_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/memory"
+	"github.com/reddec/token-login/internal/types"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "tokens.json")
+	url := "memory://?load=" + file + "&save=" + file
+
+	// missing load file is fine when it's the save file
+	store, err := memory.Open(ctx, url)
+	require.NoError(t, err)
+
+	project, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "web", Hosts: []string{"example.com"}})
+	require.NoError(t, err)
+	require.NoError(t, store.AddProjectAlias(ctx, "alice", project.ID, "www"))
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	token, err := store.CreateToken(ctx, dbo.CreateTokenParams{
+		User:      "alice",
+		Hash:      key.Hash(),
+		HashSpec:  types.HashSpec{Algorithm: types.HashSHA3},
+		KeyID:     &kid,
+		Label:     "ci",
+		ProjectID: project.ID,
+	})
+	require.NoError(t, err)
+	disabledAt := time.Now().Add(-time.Hour)
+	_, err = store.DisableToken(ctx, token.ID, "leaked", disabledAt)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+	require.NoError(t, store.Close(), "second close is no-op")
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	restored, err := memory.Open(ctx, url)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	got, err := restored.FindTokenByKeyID(ctx, kid)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", got.Label)
+	assert.Equal(t, "web", got.ProjectSlug)
+	assert.Equal(t, []string{"www"}, got.ProjectAliases)
+	assert.Equal(t, []string{"example.com"}, got.ProjectHosts)
+	assert.True(t, types.DefaultHasher.Verify(got.HashSpec(), key.Payload(), got.Hash))
+	require.NotNil(t, got.DisabledAt)
+	assert.True(t, disabledAt.Equal(*got.DisabledAt), "disable time is kept")
+	assert.Equal(t, "leaked", got.DisabledReason)
+
+	_, err = memory.Open(ctx, "memory://?load="+filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestOpenShared(t *testing.T) {
+	ctx := context.Background()
+	first, err := memory.Open(ctx, "memory://shared")
+	require.NoError(t, err)
+	second, err := memory.Open(ctx, "memory://shared")
+	require.NoError(t, err)
+	other, err := memory.Open(ctx, "memory://")
+	require.NoError(t, err)
+	defer other.Close()
+
+	_, err = first.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "web"})
+	require.NoError(t, err)
+
+	projects, err := second.ListProjects(ctx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, projects, 1)
+
+	projects, err = other.ListProjects(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, projects, "unnamed stores are not shared")
+
+	// data is released with the last handle
+	require.NoError(t, first.Close())
+	require.NoError(t, first.Close())
+	projects, err = second.ListProjects(ctx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, projects, 1)
+	require.NoError(t, second.Close())
+
+	reopened, err := memory.Open(ctx, "memory://shared")
+	require.NoError(t, err)
+	defer reopened.Close()
+	projects, err = reopened.ListProjects(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestListTokens(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	project, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: ""})
+	require.NoError(t, err)
+	for _, label := range []string{"b", "A", "c"} {
+		key, err := types.NewKey()
+		require.NoError(t, err)
+		kid := key.ID()
+		_, err = store.CreateToken(ctx, dbo.CreateTokenParams{User: "alice", Hash: key.Hash(), KeyID: &kid, Label: label, Meta: types.Meta{"label": label}, ProjectID: project.ID})
+		require.NoError(t, err)
+	}
+
+	page, err := store.ListTokens(ctx, dbo.ListTokensParams{User: "alice", Sort: dbo.SortLabel, Asc: true, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "A", page[0].Label)
+	assert.Equal(t, "b", page[1].Label)
+
+	last := page[1]
+	page, err = store.ListTokens(ctx, dbo.ListTokensParams{
+		User: "alice", Sort: dbo.SortLabel, Asc: true,
+		After: &dbo.TokenCursor{ID: last.ID, Label: last.Label},
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "c", page[0].Label)
+
+	// newest first by default
+	page, err = store.ListTokens(ctx, dbo.ListTokensParams{User: "alice"})
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	assert.Equal(t, "c", page[0].Label)
+
+	page, err = store.ListTokens(ctx, dbo.ListTokensParams{User: "alice", Meta: types.Meta{"label": "b"}})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "b", page[0].Label)
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/transfer"
+)
+
+var (
+	registryLock sync.Mutex
+	registry     = make(map[string]*shared)
+)
+
+// shared is a named store used by all handles opened with the same name.
+type shared struct {
+	*store
+	refs int
+	save string
+}
+
+// handle closes the shared store once.
+type handle struct {
+	*shared
+	name   string
+	closed atomic.Bool
+}
+
+// Open returns a store for the URL memory://[name][?load=path][&save=path].
+//
+// Handles with the same non-empty name share data within the process until the last of them
+// is closed. The store is seeded from the export document (JSON or YAML) at the load path when
+// it's created. On the last Close, projects and tokens (with hashes) are written to the save path
+// as a JSON document. Usage stats are not saved.
+//
+// The load file may be missing if it's the same as the save file, so the URL
+// memory://?load=tokens.json&save=tokens.json persists data between restarts.
+func Open(ctx context.Context, rawURL string) (dbo.Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse DSN: %w", err)
+	}
+	name := u.Host
+	query := u.Query()
+	load, save := query.Get("load"), query.Get("save")
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if s, ok := registry[name]; ok && name != "" {
+		s.refs++
+		return &handle{shared: s, name: name}, nil
+	}
+	s := &shared{store: newStore(), refs: 1, save: save}
+	if load != "" {
+		if err := s.load(ctx, load, load == save); err != nil {
+			return nil, err
+		}
+	}
+	if name != "" {
+		registry[name] = s
+	}
+	return &handle{shared: s, name: name}, nil
+}
+
+func (s *shared) load(ctx context.Context, path string, allowMissing bool) error {
+	f, err := os.Open(path)
+	if allowMissing && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open document: %w", err)
+	}
+	defer f.Close()
+	// JSON is also valid YAML
+	doc, err := transfer.Decode(f, transfer.YAML)
+	if err != nil {
+		return fmt.Errorf("decode document %s: %w", path, err)
+	}
+	report, err := transfer.Import(ctx, s.store, doc, transfer.ImportOptions{
+		// keys are verified by the server, which knows the peppers
+		AnyPepper: true,
+	})
+	if err != nil {
+		return fmt.Errorf("import document %s: %w", path, err)
+	}
+	for _, change := range report.Tokens {
+		if change.Key != "" {
+			slog.Warn("token without hash got a new key, export it with hashes to keep keys", "key_id", change.KeyID, "user", change.User)
+		}
+	}
+	slog.Info("memory store loaded", "path", path, "projects", len(report.Projects), "tokens", len(report.Tokens))
+	return nil
+}
+
+func (h *handle) Close() error {
+	if !h.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	registryLock.Lock()
+	h.refs--
+	last := h.refs == 0
+	if last && h.name != "" {
+		delete(registry, h.name)
+	}
+	registryLock.Unlock()
+	if !last || h.save == "" {
+		return nil
+	}
+	return h.persist(context.Background())
+}
+
+// persist writes the document into a temporary file, which replaces the target,
+// so the previous document stays intact on failure.
+func (s *shared) persist(ctx context.Context) error {
+	doc, err := transfer.Export(ctx, s.store, transfer.ExportOptions{Hashes: true})
+	if err != nil {
+		return fmt.Errorf("export store: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.save), filepath.Base(s.save)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := doc.Encode(tmp, transfer.JSON); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write document: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close document: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.save); err != nil {
+		return fmt.Errorf("replace document %s: %w", s.save, err)
+	}
+	slog.Info("memory store saved", "path", s.save)
+	return nil
+}
@@ -0,0 +1,887 @@
+// Package memory provides a dbo.Store kept in process memory, for tests and deployments without disk.
+// It follows the semantics of the SQL stores: the same uniqueness and reference checks, cascades and
+// ordering.
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/types"
+)
+
+var (
+	errNoProject     = errors.New("project does not exist")
+	errKeyIDInUse    = errors.New("key ID is already in use")
+	errAlreadyLinked = errors.New("project is already linked")
+	errProjectInUse  = errors.New("project with the same user and slug already exists")
+)
+
+type store struct {
+	mu sync.RWMutex
+	// last used IDs
+	projectSeq, aliasSeq, tokenSeq int64
+	projects                       map[int64]*dbo.Project
+	aliases                        map[int64]*dbo.ProjectAlias
+	// tokens keep own fields only; project fields, aliases and links are resolved on read
+	tokens map[int64]*dbo.Token
+	// links are additional projects of tokens in order of linking
+	links map[int64][]int64
+	// changed are times of the last change of tokens, like changed_at column of the SQL stores
+	changed    map[int64]time.Time
+	tombstones []tombstone
+}
+
+type tombstone struct {
+	id int64
+	at time.Time
+}
+
+// NewStore creates an empty in-memory store. Close is no-op.
+func NewStore() dbo.Store {
+	return newStore()
+}
+
+func newStore() *store {
+	return &store{
+		projects: make(map[int64]*dbo.Project),
+		aliases:  make(map[int64]*dbo.ProjectAlias),
+		tokens:   make(map[int64]*dbo.Token),
+		links:    make(map[int64][]int64),
+		changed:  make(map[int64]time.Time),
+	}
+}
+
+func (s *store) Close() error {
+	return nil
+}
+
+func (s *store) CreateToken(_ context.Context, p dbo.CreateTokenParams) (*dbo.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkProject(p.ProjectID); err != nil {
+		return nil, fmt.Errorf("create token: %w", err)
+	}
+	if s.keyIDInUse(*p.KeyID, 0) {
+		return nil, fmt.Errorf("create token %s: %w", p.KeyID, errKeyIDInUse)
+	}
+	if err := s.checkLinks(p.LinkedProjectIDs); err != nil {
+		return nil, err
+	}
+	s.tokenSeq++
+	now := time.Now().UTC()
+	kid := *p.KeyID
+	row := &dbo.Token{
+		ID:           s.tokenSeq,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		KeyID:        &kid,
+		Hash:         slices.Clone(p.Hash),
+		HashAlg:      p.HashSpec.Algorithm,
+		PepperID:     p.HashSpec.PepperID,
+		User:         p.User,
+		Label:        p.Label,
+		Hosts:        slices.Clone(p.Hosts),
+		Paths:        slices.Clone(p.Paths),
+		Headers:      headersOrEmpty(p.Headers),
+		Meta:         metaOrEmpty(p.Meta),
+		ProjectID:    p.ProjectID,
+		LastAccessAt: now,
+		KeyFormat:    keyFormat(p.KeyFormat),
+		KeyPrefix:    p.KeyPrefix,
+	}
+	s.tokens[row.ID] = row
+	s.changed[row.ID] = now
+	if len(p.LinkedProjectIDs) > 0 {
+		s.links[row.ID] = slices.Clone(p.LinkedProjectIDs)
+	}
+	return s.tokenView(row), nil
+}
+
+func (s *store) GetToken(_ context.Context, user string, id int64) (*dbo.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.tokens[id]
+	if !ok || row.User != user {
+		return nil, fmt.Errorf("get token %d: %w", id, dbo.ErrNotFound)
+	}
+	return s.tokenView(row), nil
+}
+
+func (s *store) GetTokenByID(_ context.Context, id int64) (*dbo.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.tokens[id]
+	if !ok {
+		return nil, fmt.Errorf("get token %d: %w", id, dbo.ErrNotFound)
+	}
+	return s.tokenView(row), nil
+}
+
+func (s *store) GetTokenByKeyID(_ context.Context, user string, keyID types.KeyID) (*dbo.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row := s.findByKeyID(keyID)
+	if row == nil || row.User != user {
+		return nil, fmt.Errorf("get token by key id %s: %w", keyID, dbo.ErrNotFound)
+	}
+	return s.tokenView(row), nil
+}
+
+func (s *store) FindTokenByKeyID(_ context.Context, keyID types.KeyID) (*dbo.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row := s.findByKeyID(keyID)
+	if row == nil {
+		return nil, dbo.ErrNotFound
+	}
+	return s.tokenView(row), nil
+}
+
+func (s *store) DisableToken(_ context.Context, id int64, reason string, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.tokens[id]
+	if !ok || row.DisabledAt != nil {
+		return 0, nil
+	}
+	now := time.Now().UTC()
+	at = at.UTC()
+	row.DisabledAt = &at
+	row.DisabledReason = reason
+	row.UpdatedAt = now
+	s.changed[id] = now
+	return 1, nil
+}
+
+func (s *store) ListTokens(_ context.Context, p dbo.ListTokensParams) ([]*dbo.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	order := tokenOrder(p)
+	var cursor *dbo.Token
+	if p.After != nil {
+		cursor = &dbo.Token{ID: p.After.ID, Label: p.After.Label, LastAccessAt: p.After.LastAccessAt, Requests: p.After.Requests}
+	}
+	var rows []*dbo.Token
+	for _, row := range s.tokens {
+		if row.User != p.User || !s.matchToken(row, p) {
+			continue
+		}
+		if cursor != nil && order(row, cursor) <= 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, order)
+	if p.Limit > 0 && len(rows) > p.Limit {
+		rows = rows[:p.Limit]
+	}
+	return s.tokenViews(rows), nil
+}
+
+func (s *store) UpdateToken(_ context.Context, p dbo.UpdateTokenParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.tokens[p.ID]
+	if !ok || row.User != p.User {
+		return 0, fmt.Errorf("get token %d for update: %w", p.ID, dbo.ErrNotFound)
+	}
+	if p.ProjectID != nil {
+		if err := s.checkProject(*p.ProjectID); err != nil {
+			return 0, fmt.Errorf("update token: %w", err)
+		}
+	}
+	if p.LinkedProjectIDs != nil {
+		if err := s.checkLinks(*p.LinkedProjectIDs); err != nil {
+			return 0, err
+		}
+	}
+	if p.Hosts != nil {
+		row.Hosts = slices.Clone(*p.Hosts)
+	}
+	if p.Paths != nil {
+		row.Paths = slices.Clone(*p.Paths)
+	}
+	if p.Label != nil {
+		row.Label = *p.Label
+	}
+	if p.Headers != nil {
+		row.Headers = headersOrEmpty(*p.Headers)
+	}
+	if p.Meta != nil {
+		row.Meta = metaOrEmpty(*p.Meta)
+	}
+	if p.ProjectID != nil {
+		row.ProjectID = *p.ProjectID
+	}
+	if p.LinkedProjectIDs != nil {
+		s.setLinks(p.ID, *p.LinkedProjectIDs)
+	}
+	// the primary project can't be linked too
+	s.setLinks(p.ID, slices.DeleteFunc(slices.Clone(s.links[p.ID]), func(id int64) bool { return id == row.ProjectID }))
+	row.UpdatedAt = time.Now().UTC()
+	s.changed[p.ID] = row.UpdatedAt
+	return 1, nil
+}
+
+func (s *store) DeleteToken(_ context.Context, user string, id int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.tokens[id]
+	if !ok || row.User != user {
+		return 0, nil
+	}
+	s.deleteToken(id)
+	return 1, nil
+}
+
+func (s *store) RefreshToken(_ context.Context, user string, id int64, hash []byte, spec types.HashSpec, keyID *types.KeyID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.tokens[id]
+	if !ok || row.User != user {
+		return 0, nil
+	}
+	if s.keyIDInUse(*keyID, id) {
+		return 0, fmt.Errorf("refresh token %d: %w", id, errKeyIDInUse)
+	}
+	kid := *keyID
+	row.Hash = slices.Clone(hash)
+	row.HashAlg = spec.Algorithm
+	row.PepperID = spec.PepperID
+	row.KeyID = &kid
+	row.KeyFormat = types.KeyNative
+	row.KeyPrefix = ""
+	row.DisabledAt = nil
+	row.DisabledReason = ""
+	row.UpdatedAt = time.Now().UTC()
+	s.changed[id] = row.UpdatedAt
+	return 1, nil
+}
+
+func (s *store) RehashToken(_ context.Context, id int64, oldHash, newHash []byte, spec types.HashSpec) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.tokens[id]
+	if !ok || !bytes.Equal(row.Hash, oldHash) {
+		return 0, nil
+	}
+	row.Hash = slices.Clone(newHash)
+	row.HashAlg = spec.Algorithm
+	row.PepperID = spec.PepperID
+	s.changed[id] = time.Now().UTC()
+	return 1, nil
+}
+
+func (s *store) CreateProject(_ context.Context, p dbo.CreateProjectParams) (*dbo.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.slugInUse(p.User, p.Slug, 0) {
+		return nil, fmt.Errorf("create project %q: %w", p.Slug, dbo.ErrSlugInUse)
+	}
+	s.projectSeq++
+	now := time.Now().UTC()
+	row := &dbo.Project{
+		ID:          s.projectSeq,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		User:        p.User,
+		Slug:        p.Slug,
+		Description: p.Description,
+		Hosts:       nonNil(p.Hosts),
+		Paths:       nonNil(p.Paths),
+		Headers:     headersOrEmpty(p.Headers),
+	}
+	s.projects[row.ID] = row
+	return s.projectView(row), nil
+}
+
+func (s *store) GetProject(_ context.Context, user string, id int64) (*dbo.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.projects[id]
+	if !ok || row.User != user {
+		return nil, fmt.Errorf("get project %d: %w", id, dbo.ErrNotFound)
+	}
+	return s.projectView(row), nil
+}
+
+func (s *store) ListProjects(_ context.Context, user string) ([]*dbo.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*dbo.Project, 0)
+	for _, id := range sortedKeys(s.projects) {
+		if row := s.projects[id]; row.User == user {
+			out = append(out, s.projectView(row))
+		}
+	}
+	return out, nil
+}
+
+func (s *store) UpdateProject(_ context.Context, p dbo.UpdateProjectParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.projects[p.ID]
+	if !ok || row.User != p.User {
+		return 0, nil
+	}
+	if p.Slug != nil && *p.Slug != row.Slug {
+		slug := *p.Slug
+		// renaming back to an own alias restores it
+		if s.slugInUse(p.User, slug, p.ID) {
+			return 0, fmt.Errorf("rename project %d to %q: %w", p.ID, slug, dbo.ErrSlugInUse)
+		}
+		s.deleteAliases(func(a *dbo.ProjectAlias) bool { return a.ProjectID == p.ID && a.Slug == slug })
+		s.addAlias(p.ID, p.User, row.Slug)
+		row.Slug = slug
+	}
+	if p.Description != nil {
+		row.Description = *p.Description
+	}
+	if p.Hosts != nil {
+		row.Hosts = nonNil(*p.Hosts)
+	}
+	if p.Paths != nil {
+		row.Paths = nonNil(*p.Paths)
+	}
+	if p.Headers != nil {
+		row.Headers = headersOrEmpty(*p.Headers)
+	}
+	row.UpdatedAt = time.Now().UTC()
+	return 1, nil
+}
+
+func (s *store) DeleteProject(_ context.Context, user string, id int64) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokenIDs := s.projectTokenIDs(id)
+	row, ok := s.projects[id]
+	if !ok || row.User != user {
+		return tokenIDs, nil
+	}
+	for tokenID, t := range s.tokens {
+		if t.ProjectID == id {
+			s.deleteToken(tokenID)
+		}
+	}
+	now := time.Now().UTC()
+	for tokenID, linked := range s.links {
+		if slices.Contains(linked, id) {
+			s.setLinks(tokenID, slices.DeleteFunc(linked, func(v int64) bool { return v == id }))
+			s.changed[tokenID] = now
+		}
+	}
+	s.deleteAliases(func(a *dbo.ProjectAlias) bool { return a.ProjectID == id })
+	delete(s.projects, id)
+	return tokenIDs, nil
+}
+
+func (s *store) ProjectExists(_ context.Context, user string, id int64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.projects[id]
+	return ok && row.User == user, nil
+}
+
+func (s *store) ListProjectTokenIDs(_ context.Context, user string, id int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.projects[id]
+	if !ok || row.User != user {
+		return []int64{}, nil
+	}
+	return s.projectTokenIDs(id), nil
+}
+
+func (s *store) DeleteProjectAlias(_ context.Context, user string, id int64, slug string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := s.deleteAliases(func(a *dbo.ProjectAlias) bool {
+		return a.User == user && a.ProjectID == id && a.Slug == slug
+	})
+	if deleted > 0 {
+		s.projects[id].UpdatedAt = time.Now().UTC()
+	}
+	return deleted, nil
+}
+
+func (s *store) AddProjectAlias(_ context.Context, user string, id int64, slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row, ok := s.projects[id]
+	if !ok || row.User != user {
+		return fmt.Errorf("project %d: %w", id, dbo.ErrNotFound)
+	}
+	if s.slugInUse(user, slug, 0) {
+		return fmt.Errorf("add alias %q: %w", slug, dbo.ErrSlugInUse)
+	}
+	s.addAlias(id, user, slug)
+	row.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *store) ListAllTokens(_ context.Context) ([]*dbo.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rows := make([]*dbo.Token, 0, len(s.tokens))
+	for _, id := range sortedKeys(s.tokens) {
+		rows = append(rows, s.tokens[id])
+	}
+	return s.tokenViews(rows), nil
+}
+
+func (s *store) ListAllProjects(_ context.Context) ([]*dbo.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*dbo.Project, 0, len(s.projects))
+	for _, id := range sortedKeys(s.projects) {
+		out = append(out, s.projectView(s.projects[id]))
+	}
+	return out, nil
+}
+
+func (s *store) ListTokenChanges(ctx context.Context, since time.Time) (*dbo.TokenChanges, error) {
+	out := &dbo.TokenChanges{At: time.Now().UTC()}
+	if since.IsZero() {
+		tokens, err := s.ListAllTokens(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out.Tokens = tokens
+		return out, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.tombstones {
+		if !t.at.Before(since) && !slices.Contains(out.Deleted, t.id) {
+			out.Deleted = append(out.Deleted, t.id)
+		}
+	}
+	projectChanged := func(id int64) bool {
+		return !s.projects[id].UpdatedAt.Before(since)
+	}
+	var rows []*dbo.Token
+	for _, id := range sortedKeys(s.tokens) {
+		row := s.tokens[id]
+		if !s.changed[id].Before(since) || projectChanged(row.ProjectID) || slices.ContainsFunc(s.links[id], projectChanged) {
+			rows = append(rows, row)
+		}
+	}
+	out.Tokens = s.tokenViews(rows)
+	return out, nil
+}
+
+func (s *store) UpdateStats(_ context.Context, stats map[int64]dbo.StatsEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	for id, entry := range stats {
+		row, ok := s.tokens[id]
+		if !ok {
+			continue
+		}
+		row.Requests += entry.Hits
+		row.LastAccessAt = entry.Last.UTC()
+		row.UpdatedAt = now
+	}
+	return nil
+}
+
+func (s *store) Snapshot(ctx context.Context) (*dbo.Snapshot, error) {
+	projects, err := s.ListAllProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.ListAllTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := &dbo.Snapshot{Projects: projects, Tokens: tokens, Aliases: make([]*dbo.ProjectAlias, 0, len(s.aliases))}
+	for _, id := range sortedKeys(s.aliases) {
+		alias := *s.aliases[id]
+		out.Aliases = append(out.Aliases, &alias)
+	}
+	return out, nil
+}
+
+// Restore applies the snapshot to a copy of the data, which replaces the current one only if
+// all rows were restored.
+func (s *store) Restore(_ context.Context, snapshot *dbo.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := &store{
+		projectSeq: s.projectSeq,
+		aliasSeq:   s.aliasSeq,
+		tokenSeq:   s.tokenSeq,
+		projects:   maps.Clone(s.projects),
+		aliases:    maps.Clone(s.aliases),
+		tokens:     maps.Clone(s.tokens),
+		links:      maps.Clone(s.links),
+		changed:    maps.Clone(s.changed),
+		tombstones: s.tombstones,
+	}
+	if err := next.restore(snapshot); err != nil {
+		return err
+	}
+	s.projectSeq, s.aliasSeq, s.tokenSeq = next.projectSeq, next.aliasSeq, next.tokenSeq
+	s.projects, s.aliases, s.tokens, s.links = next.projects, next.aliases, next.tokens, next.links
+	s.changed = next.changed
+	return nil
+}
+
+func (s *store) restore(snapshot *dbo.Snapshot) error {
+	for _, p := range snapshot.Projects {
+		for id, other := range s.projects {
+			if id != p.ID && other.User == p.User && other.Slug == p.Slug {
+				return fmt.Errorf("restore project %d: %w", p.ID, errProjectInUse)
+			}
+		}
+		s.projects[p.ID] = &dbo.Project{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt.UTC(),
+			UpdatedAt:   p.UpdatedAt.UTC(),
+			User:        p.User,
+			Slug:        p.Slug,
+			Description: p.Description,
+			Hosts:       nonNil(slices.Clone(p.Hosts)),
+			Paths:       nonNil(slices.Clone(p.Paths)),
+			Headers:     headersOrEmpty(p.Headers),
+		}
+		s.projectSeq = max(s.projectSeq, p.ID)
+	}
+	for _, a := range snapshot.Aliases {
+		if err := s.checkProject(a.ProjectID); err != nil {
+			return fmt.Errorf("restore project alias %d: %w", a.ID, err)
+		}
+		alias := *a
+		alias.CreatedAt = a.CreatedAt.UTC()
+		s.aliases[a.ID] = &alias
+		s.aliasSeq = max(s.aliasSeq, a.ID)
+	}
+	for _, t := range snapshot.Tokens {
+		if err := s.checkProject(t.ProjectID); err != nil {
+			return fmt.Errorf("restore token %d: %w", t.ID, err)
+		}
+		if s.keyIDInUse(*t.KeyID, t.ID) {
+			return fmt.Errorf("restore token %d: %w", t.ID, errKeyIDInUse)
+		}
+		linked := make([]int64, 0, len(t.LinkedProjects))
+		for _, ref := range t.LinkedProjects {
+			linked = append(linked, ref.ID)
+		}
+		if err := s.checkLinks(linked); err != nil {
+			return fmt.Errorf("restore token %d: %w", t.ID, err)
+		}
+		kid := *t.KeyID
+		row := &dbo.Token{
+			ID:             t.ID,
+			CreatedAt:      t.CreatedAt.UTC(),
+			UpdatedAt:      t.UpdatedAt.UTC(),
+			KeyID:          &kid,
+			Hash:           slices.Clone(t.Hash),
+			HashAlg:        t.HashAlg,
+			PepperID:       t.PepperID,
+			User:           t.User,
+			Label:          t.Label,
+			Hosts:          slices.Clone(t.Hosts),
+			Paths:          slices.Clone(t.Paths),
+			Headers:        headersOrEmpty(t.Headers),
+			Meta:           metaOrEmpty(t.Meta),
+			ProjectID:      t.ProjectID,
+			Requests:       t.Requests,
+			LastAccessAt:   t.LastAccessAt.UTC(),
+			DisabledReason: t.DisabledReason,
+			KeyFormat:      keyFormat(t.KeyFormat),
+			KeyPrefix:      t.KeyPrefix,
+		}
+		if t.DisabledAt != nil {
+			at := t.DisabledAt.UTC()
+			row.DisabledAt = &at
+		}
+		s.tokens[t.ID] = row
+		s.changed[t.ID] = time.Now().UTC()
+		s.setLinks(t.ID, linked)
+		s.tokenSeq = max(s.tokenSeq, t.ID)
+	}
+	return nil
+}
+
+func (s *store) CountRows(_ context.Context) (dbo.RowCounts, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := dbo.RowCounts{
+		Projects: int64(len(s.projects)),
+		Aliases:  int64(len(s.aliases)),
+		Tokens:   int64(len(s.tokens)),
+	}
+	for _, linked := range s.links {
+		counts.TokenProjects += int64(len(linked))
+	}
+	return counts, nil
+}
+
+func (s *store) checkProject(id int64) error {
+	if _, ok := s.projects[id]; !ok {
+		return fmt.Errorf("project %d: %w", id, errNoProject)
+	}
+	return nil
+}
+
+// checkLinks validates additional projects of a token.
+func (s *store) checkLinks(ids []int64) error {
+	for i, id := range ids {
+		if err := s.checkProject(id); err != nil {
+			return fmt.Errorf("link project %d: %w", id, err)
+		}
+		if slices.Contains(ids[:i], id) {
+			return fmt.Errorf("link project %d: %w", id, errAlreadyLinked)
+		}
+	}
+	return nil
+}
+
+func (s *store) setLinks(tokenID int64, ids []int64) {
+	if len(ids) == 0 {
+		delete(s.links, tokenID)
+		return
+	}
+	s.links[tokenID] = slices.Clone(ids)
+}
+
+// deleteToken removes the token and leaves a tombstone for ListTokenChanges.
+func (s *store) deleteToken(id int64) {
+	delete(s.tokens, id)
+	delete(s.links, id)
+	delete(s.changed, id)
+	now := time.Now().UTC()
+	s.tombstones = slices.DeleteFunc(s.tombstones, func(t tombstone) bool {
+		return now.Sub(t.at) > dbo.TombstoneRetention
+	})
+	s.tombstones = append(s.tombstones, tombstone{id: id, at: now})
+}
+
+// keyIDInUse reports whether the key ID belongs to a token other than except.
+func (s *store) keyIDInUse(keyID types.KeyID, except int64) bool {
+	row := s.findByKeyID(keyID)
+	return row != nil && row.ID != except
+}
+
+func (s *store) findByKeyID(keyID types.KeyID) *dbo.Token {
+	for _, row := range s.tokens {
+		if *row.KeyID == keyID {
+			return row
+		}
+	}
+	return nil
+}
+
+// slugInUse reports whether the slug is used by a project or an alias of the user. Aliases of
+// the except project are ignored.
+func (s *store) slugInUse(user, slug string, except int64) bool {
+	for _, p := range s.projects {
+		if p.User == user && p.Slug == slug {
+			return true
+		}
+	}
+	for _, a := range s.aliases {
+		if a.User == user && a.Slug == slug && a.ProjectID != except {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *store) addAlias(projectID int64, user, slug string) {
+	s.aliasSeq++
+	s.aliases[s.aliasSeq] = &dbo.ProjectAlias{
+		ID:        s.aliasSeq,
+		CreatedAt: time.Now().UTC(),
+		ProjectID: projectID,
+		User:      user,
+		Slug:      slug,
+	}
+}
+
+func (s *store) deleteAliases(match func(a *dbo.ProjectAlias) bool) int64 {
+	var n int64
+	for id, a := range s.aliases {
+		if match(a) {
+			delete(s.aliases, id)
+			n++
+		}
+	}
+	return n
+}
+
+// aliasesOf returns alias slugs of the project in order of creation.
+func (s *store) aliasesOf(projectID int64) []string {
+	var out []string
+	for _, id := range sortedKeys(s.aliases) {
+		if a := s.aliases[id]; a.ProjectID == projectID {
+			out = append(out, a.Slug)
+		}
+	}
+	return out
+}
+
+// projectTokenIDs returns IDs of tokens of the project, own or linked.
+func (s *store) projectTokenIDs(projectID int64) []int64 {
+	out := make([]int64, 0)
+	for _, id := range sortedKeys(s.tokens) {
+		if s.tokens[id].ProjectID == projectID || slices.Contains(s.links[id], projectID) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (s *store) matchToken(row *dbo.Token, p dbo.ListTokensParams) bool {
+	if p.ProjectID != 0 && row.ProjectID != p.ProjectID && !slices.Contains(s.links[row.ID], p.ProjectID) {
+		return false
+	}
+	for k, v := range p.Meta {
+		if got, ok := row.Meta[k]; !ok || got != v {
+			return false
+		}
+	}
+	if p.Search != "" &&
+		!strings.Contains(strings.ToLower(row.Label), strings.ToLower(p.Search)) &&
+		!strings.HasPrefix(strings.ToUpper(row.KeyID.String()), strings.ToUpper(p.Search)) {
+		return false
+	}
+	if p.Host != "" && !slices.ContainsFunc(row.Hosts, func(h string) bool {
+		return strings.Contains(strings.ToLower(h), strings.ToLower(p.Host))
+	}) {
+		return false
+	}
+	// time is compared with one second precision, like in SQL stores
+	last := row.LastAccessAt.Unix()
+	if !p.AccessedAfter.IsZero() && (row.Requests == 0 || last < p.AccessedAfter.Unix()) {
+		return false
+	}
+	if !p.AccessedBefore.IsZero() && (row.Requests == 0 || last >= p.AccessedBefore.Unix()) {
+		return false
+	}
+	if !p.UnusedSince.IsZero() && row.Requests > 0 && last >= p.UnusedSince.Unix() {
+		return false
+	}
+	if p.Disabled != nil && *p.Disabled != (row.DisabledAt != nil) {
+		return false
+	}
+	return true
+}
+
+// tokenOrder compares tokens by the sort field, ties are resolved by ID.
+func tokenOrder(p dbo.ListTokensParams) func(a, b *dbo.Token) int {
+	return func(a, b *dbo.Token) int {
+		var c int
+		switch p.Sort {
+		case dbo.SortLabel:
+			c = strings.Compare(strings.ToLower(a.Label), strings.ToLower(b.Label))
+		case dbo.SortLastAccess:
+			c = cmp.Compare(a.LastAccessAt.Unix(), b.LastAccessAt.Unix())
+		case dbo.SortRequests:
+			c = cmp.Compare(a.Requests, b.Requests)
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if !p.Asc {
+			return -c
+		}
+		return c
+	}
+}
+
+func (s *store) tokenViews(rows []*dbo.Token) []*dbo.Token {
+	out := make([]*dbo.Token, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, s.tokenView(row))
+	}
+	return out
+}
+
+// tokenView copies the token and resolves its project fields, aliases and linked projects.
+func (s *store) tokenView(row *dbo.Token) *dbo.Token {
+	tok := *row
+	kid := *row.KeyID
+	tok.KeyID = &kid
+	tok.Hash = slices.Clone(row.Hash)
+	tok.Hosts = slices.Clone(row.Hosts)
+	tok.Paths = slices.Clone(row.Paths)
+	tok.Headers = slices.Clone(row.Headers)
+	tok.Meta = maps.Clone(row.Meta)
+	if row.DisabledAt != nil {
+		at := *row.DisabledAt
+		tok.DisabledAt = &at
+	}
+	project := s.projects[row.ProjectID]
+	tok.ProjectSlug = project.Slug
+	tok.ProjectAliases = s.aliasesOf(project.ID)
+	tok.ProjectHosts = slices.Clone(project.Hosts)
+	tok.ProjectPaths = slices.Clone(project.Paths)
+	tok.ProjectHeaders = slices.Clone(project.Headers)
+	for _, id := range s.links[row.ID] {
+		tok.LinkedProjects = append(tok.LinkedProjects, dbo.ProjectRef{
+			ID:      id,
+			Slug:    s.projects[id].Slug,
+			Aliases: s.aliasesOf(id),
+		})
+	}
+	return &tok
+}
+
+func (s *store) projectView(row *dbo.Project) *dbo.Project {
+	p := *row
+	p.Aliases = s.aliasesOf(row.ID)
+	p.Hosts = slices.Clone(row.Hosts)
+	p.Paths = slices.Clone(row.Paths)
+	p.Headers = slices.Clone(row.Headers)
+	return &p
+}
+
+func sortedKeys[T any](m map[int64]T) []int64 {
+	return slices.Sorted(maps.Keys(m))
+}
+
+// keyFormat defaults empty format to native.
+func keyFormat(f types.KeyFormat) types.KeyFormat {
+	if f == "" {
+		return types.KeyNative
+	}
+	return f
+}
+
+// nonNil keeps empty lists of projects non-nil, like the SQL stores return them.
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return slices.Clone(v)
+}
+
+// headersOrEmpty and metaOrEmpty copy values, with empty values stored the same way as by the SQL stores.
+func headersOrEmpty(h types.Headers) types.Headers {
+	if h == nil {
+		return types.Headers{}
+	}
+	return slices.Clone(h)
+}
+
+func metaOrEmpty(m types.Meta) types.Meta {
+	if m == nil {
+		return types.Meta{}
+	}
+	return maps.Clone(m)
+}
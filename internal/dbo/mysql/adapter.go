@@ -0,0 +1,945 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/types"
+)
+
+type store struct {
+	db *sql.DB
+	q  *Queries
+}
+
+// NewStore wraps a *sql.DB in a dbo.Store implementation backed by MySQL or MariaDB.
+func NewStore(db *sql.DB) dbo.Store {
+	return &store{db: db, q: New(db)}
+}
+
+func (s *store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("close db: %w", err)
+	}
+	return nil
+}
+
+func (s *store) CreateToken(ctx context.Context, p dbo.CreateTokenParams) (*dbo.Token, error) {
+	hostsJSON, err := json.Marshal(p.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("marshal hosts: %w", err)
+	}
+	pathsJSON, err := json.Marshal(p.Paths)
+	if err != nil {
+		return nil, fmt.Errorf("marshal paths: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	id, err := q.CreateToken(ctx, CreateTokenParams{
+		KeyID:     *p.KeyID,
+		Hash:      p.Hash,
+		HashAlg:   string(p.HashSpec.Algorithm),
+		PepperID:  p.HashSpec.PepperID,
+		User:      p.User,
+		Label:     p.Label,
+		Paths:     string(pathsJSON),
+		Hosts:     string(hostsJSON),
+		Headers:   p.Headers,
+		Meta:      p.Meta,
+		ProjectID: p.ProjectID,
+		KeyFormat: string(keyFormat(p.KeyFormat)),
+		KeyPrefix: p.KeyPrefix,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create token: %w", err)
+	}
+	for _, projectID := range p.LinkedProjectIDs {
+		if err := q.AddTokenProject(ctx, AddTokenProjectParams{TokenID: id, ProjectID: projectID}); err != nil {
+			return nil, fmt.Errorf("link project %d: %w", projectID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return s.GetTokenByID(ctx, id)
+}
+
+func (s *store) GetToken(ctx context.Context, user string, id int64) (*dbo.Token, error) {
+	row, err := s.q.GetToken(ctx, GetTokenParams{User: user, ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+	return s.withProjects(ctx, row)
+}
+
+func (s *store) GetTokenByID(ctx context.Context, id int64) (*dbo.Token, error) {
+	row, err := s.q.GetTokenByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get token by id: %w", err)
+	}
+	return s.withProjects(ctx, row)
+}
+
+func (s *store) GetTokenByKeyID(ctx context.Context, user string, keyID types.KeyID) (*dbo.Token, error) {
+	row, err := s.q.GetTokenByKeyID(ctx, GetTokenByKeyIDParams{User: user, KeyID: keyID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get token by key id %s: %w", keyID, dbo.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get token by key id: %w", err)
+	}
+	return s.withProjects(ctx, row)
+}
+
+func (s *store) FindTokenByKeyID(ctx context.Context, keyID types.KeyID) (*dbo.Token, error) {
+	row, err := s.q.FindTokenByKeyID(ctx, keyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, dbo.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find token by key id: %w", err)
+	}
+	return s.withProjects(ctx, row)
+}
+
+func (s *store) DisableToken(ctx context.Context, id int64, reason string, at time.Time) (int64, error) {
+	n, err := s.q.DisableToken(ctx, DisableTokenParams{DisabledAt: &at, DisabledReason: reason, ID: id})
+	if err != nil {
+		return 0, fmt.Errorf("disable token: %w", err)
+	}
+	return n, nil
+}
+
+func (s *store) ListTokens(ctx context.Context, p dbo.ListTokensParams) ([]*dbo.Token, error) {
+	metaFilter := []byte("{}")
+	if p.Meta != nil {
+		data, err := json.Marshal(p.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("marshal meta filter: %w", err)
+		}
+		metaFilter = data
+	}
+	limit := int32(math.MaxInt32) // MySQL has no "no limit" value
+	if p.Limit > 0 {
+		limit = int32(min(p.Limit, math.MaxInt32)) //nolint:gosec // clamped above
+	}
+	params := ListTokensParams{
+		Sort:           sortKey(p),
+		User:           p.User,
+		ProjectID:      p.ProjectID,
+		Meta:           string(metaFilter),
+		Search:         p.Search,
+		Host:           p.Host,
+		AccessedAfter:  unixOrZero(p.AccessedAfter),
+		AccessedBefore: unixOrZero(p.AccessedBefore),
+		UnusedSince:    unixOrZero(p.UnusedSince),
+		State:          stateFilter(p.Disabled),
+		Limit:          limit,
+	}
+	if p.After != nil {
+		params.AfterID = p.After.ID
+		params.AfterLabel = p.After.Label
+		switch p.Sort {
+		case dbo.SortLastAccess:
+			params.AfterValue = p.After.LastAccessAt.Unix()
+		case dbo.SortRequests:
+			params.AfterValue = p.After.Requests
+		}
+	}
+	rows, err := s.q.ListTokens(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+	aliases, err := s.q.ListProjectAliases(ctx, p.User)
+	if err != nil {
+		return nil, fmt.Errorf("list project aliases: %w", err)
+	}
+	links, err := s.q.ListTokenProjectsByUser(ctx, p.User)
+	if err != nil {
+		return nil, fmt.Errorf("list token projects: %w", err)
+	}
+	return mapTokens(rows, groupAliases(aliases), groupLinks(links)), nil
+}
+
+func (s *store) UpdateToken(ctx context.Context, p dbo.UpdateTokenParams) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	current, err := q.GetToken(ctx, GetTokenParams{User: p.User, ID: p.ID})
+	if err != nil {
+		return 0, fmt.Errorf("get token for update: %w", err)
+	}
+	var hosts, paths []string
+	if err := json.Unmarshal([]byte(current.Hosts), &hosts); err != nil {
+		return 0, fmt.Errorf("unmarshal hosts for token %d: %w", p.ID, err)
+	}
+	if err := json.Unmarshal([]byte(current.Paths), &paths); err != nil {
+		return 0, fmt.Errorf("unmarshal paths for token %d: %w", p.ID, err)
+	}
+	label := current.Label
+	headers := current.Headers
+	meta := current.Meta
+	projectID := current.ProjectID
+	if p.Hosts != nil {
+		hosts = *p.Hosts
+	}
+	if p.Paths != nil {
+		paths = *p.Paths
+	}
+	if p.Label != nil {
+		label = *p.Label
+	}
+	if p.Headers != nil {
+		headers = *p.Headers
+	}
+	if p.Meta != nil {
+		meta = *p.Meta
+	}
+	if p.ProjectID != nil {
+		projectID = *p.ProjectID
+	}
+	hostsJSON, merr := json.Marshal(hosts)
+	if merr != nil {
+		return 0, fmt.Errorf("marshal hosts for token %d: %w", p.ID, merr)
+	}
+	pathsJSON, merr := json.Marshal(paths)
+	if merr != nil {
+		return 0, fmt.Errorf("marshal paths for token %d: %w", p.ID, merr)
+	}
+	changed, err := q.UpdateToken(ctx, UpdateTokenParams{
+		Hosts:     string(hostsJSON),
+		Paths:     string(pathsJSON),
+		Label:     label,
+		Headers:   headers,
+		Meta:      meta,
+		ProjectID: projectID,
+		User:      p.User,
+		ID:        p.ID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update token: %w", err)
+	}
+	if p.LinkedProjectIDs != nil {
+		if err := q.ClearTokenProjects(ctx, p.ID); err != nil {
+			return 0, fmt.Errorf("clear linked projects: %w", err)
+		}
+		for _, linked := range *p.LinkedProjectIDs {
+			if err := q.AddTokenProject(ctx, AddTokenProjectParams{TokenID: p.ID, ProjectID: linked}); err != nil {
+				return 0, fmt.Errorf("link project %d: %w", linked, err)
+			}
+		}
+	}
+	if projectID != current.ProjectID || p.LinkedProjectIDs != nil {
+		// the primary project can't be linked too
+		if err := q.RemoveTokenProject(ctx, RemoveTokenProjectParams{TokenID: p.ID, ProjectID: projectID}); err != nil {
+			return 0, fmt.Errorf("unlink project %d: %w", projectID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return changed, nil
+}
+
+func (s *store) DeleteToken(ctx context.Context, user string, id int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	deleted, err := q.DeleteToken(ctx, DeleteTokenParams{User: user, ID: id})
+	if err != nil {
+		return 0, fmt.Errorf("delete token: %w", err)
+	}
+	if deleted > 0 {
+		if err := q.CreateTokenTombstone(ctx, id); err != nil {
+			return 0, fmt.Errorf("create tombstone: %w", err)
+		}
+		if err := purgeTombstones(ctx, q); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *store) RefreshToken(ctx context.Context, user string, id int64, hash []byte, spec types.HashSpec, keyID *types.KeyID) (int64, error) {
+	return s.q.RefreshToken(ctx, RefreshTokenParams{
+		Hash:     hash,
+		HashAlg:  string(spec.Algorithm),
+		PepperID: spec.PepperID,
+		KeyID:    *keyID,
+		User:     user,
+		ID:       id,
+	})
+}
+
+func (s *store) RehashToken(ctx context.Context, id int64, oldHash, newHash []byte, spec types.HashSpec) (int64, error) {
+	n, err := s.q.RehashToken(ctx, RehashTokenParams{
+		NewHash:  newHash,
+		HashAlg:  string(spec.Algorithm),
+		PepperID: spec.PepperID,
+		ID:       id,
+		OldHash:  oldHash,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("rehash token: %w", err)
+	}
+	return n, nil
+}
+
+func (s *store) CreateProject(ctx context.Context, p dbo.CreateProjectParams) (*dbo.Project, error) {
+	hostsJSON, err := json.Marshal(nonNil(p.Hosts))
+	if err != nil {
+		return nil, fmt.Errorf("marshal hosts: %w", err)
+	}
+	pathsJSON, err := json.Marshal(nonNil(p.Paths))
+	if err != nil {
+		return nil, fmt.Errorf("marshal paths: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	inUse, err := q.SlugInUse(ctx, SlugInUseParams{User: p.User, Slug: p.Slug})
+	if err != nil {
+		return nil, fmt.Errorf("check slug: %w", err)
+	}
+	if inUse {
+		return nil, fmt.Errorf("create project %q: %w", p.Slug, dbo.ErrSlugInUse)
+	}
+	id, err := q.CreateProject(ctx, CreateProjectParams{
+		User:        p.User,
+		Slug:        p.Slug,
+		Description: p.Description,
+		Hosts:       string(hostsJSON),
+		Paths:       string(pathsJSON),
+		Headers:     p.Headers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create project: %w", err)
+	}
+	row, err := q.GetProject(ctx, GetProjectParams{User: p.User, ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("get created project: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return mapProject(row, nil)
+}
+
+func (s *store) GetProject(ctx context.Context, user string, id int64) (*dbo.Project, error) {
+	row, err := s.q.GetProject(ctx, GetProjectParams{User: user, ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	aliases, err := s.q.ListProjectAliasesByProject(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list project aliases: %w", err)
+	}
+	return mapProject(row, groupAliases(aliases)[row.ID])
+}
+
+func (s *store) ListProjects(ctx context.Context, user string) ([]*dbo.Project, error) {
+	rows, err := s.q.ListProjects(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	aliases, err := s.q.ListProjectAliases(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("list project aliases: %w", err)
+	}
+	return mapProjects(rows, groupAliases(aliases)), nil
+}
+
+func (s *store) UpdateProject(ctx context.Context, p dbo.UpdateProjectParams) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	current, err := q.GetProject(ctx, GetProjectParams{User: p.User, ID: p.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get project for update: %w", err)
+	}
+	slug := current.Slug
+	description := current.Description
+	hostsJSON := current.Hosts
+	pathsJSON := current.Paths
+	headers := current.Headers
+	if p.Description != nil {
+		description = *p.Description
+	}
+	if p.Hosts != nil {
+		data, err := json.Marshal(nonNil(*p.Hosts))
+		if err != nil {
+			return 0, fmt.Errorf("marshal hosts for project %d: %w", p.ID, err)
+		}
+		hostsJSON = string(data)
+	}
+	if p.Paths != nil {
+		data, err := json.Marshal(nonNil(*p.Paths))
+		if err != nil {
+			return 0, fmt.Errorf("marshal paths for project %d: %w", p.ID, err)
+		}
+		pathsJSON = string(data)
+	}
+	if p.Headers != nil {
+		headers = *p.Headers
+	}
+	if p.Slug != nil && *p.Slug != current.Slug {
+		slug = *p.Slug
+		// renaming back to an own alias restores it
+		if _, err := q.DeleteProjectAlias(ctx, DeleteProjectAliasParams{User: p.User, ProjectID: p.ID, Slug: slug}); err != nil {
+			return 0, fmt.Errorf("delete alias %q: %w", slug, err)
+		}
+		inUse, err := q.SlugInUse(ctx, SlugInUseParams{User: p.User, Slug: slug})
+		if err != nil {
+			return 0, fmt.Errorf("check slug: %w", err)
+		}
+		if inUse {
+			return 0, fmt.Errorf("rename project %d to %q: %w", p.ID, slug, dbo.ErrSlugInUse)
+		}
+		if err := q.CreateProjectAlias(ctx, CreateProjectAliasParams{ProjectID: p.ID, User: p.User, Slug: current.Slug}); err != nil {
+			return 0, fmt.Errorf("create alias %q: %w", current.Slug, err)
+		}
+	}
+	changed, err := q.UpdateProject(ctx, UpdateProjectParams{
+		Slug:        slug,
+		Description: description,
+		Hosts:       hostsJSON,
+		Paths:       pathsJSON,
+		Headers:     headers,
+		User:        p.User,
+		ID:          p.ID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update project: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return changed, nil
+}
+
+func (s *store) DeleteProject(ctx context.Context, user string, id int64) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	tokenIDs, err := q.ListTokenIDsByProject(ctx, ListTokenIDsByProjectParams{ProjectID: id})
+	if err != nil {
+		return nil, fmt.Errorf("list token ids: %w", err)
+	}
+	exists, err := q.ProjectExists(ctx, ProjectExistsParams{User: user, ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("check project exists: %w", err)
+	}
+	if !exists {
+		return tokenIDs, nil
+	}
+	// own tokens are deleted with the project, linked ones lose the link
+	if err := q.CreateProjectTokenTombstones(ctx, id); err != nil {
+		return nil, fmt.Errorf("create tombstones: %w", err)
+	}
+	if err := q.TouchLinkedTokens(ctx, id); err != nil {
+		return nil, fmt.Errorf("touch linked tokens: %w", err)
+	}
+	if err := purgeTombstones(ctx, q); err != nil {
+		return nil, err
+	}
+	if _, err := q.DeleteProject(ctx, DeleteProjectParams{User: user, ID: id}); err != nil {
+		return nil, fmt.Errorf("delete project: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return tokenIDs, nil
+}
+
+func (s *store) ProjectExists(ctx context.Context, user string, id int64) (bool, error) {
+	ok, err := s.q.ProjectExists(ctx, ProjectExistsParams{User: user, ID: id})
+	if err != nil {
+		return false, fmt.Errorf("check project exists: %w", err)
+	}
+	return ok, nil
+}
+
+func (s *store) ListProjectTokenIDs(ctx context.Context, user string, id int64) ([]int64, error) {
+	ids, err := s.q.ListProjectTokenIDs(ctx, ListProjectTokenIDsParams{User: user, ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("list project token ids: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *store) DeleteProjectAlias(ctx context.Context, user string, id int64, slug string) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	deleted, err := q.DeleteProjectAlias(ctx, DeleteProjectAliasParams{User: user, ProjectID: id, Slug: slug})
+	if err != nil {
+		return 0, fmt.Errorf("delete alias %q: %w", slug, err)
+	}
+	if deleted > 0 {
+		if err := q.TouchProject(ctx, id); err != nil {
+			return 0, fmt.Errorf("touch project: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *store) AddProjectAlias(ctx context.Context, user string, id int64, slug string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	if _, err := q.GetProject(ctx, GetProjectParams{User: user, ID: id}); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("project %d: %w", id, dbo.ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+	inUse, err := q.SlugInUse(ctx, SlugInUseParams{User: user, Slug: slug})
+	if err != nil {
+		return fmt.Errorf("check slug: %w", err)
+	}
+	if inUse {
+		return fmt.Errorf("add alias %q: %w", slug, dbo.ErrSlugInUse)
+	}
+	if err := q.CreateProjectAlias(ctx, CreateProjectAliasParams{ProjectID: id, User: user, Slug: slug}); err != nil {
+		return fmt.Errorf("create alias %q: %w", slug, err)
+	}
+	if err := q.TouchProject(ctx, id); err != nil {
+		return fmt.Errorf("touch project: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func (s *store) ListAllTokens(ctx context.Context) ([]*dbo.Token, error) {
+	rows, err := s.q.ListAllTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all tokens: %w", err)
+	}
+	aliases, err := s.q.ListAllProjectAliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all project aliases: %w", err)
+	}
+	links, err := s.q.ListAllTokenProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all token projects: %w", err)
+	}
+	return mapTokens(rows, groupAliases(aliases), groupLinks(links)), nil
+}
+
+func (s *store) ListAllProjects(ctx context.Context) ([]*dbo.Project, error) {
+	rows, err := s.q.ListAllProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all projects: %w", err)
+	}
+	aliases, err := s.q.ListAllProjectAliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all project aliases: %w", err)
+	}
+	return mapProjects(rows, groupAliases(aliases)), nil
+}
+
+func (s *store) ListTokenChanges(ctx context.Context, since time.Time) (*dbo.TokenChanges, error) {
+	out := &dbo.TokenChanges{}
+	if err := s.db.QueryRowContext(ctx, "SELECT CURRENT_TIMESTAMP(6)").Scan(&out.At); err != nil {
+		return nil, fmt.Errorf("get database time: %w", err)
+	}
+	if since.IsZero() {
+		tokens, err := s.ListAllTokens(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out.Tokens = tokens
+		return out, nil
+	}
+	deleted, err := s.q.ListTokenTombstones(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("list token tombstones: %w", err)
+	}
+	rows, err := s.q.ListChangedTokens(ctx, ListChangedTokensParams{Since: since})
+	if err != nil {
+		return nil, fmt.Errorf("list changed tokens: %w", err)
+	}
+	out.Deleted = deleted
+	out.Tokens = make([]*dbo.Token, 0, len(rows))
+	if len(rows) == 0 {
+		return out, nil
+	}
+	aliases, err := s.q.ListAllProjectAliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all project aliases: %w", err)
+	}
+	links, err := s.q.ListAllTokenProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all token projects: %w", err)
+	}
+	out.Tokens = mapTokens(rows, groupAliases(aliases), groupLinks(links))
+	return out, nil
+}
+
+func (s *store) UpdateStats(ctx context.Context, stats map[int64]dbo.StatsEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	for id, entry := range stats {
+		if err := q.UpdateTokenStats(ctx, UpdateTokenStatsParams{
+			Requests:     entry.Hits,
+			LastAccessAt: entry.Last.UTC(),
+			ID:           id,
+		}); err != nil {
+			return fmt.Errorf("update stats for %d: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// purgeTombstones removes tombstones older than dbo.TombstoneRetention.
+func purgeTombstones(ctx context.Context, q *Queries) error {
+	if err := q.PurgeTokenTombstones(ctx, time.Now().UTC().Add(-dbo.TombstoneRetention)); err != nil {
+		return fmt.Errorf("purge tombstones: %w", err)
+	}
+	return nil
+}
+
+func (s *store) withProjects(ctx context.Context, row TokenView) (*dbo.Token, error) {
+	tok, err := mapToken(row)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := s.q.ListProjectAliases(ctx, row.User)
+	if err != nil {
+		return nil, fmt.Errorf("list project aliases: %w", err)
+	}
+	links, err := s.q.ListTokenProjects(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list token projects: %w", err)
+	}
+	attachProjects(tok, groupAliases(aliases), links)
+	return tok, nil
+}
+
+func mapTokens(rows []TokenView, aliases map[int64][]string, links map[int64][]TokenProjectView) []*dbo.Token {
+	out := make([]*dbo.Token, 0, len(rows))
+	for _, r := range rows {
+		tok, err := mapToken(r)
+		if err != nil {
+			slog.Warn("skipping corrupt token in list", "id", r.ID, "error", err)
+			continue
+		}
+		attachProjects(tok, aliases, links[r.ID])
+		out = append(out, tok)
+	}
+	return out
+}
+
+// attachProjects fills project aliases and linked projects of the token.
+func attachProjects(tok *dbo.Token, aliases map[int64][]string, links []TokenProjectView) {
+	tok.ProjectAliases = aliases[tok.ProjectID]
+	for _, l := range links {
+		tok.LinkedProjects = append(tok.LinkedProjects, dbo.ProjectRef{
+			ID:      l.ProjectID,
+			Slug:    l.ProjectSlug,
+			Aliases: aliases[l.ProjectID],
+		})
+	}
+}
+
+// keyFormat defaults empty format to native.
+func keyFormat(f types.KeyFormat) types.KeyFormat {
+	if f == "" {
+		return types.KeyNative
+	}
+	return f
+}
+
+func mapToken(row TokenView) (*dbo.Token, error) {
+	var hosts, paths []string
+	if err := json.Unmarshal([]byte(row.Hosts), &hosts); err != nil {
+		return nil, fmt.Errorf("unmarshal hosts for token %d: %w", row.ID, err)
+	}
+	if err := json.Unmarshal([]byte(row.Paths), &paths); err != nil {
+		return nil, fmt.Errorf("unmarshal paths for token %d: %w", row.ID, err)
+	}
+	var projectHosts, projectPaths []string
+	if err := json.Unmarshal([]byte(row.ProjectHosts), &projectHosts); err != nil {
+		return nil, fmt.Errorf("unmarshal project hosts for token %d: %w", row.ID, err)
+	}
+	if err := json.Unmarshal([]byte(row.ProjectPaths), &projectPaths); err != nil {
+		return nil, fmt.Errorf("unmarshal project paths for token %d: %w", row.ID, err)
+	}
+	return &dbo.Token{
+		ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt,
+		KeyID: &row.KeyID, Hash: row.Hash, HashAlg: types.HashAlgorithm(row.HashAlg), PepperID: row.PepperID, User: row.User, Label: row.Label,
+		Paths: paths, Hosts: hosts, Headers: row.Headers, Meta: row.Meta,
+		ProjectID: row.ProjectID, ProjectSlug: row.ProjectSlug,
+		ProjectHosts: projectHosts, ProjectPaths: projectPaths, ProjectHeaders: row.ProjectHeaders,
+		Requests: row.Requests, LastAccessAt: row.LastAccessAt,
+		DisabledAt: row.DisabledAt, DisabledReason: row.DisabledReason,
+		KeyFormat: types.KeyFormat(row.KeyFormat), KeyPrefix: row.KeyPrefix,
+	}, nil
+}
+
+func mapProjects(rows []Project, aliases map[int64][]string) []*dbo.Project {
+	out := make([]*dbo.Project, 0, len(rows))
+	for _, r := range rows {
+		p, err := mapProject(r, aliases[r.ID])
+		if err != nil {
+			slog.Warn("skipping corrupt project in list", "id", r.ID, "error", err)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func mapProject(row Project, aliases []string) (*dbo.Project, error) {
+	var hosts, paths []string
+	if err := json.Unmarshal([]byte(row.Hosts), &hosts); err != nil {
+		return nil, fmt.Errorf("unmarshal hosts for project %d: %w", row.ID, err)
+	}
+	if err := json.Unmarshal([]byte(row.Paths), &paths); err != nil {
+		return nil, fmt.Errorf("unmarshal paths for project %d: %w", row.ID, err)
+	}
+	return &dbo.Project{
+		ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt,
+		User: row.User, Slug: row.Slug, Description: row.Description,
+		Aliases: aliases, Hosts: hosts, Paths: paths, Headers: row.Headers,
+	}, nil
+}
+
+// nonNil keeps empty lists encoded as JSON arrays instead of null.
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
+
+// groupAliases indexes alias slugs by project ID.
+func groupAliases(rows []ProjectAlias) map[int64][]string {
+	out := make(map[int64][]string)
+	for _, r := range rows {
+		out[r.ProjectID] = append(out[r.ProjectID], r.Slug)
+	}
+	return out
+}
+
+// sortKey maps sort options to the ordering understood by the ListTokens query.
+func sortKey(p dbo.ListTokensParams) string {
+	field := p.Sort
+	if field == "" {
+		field = dbo.SortCreated
+	}
+	if p.Asc {
+		return string(field) + "_asc"
+	}
+	return string(field) + "_desc"
+}
+
+// stateFilter maps the optional disabled flag to the state argument of ListTokens query.
+func stateFilter(disabled *bool) string {
+	switch {
+	case disabled == nil:
+		return ""
+	case *disabled:
+		return "disabled"
+	default:
+		return "active"
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// groupLinks indexes linked projects by token ID.
+func groupLinks(rows []TokenProjectView) map[int64][]TokenProjectView {
+	out := make(map[int64][]TokenProjectView)
+	for _, r := range rows {
+		out[r.TokenID] = append(out[r.TokenID], r)
+	}
+	return out
+}
+
+func (s *store) Snapshot(ctx context.Context) (*dbo.Snapshot, error) {
+	projects, err := s.ListAllProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := s.q.ListAllProjectAliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list all project aliases: %w", err)
+	}
+	tokens, err := s.ListAllTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := &dbo.Snapshot{Projects: projects, Tokens: tokens, Aliases: make([]*dbo.ProjectAlias, 0, len(aliases))}
+	for _, a := range aliases {
+		out.Aliases = append(out.Aliases, &dbo.ProjectAlias{ID: a.ID, CreatedAt: a.CreatedAt, ProjectID: a.ProjectID, User: a.User, Slug: a.Slug})
+	}
+	return out, nil
+}
+
+//nolint:funlen // one insert per table
+func (s *store) Restore(ctx context.Context, snapshot *dbo.Snapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.q.WithTx(tx)
+	for _, p := range snapshot.Projects {
+		hostsJSON, err := json.Marshal(p.Hosts)
+		if err != nil {
+			return fmt.Errorf("marshal hosts: %w", err)
+		}
+		pathsJSON, err := json.Marshal(p.Paths)
+		if err != nil {
+			return fmt.Errorf("marshal paths: %w", err)
+		}
+		if err := q.RestoreProject(ctx, RestoreProjectParams{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt.UTC(),
+			UpdatedAt:   p.UpdatedAt.UTC(),
+			User:        p.User,
+			Slug:        p.Slug,
+			Description: p.Description,
+			Hosts:       string(hostsJSON),
+			Paths:       string(pathsJSON),
+			Headers:     p.Headers,
+		}); err != nil {
+			return fmt.Errorf("restore project %d: %w", p.ID, err)
+		}
+	}
+	for _, a := range snapshot.Aliases {
+		if err := q.RestoreProjectAlias(ctx, RestoreProjectAliasParams{
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt.UTC(),
+			ProjectID: a.ProjectID,
+			User:      a.User,
+			Slug:      a.Slug,
+		}); err != nil {
+			return fmt.Errorf("restore project alias %d: %w", a.ID, err)
+		}
+	}
+	for _, t := range snapshot.Tokens {
+		hostsJSON, err := json.Marshal(t.Hosts)
+		if err != nil {
+			return fmt.Errorf("marshal hosts: %w", err)
+		}
+		pathsJSON, err := json.Marshal(t.Paths)
+		if err != nil {
+			return fmt.Errorf("marshal paths: %w", err)
+		}
+		var disabledAt *time.Time
+		if t.DisabledAt != nil {
+			at := t.DisabledAt.UTC()
+			disabledAt = &at
+		}
+		if err := q.RestoreToken(ctx, RestoreTokenParams{
+			ID:             t.ID,
+			CreatedAt:      t.CreatedAt.UTC(),
+			UpdatedAt:      t.UpdatedAt.UTC(),
+			KeyID:          *t.KeyID,
+			Hash:           t.Hash,
+			HashAlg:        string(t.HashAlg),
+			PepperID:       t.PepperID,
+			User:           t.User,
+			Label:          t.Label,
+			Hosts:          string(hostsJSON),
+			Paths:          string(pathsJSON),
+			Headers:        t.Headers,
+			Meta:           t.Meta,
+			Requests:       t.Requests,
+			LastAccessAt:   t.LastAccessAt.UTC(),
+			ProjectID:      t.ProjectID,
+			DisabledAt:     disabledAt,
+			DisabledReason: t.DisabledReason,
+			KeyFormat:      string(keyFormat(t.KeyFormat)),
+			KeyPrefix:      t.KeyPrefix,
+		}); err != nil {
+			return fmt.Errorf("restore token %d: %w", t.ID, err)
+		}
+		if err := q.ClearTokenProjects(ctx, t.ID); err != nil {
+			return fmt.Errorf("clear linked projects of token %d: %w", t.ID, err)
+		}
+		for _, ref := range t.LinkedProjects {
+			if err := q.AddTokenProject(ctx, AddTokenProjectParams{TokenID: t.ID, ProjectID: ref.ID}); err != nil {
+				return fmt.Errorf("link project %d to token %d: %w", ref.ID, t.ID, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func (s *store) CountRows(ctx context.Context) (dbo.RowCounts, error) {
+	row, err := s.q.CountRows(ctx)
+	if err != nil {
+		return dbo.RowCounts{}, fmt.Errorf("count rows: %w", err)
+	}
+	return dbo.RowCounts(row), nil
+}
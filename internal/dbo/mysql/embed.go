@@ -0,0 +1,6 @@
+package mysql
+
+import "embed"
+
+//go:embed migrations/*.sql
+var MigrationsFS embed.FS
@@ -0,0 +1,21 @@
+-- +migrate Up
+-- Existing v1 (Ent) databases already have the table, so it is kept as is.
+CREATE TABLE IF NOT EXISTS token
+(
+    id             BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    created_at     DATETIME(6)  NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at     DATETIME(6)  NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    key_id         VARCHAR(255) NOT NULL,
+    hash           BLOB         NOT NULL,
+    `user`         VARCHAR(255) NOT NULL,
+    label          VARCHAR(255) NOT NULL DEFAULT '',
+    path           VARCHAR(255) NOT NULL DEFAULT '/**',
+    headers        JSON,
+    requests       BIGINT       NOT NULL DEFAULT 0,
+    last_access_at DATETIME(6)  NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY token_key_id (key_id),
+    KEY token_user (`user`)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- +migrate Down
+DROP TABLE IF EXISTS token;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE token ADD COLUMN host VARCHAR(255) NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE token DROP COLUMN host;
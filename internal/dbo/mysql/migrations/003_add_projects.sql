@@ -0,0 +1,37 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS project
+(
+    id          BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    created_at  DATETIME(6)  NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at  DATETIME(6)  NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    `user`      VARCHAR(255) NOT NULL DEFAULT '',
+    slug        VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL,
+    UNIQUE KEY project_user_slug (`user`, slug)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- Pre-fill default project for each user who already has tokens
+INSERT IGNORE INTO project (`user`, slug, description)
+SELECT DISTINCT `user`, '', 'Default project'
+FROM token;
+
+ALTER TABLE token ADD COLUMN project_id BIGINT;
+
+-- Assign all existing tokens to their user's default project
+UPDATE token SET project_id = (
+    SELECT id FROM project WHERE project.`user` = token.`user` AND project.slug = ''
+);
+
+ALTER TABLE token MODIFY COLUMN project_id BIGINT NOT NULL;
+ALTER TABLE token ADD CONSTRAINT token_project_fk FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label, t.path, t.host, t.headers, t.requests, t.last_access_at, t.project_id, p.slug AS project_slug
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_view;
+ALTER TABLE token DROP FOREIGN KEY token_project_fk;
+ALTER TABLE token DROP COLUMN project_id;
+DROP TABLE IF EXISTS project;
@@ -0,0 +1,17 @@
+-- +migrate Up
+-- Aliases share the slug namespace of the owner, so the same user can not have
+-- two aliases with the same slug (clashes with live slugs are checked in code).
+CREATE TABLE IF NOT EXISTS project_alias
+(
+    id         BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME(6)  NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    project_id BIGINT       NOT NULL,
+    `user`     VARCHAR(255) NOT NULL,
+    slug       VARCHAR(255) NOT NULL,
+    UNIQUE KEY project_alias_user_slug (`user`, slug),
+    KEY project_alias_project (project_id),
+    CONSTRAINT project_alias_project_fk FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- +migrate Down
+DROP TABLE IF EXISTS project_alias;
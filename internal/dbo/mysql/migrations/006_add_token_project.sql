@@ -0,0 +1,20 @@
+-- +migrate Up
+-- Additional projects a token is valid for, next to its own token.project_id.
+CREATE TABLE IF NOT EXISTS token_project
+(
+    token_id   BIGINT NOT NULL,
+    project_id BIGINT NOT NULL,
+    PRIMARY KEY (token_id, project_id),
+    KEY token_project_project (project_id),
+    CONSTRAINT token_project_token_fk FOREIGN KEY (token_id) REFERENCES token (id) ON DELETE CASCADE,
+    CONSTRAINT token_project_project_fk FOREIGN KEY (project_id) REFERENCES project (id) ON DELETE CASCADE
+);
+
+CREATE VIEW token_project_view AS
+SELECT tp.token_id, tp.project_id, p.slug AS project_slug, p.`user`
+FROM token_project tp
+JOIN project p ON tp.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_project_view;
+DROP TABLE IF EXISTS token_project;
@@ -0,0 +1,26 @@
+-- +migrate Up
+-- Arbitrary key/value metadata (JSON object with string values).
+ALTER TABLE token ADD COLUMN meta JSON NOT NULL DEFAULT ('{}');
+
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label,
+       t.hosts, t.paths, t.headers, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+ALTER TABLE token DROP COLUMN meta;
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- Algorithm of the stored hash; outdated hashes are upgraded on the next successful authorization.
+ALTER TABLE token ADD COLUMN hash_alg VARCHAR(32) NOT NULL DEFAULT 'sha3-384';
+
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers,
+       t.disabled_at, t.disabled_reason, t.hash_alg
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers,
+       t.disabled_at, t.disabled_reason
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+ALTER TABLE token DROP COLUMN hash_alg;
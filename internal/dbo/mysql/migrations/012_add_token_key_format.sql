@@ -0,0 +1,31 @@
+-- +migrate Up
+-- Format of the key presented by clients: 'native' keys are generated by the service,
+-- 'imported' keys came from another system and are identified by their public prefix.
+ALTER TABLE token ADD COLUMN key_format VARCHAR(32) NOT NULL DEFAULT 'native';
+ALTER TABLE token ADD COLUMN key_prefix VARCHAR(255) NOT NULL DEFAULT '';
+
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers,
+       t.disabled_at, t.disabled_reason, t.hash_alg, t.pepper_id, t.key_format, t.key_prefix
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t.`user`, t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers,
+       t.disabled_at, t.disabled_reason, t.hash_alg, t.pepper_id
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+ALTER TABLE token DROP COLUMN key_prefix;
+ALTER TABLE token DROP COLUMN key_format;
@@ -0,0 +1,19 @@
+-- +migrate Up
+-- Time of the last change of token config, key or state, used to sync caches incrementally.
+-- Unlike updated_at it's not changed by usage stats.
+ALTER TABLE token ADD COLUMN changed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6);
+CREATE INDEX token_changed_at ON token (changed_at);
+
+-- Deleted tokens, kept for a while so caches can drop them without full reload.
+CREATE TABLE IF NOT EXISTS token_tombstone
+(
+    id         BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    token_id   BIGINT      NOT NULL,
+    deleted_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    KEY token_tombstone_deleted_at (deleted_at)
+) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+-- +migrate Down
+DROP TABLE IF EXISTS token_tombstone;
+DROP INDEX token_changed_at ON token;
+ALTER TABLE token DROP COLUMN changed_at;
@@ -0,0 +1,77 @@
+-- name: GetProject :one
+SELECT * FROM project WHERE `user` = ? AND id = ?;
+
+-- name: ListProjects :many
+SELECT * FROM project WHERE `user` = ? ORDER BY id ASC;
+
+-- name: ListAllProjects :many
+SELECT * FROM project;
+
+-- name: CreateProject :execlastid
+INSERT INTO project (`user`, slug, description, hosts, paths, headers)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: UpdateProject :execrows
+UPDATE project SET slug = ?, description = ?, hosts = ?, paths = ?, headers = ?, updated_at = CURRENT_TIMESTAMP(6)
+WHERE `user` = ? AND id = ?;
+
+-- name: DeleteProject :execrows
+DELETE FROM project WHERE `user` = ? AND id = ?;
+
+-- name: ProjectExists :one
+SELECT EXISTS(SELECT 1 FROM project WHERE `user` = ? AND id = ?) AS ok;
+
+
+-- name: SlugInUse :one
+SELECT EXISTS(
+    SELECT 1 FROM project WHERE project.`user` = sqlc.arg('user') AND project.slug = sqlc.arg('slug')
+    UNION ALL
+    SELECT 1 FROM project_alias WHERE project_alias.`user` = sqlc.arg('user') AND project_alias.slug = sqlc.arg('slug')
+) AS ok;
+
+-- name: ListProjectTokenIDs :many
+SELECT t.id FROM token t JOIN project p ON t.project_id = p.id
+WHERE p.`user` = sqlc.arg('user') AND p.id = sqlc.arg('id')
+UNION
+SELECT tp.token_id FROM token_project tp JOIN project p ON tp.project_id = p.id
+WHERE p.`user` = sqlc.arg('user') AND p.id = sqlc.arg('id');
+
+-- name: ListProjectAliases :many
+SELECT * FROM project_alias WHERE `user` = ? ORDER BY id ASC;
+
+-- name: ListProjectAliasesByProject :many
+SELECT * FROM project_alias WHERE project_id = ? ORDER BY id ASC;
+
+-- name: ListAllProjectAliases :many
+SELECT * FROM project_alias ORDER BY id ASC;
+
+-- name: CreateProjectAlias :exec
+INSERT INTO project_alias (project_id, `user`, slug)
+VALUES (?, ?, ?);
+
+-- name: TouchProject :exec
+-- Marks project as changed, for example after changes of its aliases.
+UPDATE project SET updated_at = CURRENT_TIMESTAMP(6) WHERE id = ?;
+
+-- name: DeleteProjectAlias :execrows
+DELETE FROM project_alias WHERE `user` = ? AND project_id = ? AND slug = ?;
+
+-- name: RestoreProject :exec
+-- Insert or replace project with its original ID, used to copy databases.
+INSERT INTO project (id, created_at, updated_at, `user`, slug, description, hosts, paths, headers)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+    created_at = VALUES(created_at), updated_at = VALUES(updated_at), `user` = VALUES(`user`), slug = VALUES(slug),
+    description = VALUES(description), hosts = VALUES(hosts), paths = VALUES(paths), headers = VALUES(headers);
+
+-- name: RestoreProjectAlias :exec
+INSERT INTO project_alias (id, created_at, project_id, `user`, slug)
+VALUES (?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+    created_at = VALUES(created_at), project_id = VALUES(project_id), `user` = VALUES(`user`), slug = VALUES(slug);
+
+-- name: CountRows :one
+SELECT (SELECT COUNT(*) FROM project)       AS projects,
+       (SELECT COUNT(*) FROM project_alias) AS aliases,
+       (SELECT COUNT(*) FROM token)         AS tokens,
+       (SELECT COUNT(*) FROM token_project) AS token_projects;
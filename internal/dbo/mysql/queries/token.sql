@@ -0,0 +1,167 @@
+-- name: GetToken :one
+SELECT * FROM token_view WHERE `user` = ? AND id = ?;
+
+-- name: GetTokenByID :one
+SELECT * FROM token_view WHERE id = ?;
+
+-- name: GetTokenByKeyID :one
+SELECT * FROM token_view WHERE `user` = ? AND key_id = ?;
+
+-- name: FindTokenByKeyID :one
+SELECT * FROM token_view WHERE key_id = ?;
+
+-- name: ListTokens :many
+-- Empty/zero arguments disable the corresponding filter.
+-- meta is a JSON object, all pairs of which must be present in token meta.
+-- Keyset pagination: rows strictly after the cursor (after_id and the sort value) in the chosen order.
+-- Time is compared with one second precision; ties are resolved by id.
+SELECT token_view.*
+FROM token_view,
+     (SELECT CAST(sqlc.arg(sort) AS CHAR) AS sort) opts
+WHERE token_view.`user` = sqlc.arg(user)
+  AND (CAST(sqlc.arg(project_id) AS SIGNED) = 0
+    OR token_view.project_id = sqlc.arg(project_id)
+    OR token_view.id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id)))
+  AND JSON_CONTAINS(token_view.meta, CAST(sqlc.arg(meta) AS CHAR))
+  AND (CAST(sqlc.arg(search) AS CHAR) = ''
+    OR LOCATE(LOWER(sqlc.arg(search)), LOWER(token_view.label)) > 0
+    OR LOCATE(UPPER(sqlc.arg(search)), UPPER(token_view.key_id)) = 1)
+  AND (CAST(sqlc.arg(host) AS CHAR) = '' OR LOCATE(LOWER(sqlc.arg(host)), LOWER(CAST(token_view.hosts AS CHAR))) > 0)
+  AND (CAST(sqlc.arg(accessed_after) AS SIGNED) = 0
+    OR (token_view.requests > 0 AND FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) >= CAST(sqlc.arg(accessed_after) AS SIGNED)))
+  AND (CAST(sqlc.arg(accessed_before) AS SIGNED) = 0
+    OR (token_view.requests > 0 AND FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) < CAST(sqlc.arg(accessed_before) AS SIGNED)))
+  AND (CAST(sqlc.arg(unused_since) AS SIGNED) = 0
+    OR token_view.requests = 0 OR FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) < CAST(sqlc.arg(unused_since) AS SIGNED))
+  AND (CAST(sqlc.arg(state) AS CHAR) = ''
+    OR (sqlc.arg(state) = 'disabled') = (token_view.disabled_at IS NOT NULL))
+  AND (CAST(sqlc.arg(after_id) AS SIGNED) = 0 OR CASE opts.sort
+    WHEN 'created_asc' THEN token_view.id > sqlc.arg(after_id)
+    WHEN 'label_asc' THEN LOWER(token_view.label) > LOWER(sqlc.arg(after_label))
+        OR (LOWER(token_view.label) = LOWER(sqlc.arg(after_label)) AND token_view.id > sqlc.arg(after_id))
+    WHEN 'label_desc' THEN LOWER(token_view.label) < LOWER(sqlc.arg(after_label))
+        OR (LOWER(token_view.label) = LOWER(sqlc.arg(after_label)) AND token_view.id < sqlc.arg(after_id))
+    WHEN 'last_access_asc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) > CAST(sqlc.arg(after_value) AS SIGNED)
+        OR (FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) = CAST(sqlc.arg(after_value) AS SIGNED) AND token_view.id > sqlc.arg(after_id))
+    WHEN 'last_access_desc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) < CAST(sqlc.arg(after_value) AS SIGNED)
+        OR (FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) = CAST(sqlc.arg(after_value) AS SIGNED) AND token_view.id < sqlc.arg(after_id))
+    WHEN 'requests_asc' THEN token_view.requests > CAST(sqlc.arg(after_value) AS SIGNED)
+        OR (token_view.requests = CAST(sqlc.arg(after_value) AS SIGNED) AND token_view.id > sqlc.arg(after_id))
+    WHEN 'requests_desc' THEN token_view.requests < CAST(sqlc.arg(after_value) AS SIGNED)
+        OR (token_view.requests = CAST(sqlc.arg(after_value) AS SIGNED) AND token_view.id < sqlc.arg(after_id))
+    ELSE token_view.id < sqlc.arg(after_id)
+    END)
+ORDER BY CASE WHEN opts.sort = 'label_asc' THEN LOWER(token_view.label) END ASC,
+         CASE WHEN opts.sort = 'label_desc' THEN LOWER(token_view.label) END DESC,
+         CASE WHEN opts.sort = 'last_access_asc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) END ASC,
+         CASE WHEN opts.sort = 'last_access_desc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) END DESC,
+         CASE WHEN opts.sort = 'requests_asc' THEN token_view.requests END ASC,
+         CASE WHEN opts.sort = 'requests_desc' THEN token_view.requests END DESC,
+         CASE WHEN opts.sort IN ('created_asc', 'label_asc', 'last_access_asc', 'requests_asc') THEN token_view.id END ASC,
+         token_view.id DESC
+LIMIT ?;
+
+-- name: ListAllTokens :many
+SELECT * FROM token_view;
+
+-- name: CreateToken :execlastid
+INSERT INTO token (key_id, hash, hash_alg, pepper_id, `user`, label, paths, hosts, headers, meta, project_id, key_format, key_prefix)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateToken :execrows
+UPDATE token
+SET hosts = ?, paths = ?, label = ?, headers = ?, meta = ?, project_id = ?, updated_at = CURRENT_TIMESTAMP(6),
+    changed_at = CURRENT_TIMESTAMP(6)
+WHERE `user` = ? AND id = ?;
+
+-- name: RefreshToken :execrows
+UPDATE token
+SET hash = ?, hash_alg = ?, pepper_id = ?, key_id = ?, key_format = 'native', key_prefix = '', disabled_at = NULL, disabled_reason = '', updated_at = CURRENT_TIMESTAMP(6),
+    changed_at = CURRENT_TIMESTAMP(6)
+WHERE `user` = ? AND id = ?;
+
+-- name: RehashToken :execrows
+-- Compare-and-swap: the token is left as is if its hash was changed concurrently.
+UPDATE token
+SET hash = sqlc.arg(new_hash), hash_alg = sqlc.arg(hash_alg), pepper_id = sqlc.arg(pepper_id), changed_at = CURRENT_TIMESTAMP(6)
+WHERE id = sqlc.arg(id) AND hash = sqlc.arg(old_hash);
+
+-- name: DisableToken :execrows
+UPDATE token
+SET disabled_at = ?, disabled_reason = ?, updated_at = CURRENT_TIMESTAMP(6),
+    changed_at = CURRENT_TIMESTAMP(6)
+WHERE id = ? AND disabled_at IS NULL;
+
+-- name: DeleteToken :execrows
+DELETE FROM token WHERE `user` = ? AND id = ?;
+
+-- name: UpdateTokenStats :exec
+UPDATE token
+SET requests = requests + sqlc.arg(requests), last_access_at = sqlc.arg(last_access_at), updated_at = CURRENT_TIMESTAMP(6)
+WHERE id = sqlc.arg(id);
+
+-- name: ListChangedTokens :many
+-- Tokens changed since the time, directly or by changes of their own or linked projects.
+SELECT token_view.*
+FROM token_view
+WHERE token_view.id IN (SELECT t.id FROM token t WHERE t.changed_at >= sqlc.arg(since))
+   OR token_view.project_id IN (SELECT p.id FROM project p WHERE p.updated_at >= sqlc.arg(since))
+   OR token_view.id IN (SELECT tp.token_id
+                        FROM token_project tp
+                                 JOIN project p ON p.id = tp.project_id
+                        WHERE p.updated_at >= sqlc.arg(since));
+
+-- name: ListTokenTombstones :many
+SELECT DISTINCT token_id FROM token_tombstone WHERE deleted_at >= sqlc.arg(since);
+
+-- name: CreateTokenTombstone :exec
+INSERT INTO token_tombstone (token_id) VALUES (?);
+
+-- name: CreateProjectTokenTombstones :exec
+-- Tombstones of own tokens of the project, which are deleted with it.
+INSERT INTO token_tombstone (token_id) SELECT t.id FROM token t WHERE t.project_id = sqlc.arg(project_id);
+
+-- name: PurgeTokenTombstones :exec
+DELETE FROM token_tombstone WHERE deleted_at < sqlc.arg(before);
+
+-- name: TouchLinkedTokens :exec
+-- Marks tokens linked to the project as changed, since they lose the link when the project is deleted.
+UPDATE token SET changed_at = CURRENT_TIMESTAMP(6)
+WHERE id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id));
+
+-- name: ListTokenIDsByProject :many
+SELECT token.id FROM token WHERE token.project_id = sqlc.arg(project_id)
+UNION
+SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id);
+
+-- name: ListTokenProjects :many
+SELECT * FROM token_project_view WHERE token_id = ?;
+
+-- name: ListTokenProjectsByUser :many
+SELECT * FROM token_project_view WHERE `user` = ?;
+
+-- name: ListAllTokenProjects :many
+SELECT * FROM token_project_view;
+
+-- name: AddTokenProject :exec
+INSERT INTO token_project (token_id, project_id) VALUES (?, ?);
+
+-- name: ClearTokenProjects :exec
+DELETE FROM token_project WHERE token_id = ?;
+
+-- name: RemoveTokenProject :exec
+DELETE FROM token_project WHERE token_id = ? AND project_id = ?;
+
+
+-- name: RestoreToken :exec
+-- Insert or replace token with its original ID, hash and stats, used to copy databases.
+INSERT INTO token (id, created_at, updated_at, key_id, hash, hash_alg, pepper_id, `user`, label, hosts, paths, headers, meta,
+                   requests, last_access_at, project_id, disabled_at, disabled_reason, key_format, key_prefix)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+    created_at = VALUES(created_at), updated_at = VALUES(updated_at), key_id = VALUES(key_id), hash = VALUES(hash),
+    hash_alg = VALUES(hash_alg), pepper_id = VALUES(pepper_id), `user` = VALUES(`user`), label = VALUES(label),
+    hosts = VALUES(hosts), paths = VALUES(paths), headers = VALUES(headers), meta = VALUES(meta),
+    requests = VALUES(requests), last_access_at = VALUES(last_access_at), project_id = VALUES(project_id),
+    disabled_at = VALUES(disabled_at), disabled_reason = VALUES(disabled_reason),
+    key_format = VALUES(key_format), key_prefix = VALUES(key_prefix), changed_at = CURRENT_TIMESTAMP(6);
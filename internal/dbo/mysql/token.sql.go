@@ -0,0 +1,930 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: token.sql
+
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/reddec/token-login/internal/types"
+)
+
+const addTokenProject = `-- name: AddTokenProject :exec
+INSERT INTO token_project (token_id, project_id) VALUES (?, ?)
+`
+
+type AddTokenProjectParams struct {
+	TokenID   int64 `json:"token_id"`
+	ProjectID int64 `json:"project_id"`
+}
+
+func (q *Queries) AddTokenProject(ctx context.Context, arg AddTokenProjectParams) error {
+	_, err := q.db.ExecContext(ctx, addTokenProject, arg.TokenID, arg.ProjectID)
+	return err
+}
+
+const clearTokenProjects = `-- name: ClearTokenProjects :exec
+DELETE FROM token_project WHERE token_id = ?
+`
+
+func (q *Queries) ClearTokenProjects(ctx context.Context, tokenID int64) error {
+	_, err := q.db.ExecContext(ctx, clearTokenProjects, tokenID)
+	return err
+}
+
+const createProjectTokenTombstones = `-- name: CreateProjectTokenTombstones :exec
+INSERT INTO token_tombstone (token_id) SELECT t.id FROM token t WHERE t.project_id = ?
+`
+
+// Tombstones of own tokens of the project, which are deleted with it.
+func (q *Queries) CreateProjectTokenTombstones(ctx context.Context, projectID int64) error {
+	_, err := q.db.ExecContext(ctx, createProjectTokenTombstones, projectID)
+	return err
+}
+
+const createToken = `-- name: CreateToken :execlastid
+INSERT INTO token (key_id, hash, hash_alg, pepper_id, ` + "`" + `user` + "`" + `, label, paths, hosts, headers, meta, project_id, key_format, key_prefix)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateTokenParams struct {
+	KeyID     types.KeyID   `json:"key_id"`
+	Hash      []byte        `json:"hash"`
+	HashAlg   string        `json:"hash_alg"`
+	PepperID  string        `json:"pepper_id"`
+	User      string        `json:"user"`
+	Label     string        `json:"label"`
+	Paths     string        `json:"paths"`
+	Hosts     string        `json:"hosts"`
+	Headers   types.Headers `json:"headers"`
+	Meta      types.Meta    `json:"meta"`
+	ProjectID int64         `json:"project_id"`
+	KeyFormat string        `json:"key_format"`
+	KeyPrefix string        `json:"key_prefix"`
+}
+
+func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createToken,
+		arg.KeyID,
+		arg.Hash,
+		arg.HashAlg,
+		arg.PepperID,
+		arg.User,
+		arg.Label,
+		arg.Paths,
+		arg.Hosts,
+		arg.Headers,
+		arg.Meta,
+		arg.ProjectID,
+		arg.KeyFormat,
+		arg.KeyPrefix,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+const createTokenTombstone = `-- name: CreateTokenTombstone :exec
+INSERT INTO token_tombstone (token_id) VALUES (?)
+`
+
+func (q *Queries) CreateTokenTombstone(ctx context.Context, tokenID int64) error {
+	_, err := q.db.ExecContext(ctx, createTokenTombstone, tokenID)
+	return err
+}
+
+const deleteToken = `-- name: DeleteToken :execrows
+DELETE FROM token WHERE ` + "`" + `user` + "`" + ` = ? AND id = ?
+`
+
+type DeleteTokenParams struct {
+	User string `json:"user"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) DeleteToken(ctx context.Context, arg DeleteTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteToken, arg.User, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disableToken = `-- name: DisableToken :execrows
+UPDATE token
+SET disabled_at = ?, disabled_reason = ?, updated_at = CURRENT_TIMESTAMP(6),
+    changed_at = CURRENT_TIMESTAMP(6)
+WHERE id = ? AND disabled_at IS NULL
+`
+
+type DisableTokenParams struct {
+	DisabledAt     *time.Time `json:"disabled_at"`
+	DisabledReason string     `json:"disabled_reason"`
+	ID             int64      `json:"id"`
+}
+
+func (q *Queries) DisableToken(ctx context.Context, arg DisableTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, disableToken, arg.DisabledAt, arg.DisabledReason, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const findTokenByKeyID = `-- name: FindTokenByKeyID :one
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE key_id = ?
+`
+
+func (q *Queries) FindTokenByKeyID(ctx context.Context, keyID types.KeyID) (TokenView, error) {
+	row := q.db.QueryRowContext(ctx, findTokenByKeyID, keyID)
+	var i TokenView
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.KeyID,
+		&i.Hash,
+		&i.User,
+		&i.Label,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
+		&i.Meta,
+		&i.Requests,
+		&i.LastAccessAt,
+		&i.ProjectID,
+		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
+	)
+	return i, err
+}
+
+const getToken = `-- name: GetToken :one
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE ` + "`" + `user` + "`" + ` = ? AND id = ?
+`
+
+type GetTokenParams struct {
+	User string `json:"user"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) GetToken(ctx context.Context, arg GetTokenParams) (TokenView, error) {
+	row := q.db.QueryRowContext(ctx, getToken, arg.User, arg.ID)
+	var i TokenView
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.KeyID,
+		&i.Hash,
+		&i.User,
+		&i.Label,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
+		&i.Meta,
+		&i.Requests,
+		&i.LastAccessAt,
+		&i.ProjectID,
+		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
+	)
+	return i, err
+}
+
+const getTokenByID = `-- name: GetTokenByID :one
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE id = ?
+`
+
+func (q *Queries) GetTokenByID(ctx context.Context, id int64) (TokenView, error) {
+	row := q.db.QueryRowContext(ctx, getTokenByID, id)
+	var i TokenView
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.KeyID,
+		&i.Hash,
+		&i.User,
+		&i.Label,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
+		&i.Meta,
+		&i.Requests,
+		&i.LastAccessAt,
+		&i.ProjectID,
+		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
+	)
+	return i, err
+}
+
+const getTokenByKeyID = `-- name: GetTokenByKeyID :one
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE ` + "`" + `user` + "`" + ` = ? AND key_id = ?
+`
+
+type GetTokenByKeyIDParams struct {
+	User  string      `json:"user"`
+	KeyID types.KeyID `json:"key_id"`
+}
+
+func (q *Queries) GetTokenByKeyID(ctx context.Context, arg GetTokenByKeyIDParams) (TokenView, error) {
+	row := q.db.QueryRowContext(ctx, getTokenByKeyID, arg.User, arg.KeyID)
+	var i TokenView
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.KeyID,
+		&i.Hash,
+		&i.User,
+		&i.Label,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
+		&i.Meta,
+		&i.Requests,
+		&i.LastAccessAt,
+		&i.ProjectID,
+		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
+	)
+	return i, err
+}
+
+const listAllTokenProjects = `-- name: ListAllTokenProjects :many
+SELECT token_id, project_id, project_slug, user FROM token_project_view
+`
+
+func (q *Queries) ListAllTokenProjects(ctx context.Context) ([]TokenProjectView, error) {
+	rows, err := q.db.QueryContext(ctx, listAllTokenProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenProjectView{}
+	for rows.Next() {
+		var i TokenProjectView
+		if err := rows.Scan(
+			&i.TokenID,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.User,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllTokens = `-- name: ListAllTokens :many
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view
+`
+
+func (q *Queries) ListAllTokens(ctx context.Context) ([]TokenView, error) {
+	rows, err := q.db.QueryContext(ctx, listAllTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenView{}
+	for rows.Next() {
+		var i TokenView
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.KeyID,
+			&i.Hash,
+			&i.User,
+			&i.Label,
+			&i.Hosts,
+			&i.Paths,
+			&i.Headers,
+			&i.Meta,
+			&i.Requests,
+			&i.LastAccessAt,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.ProjectHosts,
+			&i.ProjectPaths,
+			&i.ProjectHeaders,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.HashAlg,
+			&i.PepperID,
+			&i.KeyFormat,
+			&i.KeyPrefix,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChangedTokens = `-- name: ListChangedTokens :many
+SELECT token_view.id, token_view.created_at, token_view.updated_at, token_view.key_id, token_view.hash, token_view.user, token_view.label, token_view.hosts, token_view.paths, token_view.headers, token_view.meta, token_view.requests, token_view.last_access_at, token_view.project_id, token_view.project_slug, token_view.project_hosts, token_view.project_paths, token_view.project_headers, token_view.disabled_at, token_view.disabled_reason, token_view.hash_alg, token_view.pepper_id, token_view.key_format, token_view.key_prefix
+FROM token_view
+WHERE token_view.id IN (SELECT t.id FROM token t WHERE t.changed_at >= ?)
+   OR token_view.project_id IN (SELECT p.id FROM project p WHERE p.updated_at >= ?)
+   OR token_view.id IN (SELECT tp.token_id
+                        FROM token_project tp
+                                 JOIN project p ON p.id = tp.project_id
+                        WHERE p.updated_at >= ?)
+`
+
+type ListChangedTokensParams struct {
+	Since time.Time `json:"since"`
+}
+
+// Tokens changed since the time, directly or by changes of their own or linked projects.
+func (q *Queries) ListChangedTokens(ctx context.Context, arg ListChangedTokensParams) ([]TokenView, error) {
+	rows, err := q.db.QueryContext(ctx, listChangedTokens, arg.Since, arg.Since, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenView{}
+	for rows.Next() {
+		var i TokenView
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.KeyID,
+			&i.Hash,
+			&i.User,
+			&i.Label,
+			&i.Hosts,
+			&i.Paths,
+			&i.Headers,
+			&i.Meta,
+			&i.Requests,
+			&i.LastAccessAt,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.ProjectHosts,
+			&i.ProjectPaths,
+			&i.ProjectHeaders,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.HashAlg,
+			&i.PepperID,
+			&i.KeyFormat,
+			&i.KeyPrefix,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTokenIDsByProject = `-- name: ListTokenIDsByProject :many
+SELECT token.id FROM token WHERE token.project_id = ?
+UNION
+SELECT tp.token_id FROM token_project tp WHERE tp.project_id = ?
+`
+
+type ListTokenIDsByProjectParams struct {
+	ProjectID int64 `json:"project_id"`
+}
+
+func (q *Queries) ListTokenIDsByProject(ctx context.Context, arg ListTokenIDsByProjectParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenIDsByProject, arg.ProjectID, arg.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTokenProjects = `-- name: ListTokenProjects :many
+SELECT token_id, project_id, project_slug, user FROM token_project_view WHERE token_id = ?
+`
+
+func (q *Queries) ListTokenProjects(ctx context.Context, tokenID int64) ([]TokenProjectView, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenProjects, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenProjectView{}
+	for rows.Next() {
+		var i TokenProjectView
+		if err := rows.Scan(
+			&i.TokenID,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.User,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTokenProjectsByUser = `-- name: ListTokenProjectsByUser :many
+SELECT token_id, project_id, project_slug, user FROM token_project_view WHERE ` + "`" + `user` + "`" + ` = ?
+`
+
+func (q *Queries) ListTokenProjectsByUser(ctx context.Context, user string) ([]TokenProjectView, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenProjectsByUser, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenProjectView{}
+	for rows.Next() {
+		var i TokenProjectView
+		if err := rows.Scan(
+			&i.TokenID,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.User,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTokenTombstones = `-- name: ListTokenTombstones :many
+SELECT DISTINCT token_id FROM token_tombstone WHERE deleted_at >= ?
+`
+
+func (q *Queries) ListTokenTombstones(ctx context.Context, since time.Time) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenTombstones, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var token_id int64
+		if err := rows.Scan(&token_id); err != nil {
+			return nil, err
+		}
+		items = append(items, token_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTokens = `-- name: ListTokens :many
+SELECT token_view.id, token_view.created_at, token_view.updated_at, token_view.key_id, token_view.hash, token_view.user, token_view.label, token_view.hosts, token_view.paths, token_view.headers, token_view.meta, token_view.requests, token_view.last_access_at, token_view.project_id, token_view.project_slug, token_view.project_hosts, token_view.project_paths, token_view.project_headers, token_view.disabled_at, token_view.disabled_reason, token_view.hash_alg, token_view.pepper_id, token_view.key_format, token_view.key_prefix
+FROM token_view,
+     (SELECT CAST(? AS CHAR) AS sort) opts
+WHERE token_view.` + "`" + `user` + "`" + ` = ?
+  AND (CAST(? AS SIGNED) = 0
+    OR token_view.project_id = ?
+    OR token_view.id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = ?))
+  AND JSON_CONTAINS(token_view.meta, CAST(? AS CHAR))
+  AND (CAST(? AS CHAR) = ''
+    OR LOCATE(LOWER(?), LOWER(token_view.label)) > 0
+    OR LOCATE(UPPER(?), UPPER(token_view.key_id)) = 1)
+  AND (CAST(? AS CHAR) = '' OR LOCATE(LOWER(?), LOWER(CAST(token_view.hosts AS CHAR))) > 0)
+  AND (CAST(? AS SIGNED) = 0
+    OR (token_view.requests > 0 AND FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) >= CAST(? AS SIGNED)))
+  AND (CAST(? AS SIGNED) = 0
+    OR (token_view.requests > 0 AND FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) < CAST(? AS SIGNED)))
+  AND (CAST(? AS SIGNED) = 0
+    OR token_view.requests = 0 OR FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) < CAST(? AS SIGNED))
+  AND (CAST(? AS CHAR) = ''
+    OR (? = 'disabled') = (token_view.disabled_at IS NOT NULL))
+  AND (CAST(? AS SIGNED) = 0 OR CASE opts.sort
+    WHEN 'created_asc' THEN token_view.id > ?
+    WHEN 'label_asc' THEN LOWER(token_view.label) > LOWER(?)
+        OR (LOWER(token_view.label) = LOWER(?) AND token_view.id > ?)
+    WHEN 'label_desc' THEN LOWER(token_view.label) < LOWER(?)
+        OR (LOWER(token_view.label) = LOWER(?) AND token_view.id < ?)
+    WHEN 'last_access_asc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) > CAST(? AS SIGNED)
+        OR (FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) = CAST(? AS SIGNED) AND token_view.id > ?)
+    WHEN 'last_access_desc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) < CAST(? AS SIGNED)
+        OR (FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) = CAST(? AS SIGNED) AND token_view.id < ?)
+    WHEN 'requests_asc' THEN token_view.requests > CAST(? AS SIGNED)
+        OR (token_view.requests = CAST(? AS SIGNED) AND token_view.id > ?)
+    WHEN 'requests_desc' THEN token_view.requests < CAST(? AS SIGNED)
+        OR (token_view.requests = CAST(? AS SIGNED) AND token_view.id < ?)
+    ELSE token_view.id < ?
+    END)
+ORDER BY CASE WHEN opts.sort = 'label_asc' THEN LOWER(token_view.label) END ASC,
+         CASE WHEN opts.sort = 'label_desc' THEN LOWER(token_view.label) END DESC,
+         CASE WHEN opts.sort = 'last_access_asc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) END ASC,
+         CASE WHEN opts.sort = 'last_access_desc' THEN FLOOR(UNIX_TIMESTAMP(token_view.last_access_at)) END DESC,
+         CASE WHEN opts.sort = 'requests_asc' THEN token_view.requests END ASC,
+         CASE WHEN opts.sort = 'requests_desc' THEN token_view.requests END DESC,
+         CASE WHEN opts.sort IN ('created_asc', 'label_asc', 'last_access_asc', 'requests_asc') THEN token_view.id END ASC,
+         token_view.id DESC
+LIMIT ?
+`
+
+type ListTokensParams struct {
+	Sort           interface{} `json:"sort"`
+	User           string      `json:"user"`
+	ProjectID      int64       `json:"project_id"`
+	Meta           interface{} `json:"meta"`
+	Search         string      `json:"search"`
+	Host           string      `json:"host"`
+	AccessedAfter  int64       `json:"accessed_after"`
+	AccessedBefore int64       `json:"accessed_before"`
+	UnusedSince    int64       `json:"unused_since"`
+	State          interface{} `json:"state"`
+	AfterID        int64       `json:"after_id"`
+	AfterLabel     string      `json:"after_label"`
+	AfterValue     int64       `json:"after_value"`
+	Limit          int32       `json:"limit"`
+}
+
+// Empty/zero arguments disable the corresponding filter.
+// meta is a JSON object, all pairs of which must be present in token meta.
+// Keyset pagination: rows strictly after the cursor (after_id and the sort value) in the chosen order.
+// Time is compared with one second precision; ties are resolved by id.
+func (q *Queries) ListTokens(ctx context.Context, arg ListTokensParams) ([]TokenView, error) {
+	rows, err := q.db.QueryContext(ctx, listTokens,
+		arg.Sort,
+		arg.User,
+		arg.ProjectID,
+		arg.ProjectID,
+		arg.ProjectID,
+		arg.Meta,
+		arg.Search,
+		arg.Search,
+		arg.Search,
+		arg.Host,
+		arg.Host,
+		arg.AccessedAfter,
+		arg.AccessedAfter,
+		arg.AccessedBefore,
+		arg.AccessedBefore,
+		arg.UnusedSince,
+		arg.UnusedSince,
+		arg.State,
+		arg.State,
+		arg.AfterID,
+		arg.AfterID,
+		arg.AfterLabel,
+		arg.AfterLabel,
+		arg.AfterID,
+		arg.AfterLabel,
+		arg.AfterLabel,
+		arg.AfterID,
+		arg.AfterValue,
+		arg.AfterValue,
+		arg.AfterID,
+		arg.AfterValue,
+		arg.AfterValue,
+		arg.AfterID,
+		arg.AfterValue,
+		arg.AfterValue,
+		arg.AfterID,
+		arg.AfterValue,
+		arg.AfterValue,
+		arg.AfterID,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenView{}
+	for rows.Next() {
+		var i TokenView
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.KeyID,
+			&i.Hash,
+			&i.User,
+			&i.Label,
+			&i.Hosts,
+			&i.Paths,
+			&i.Headers,
+			&i.Meta,
+			&i.Requests,
+			&i.LastAccessAt,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.ProjectHosts,
+			&i.ProjectPaths,
+			&i.ProjectHeaders,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.HashAlg,
+			&i.PepperID,
+			&i.KeyFormat,
+			&i.KeyPrefix,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeTokenTombstones = `-- name: PurgeTokenTombstones :exec
+DELETE FROM token_tombstone WHERE deleted_at < ?
+`
+
+func (q *Queries) PurgeTokenTombstones(ctx context.Context, before time.Time) error {
+	_, err := q.db.ExecContext(ctx, purgeTokenTombstones, before)
+	return err
+}
+
+const refreshToken = `-- name: RefreshToken :execrows
+UPDATE token
+SET hash = ?, hash_alg = ?, pepper_id = ?, key_id = ?, key_format = 'native', key_prefix = '', disabled_at = NULL, disabled_reason = '', updated_at = CURRENT_TIMESTAMP(6),
+    changed_at = CURRENT_TIMESTAMP(6)
+WHERE ` + "`" + `user` + "`" + ` = ? AND id = ?
+`
+
+type RefreshTokenParams struct {
+	Hash     []byte      `json:"hash"`
+	HashAlg  string      `json:"hash_alg"`
+	PepperID string      `json:"pepper_id"`
+	KeyID    types.KeyID `json:"key_id"`
+	User     string      `json:"user"`
+	ID       int64       `json:"id"`
+}
+
+func (q *Queries) RefreshToken(ctx context.Context, arg RefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, refreshToken,
+		arg.Hash,
+		arg.HashAlg,
+		arg.PepperID,
+		arg.KeyID,
+		arg.User,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rehashToken = `-- name: RehashToken :execrows
+UPDATE token
+SET hash = ?, hash_alg = ?, pepper_id = ?, changed_at = CURRENT_TIMESTAMP(6)
+WHERE id = ? AND hash = ?
+`
+
+type RehashTokenParams struct {
+	NewHash  []byte `json:"new_hash"`
+	HashAlg  string `json:"hash_alg"`
+	PepperID string `json:"pepper_id"`
+	ID       int64  `json:"id"`
+	OldHash  []byte `json:"old_hash"`
+}
+
+// Compare-and-swap: the token is left as is if its hash was changed concurrently.
+func (q *Queries) RehashToken(ctx context.Context, arg RehashTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashToken,
+		arg.NewHash,
+		arg.HashAlg,
+		arg.PepperID,
+		arg.ID,
+		arg.OldHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeTokenProject = `-- name: RemoveTokenProject :exec
+DELETE FROM token_project WHERE token_id = ? AND project_id = ?
+`
+
+type RemoveTokenProjectParams struct {
+	TokenID   int64 `json:"token_id"`
+	ProjectID int64 `json:"project_id"`
+}
+
+func (q *Queries) RemoveTokenProject(ctx context.Context, arg RemoveTokenProjectParams) error {
+	_, err := q.db.ExecContext(ctx, removeTokenProject, arg.TokenID, arg.ProjectID)
+	return err
+}
+
+const restoreToken = `-- name: RestoreToken :exec
+INSERT INTO token (id, created_at, updated_at, key_id, hash, hash_alg, pepper_id, ` + "`" + `user` + "`" + `, label, hosts, paths, headers, meta,
+                   requests, last_access_at, project_id, disabled_at, disabled_reason, key_format, key_prefix)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE
+    created_at = VALUES(created_at), updated_at = VALUES(updated_at), key_id = VALUES(key_id), hash = VALUES(hash),
+    hash_alg = VALUES(hash_alg), pepper_id = VALUES(pepper_id), ` + "`" + `user` + "`" + ` = VALUES(` + "`" + `user` + "`" + `), label = VALUES(label),
+    hosts = VALUES(hosts), paths = VALUES(paths), headers = VALUES(headers), meta = VALUES(meta),
+    requests = VALUES(requests), last_access_at = VALUES(last_access_at), project_id = VALUES(project_id),
+    disabled_at = VALUES(disabled_at), disabled_reason = VALUES(disabled_reason),
+    key_format = VALUES(key_format), key_prefix = VALUES(key_prefix), changed_at = CURRENT_TIMESTAMP(6)
+`
+
+type RestoreTokenParams struct {
+	ID             int64         `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	KeyID          types.KeyID   `json:"key_id"`
+	Hash           []byte        `json:"hash"`
+	HashAlg        string        `json:"hash_alg"`
+	PepperID       string        `json:"pepper_id"`
+	User           string        `json:"user"`
+	Label          string        `json:"label"`
+	Hosts          string        `json:"hosts"`
+	Paths          string        `json:"paths"`
+	Headers        types.Headers `json:"headers"`
+	Meta           types.Meta    `json:"meta"`
+	Requests       int64         `json:"requests"`
+	LastAccessAt   time.Time     `json:"last_access_at"`
+	ProjectID      int64         `json:"project_id"`
+	DisabledAt     *time.Time    `json:"disabled_at"`
+	DisabledReason string        `json:"disabled_reason"`
+	KeyFormat      string        `json:"key_format"`
+	KeyPrefix      string        `json:"key_prefix"`
+}
+
+// Insert or replace token with its original ID, hash and stats, used to copy databases.
+func (q *Queries) RestoreToken(ctx context.Context, arg RestoreTokenParams) error {
+	_, err := q.db.ExecContext(ctx, restoreToken,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.KeyID,
+		arg.Hash,
+		arg.HashAlg,
+		arg.PepperID,
+		arg.User,
+		arg.Label,
+		arg.Hosts,
+		arg.Paths,
+		arg.Headers,
+		arg.Meta,
+		arg.Requests,
+		arg.LastAccessAt,
+		arg.ProjectID,
+		arg.DisabledAt,
+		arg.DisabledReason,
+		arg.KeyFormat,
+		arg.KeyPrefix,
+	)
+	return err
+}
+
+const touchLinkedTokens = `-- name: TouchLinkedTokens :exec
+UPDATE token SET changed_at = CURRENT_TIMESTAMP(6)
+WHERE id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = ?)
+`
+
+// Marks tokens linked to the project as changed, since they lose the link when the project is deleted.
+func (q *Queries) TouchLinkedTokens(ctx context.Context, projectID int64) error {
+	_, err := q.db.ExecContext(ctx, touchLinkedTokens, projectID)
+	return err
+}
+
+const updateToken = `-- name: UpdateToken :execrows
+UPDATE token
+SET hosts = ?, paths = ?, label = ?, headers = ?, meta = ?, project_id = ?, updated_at = CURRENT_TIMESTAMP(6),
+    changed_at = CURRENT_TIMESTAMP(6)
+WHERE ` + "`" + `user` + "`" + ` = ? AND id = ?
+`
+
+type UpdateTokenParams struct {
+	Hosts     string        `json:"hosts"`
+	Paths     string        `json:"paths"`
+	Label     string        `json:"label"`
+	Headers   types.Headers `json:"headers"`
+	Meta      types.Meta    `json:"meta"`
+	ProjectID int64         `json:"project_id"`
+	User      string        `json:"user"`
+	ID        int64         `json:"id"`
+}
+
+func (q *Queries) UpdateToken(ctx context.Context, arg UpdateTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateToken,
+		arg.Hosts,
+		arg.Paths,
+		arg.Label,
+		arg.Headers,
+		arg.Meta,
+		arg.ProjectID,
+		arg.User,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateTokenStats = `-- name: UpdateTokenStats :exec
+UPDATE token
+SET requests = requests + ?, last_access_at = ?, updated_at = CURRENT_TIMESTAMP(6)
+WHERE id = ?
+`
+
+type UpdateTokenStatsParams struct {
+	Requests     int64     `json:"requests"`
+	LastAccessAt time.Time `json:"last_access_at"`
+	ID           int64     `json:"id"`
+}
+
+func (q *Queries) UpdateTokenStats(ctx context.Context, arg UpdateTokenStatsParams) error {
+	_, err := q.db.ExecContext(ctx, updateTokenStats, arg.Requests, arg.LastAccessAt, arg.ID)
+	return err
+}
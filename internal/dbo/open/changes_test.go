@@ -0,0 +1,159 @@
+package open_test
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/open"
+	"github.com/reddec/token-login/internal/types"
+)
+
+func TestTokenChanges(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		testTokenChanges(t, "sqlite://"+filepath.Join(t.TempDir(), "changes.db"))
+	})
+	t.Run("memory", func(t *testing.T) {
+		testTokenChanges(t, "memory://")
+	})
+	t.Run("postgres", func(t *testing.T) {
+		testTokenChanges(t, "postgres://test:test@"+pgAddr(t)+"/testdb?sslmode=disable")
+	})
+	t.Run("mysql", func(t *testing.T) {
+		testTokenChanges(t, "mysql://test:test@"+mysqlAddr(t)+"/testdb")
+	})
+}
+
+func testTokenChanges(t *testing.T, dbURL string) {
+	ctx := context.Background()
+	store, err := open.Open(ctx, dbURL, nil)
+	require.NoError(t, err)
+	defer store.Close()
+
+	createToken := func(projectID int64, linked ...int64) *dbo.Token {
+		key, err := types.NewKey()
+		require.NoError(t, err)
+		kid := key.ID()
+		token, err := store.CreateToken(ctx, dbo.CreateTokenParams{
+			User:             "alice",
+			Hash:             key.Hash(),
+			KeyID:            &kid,
+			ProjectID:        projectID,
+			LinkedProjectIDs: linked,
+		})
+		require.NoError(t, err)
+		return token
+	}
+	ids := func(tokens []*dbo.Token) []int64 {
+		out := make([]int64, 0, len(tokens))
+		for _, t := range tokens {
+			out = append(out, t.ID)
+		}
+		slices.Sort(out)
+		return out
+	}
+	// changes are compared with one second precision in SQLite,
+	// so previous changes and next ones must be in different seconds
+	nextWindow := func() time.Time {
+		time.Sleep(1100 * time.Millisecond)
+		changes, err := store.ListTokenChanges(ctx, time.Time{})
+		require.NoError(t, err)
+		time.Sleep(1100 * time.Millisecond)
+		return changes.At
+	}
+
+	first, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "first"})
+	require.NoError(t, err)
+	second, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "second"})
+	require.NoError(t, err)
+	own := createToken(first.ID)
+	linked := createToken(second.ID, first.ID)
+	other := createToken(second.ID)
+
+	all, err := store.ListTokenChanges(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{own.ID, linked.ID, other.ID}, ids(all.Tokens))
+	assert.Empty(t, all.Deleted)
+	assert.False(t, all.At.IsZero())
+
+	since := nextWindow()
+	require.NoError(t, store.UpdateStats(ctx, map[int64]dbo.StatsEntry{own.ID: {Hits: 1, Last: time.Now()}}))
+	changes, err := store.ListTokenChanges(ctx, since)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Tokens, "stats are not changes")
+	assert.Empty(t, changes.Deleted)
+
+	label := "changed"
+	_, err = store.UpdateToken(ctx, dbo.UpdateTokenParams{User: "alice", ID: own.ID, Label: &label})
+	require.NoError(t, err)
+	require.NoError(t, store.AddProjectAlias(ctx, "alice", second.ID, "alias"))
+	changes, err = store.ListTokenChanges(ctx, since)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{own.ID, linked.ID, other.ID}, ids(changes.Tokens), "alias changes tokens of the project")
+
+	since = nextWindow()
+	_, err = store.DeleteToken(ctx, "alice", other.ID)
+	require.NoError(t, err)
+	_, err = store.DeleteProject(ctx, "alice", first.ID)
+	require.NoError(t, err)
+	changes, err = store.ListTokenChanges(ctx, since)
+	require.NoError(t, err)
+	require.Equal(t, []int64{linked.ID}, ids(changes.Tokens), "linked token loses the deleted project")
+	assert.Empty(t, changes.Tokens[0].LinkedProjects)
+	deleted := slices.Clone(changes.Deleted)
+	slices.Sort(deleted)
+	assert.Equal(t, []int64{own.ID, other.ID}, deleted)
+}
+
+func TestNotifyPostgres(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, err := open.Open(ctx, "postgres://test:test@"+pgAddr(t)+"/testdb?sslmode=disable", nil)
+	require.NoError(t, err)
+	defer store.Close()
+	notifier, ok := store.(dbo.Notifier)
+	require.True(t, ok)
+
+	events := make(chan dbo.TokenEvent, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- notifier.Listen(ctx, func(event dbo.TokenEvent) { events <- event })
+	}()
+	next := func() dbo.TokenEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no notification")
+			return dbo.TokenEvent{}
+		}
+	}
+
+	project, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "notify"})
+	require.NoError(t, err)
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	// subscription is asynchronous
+	time.Sleep(500 * time.Millisecond)
+	token, err := store.CreateToken(ctx, dbo.CreateTokenParams{User: "alice", Hash: key.Hash(), KeyID: &kid, ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Equal(t, dbo.TokenEvent{ID: token.ID}, next())
+
+	_, err = store.DisableToken(ctx, token.ID, "leaked", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, dbo.TokenEvent{ID: token.ID}, next())
+
+	_, err = store.DeleteToken(ctx, "alice", token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dbo.TokenEvent{ID: token.ID, Removed: true}, next())
+
+	cancel()
+	require.Error(t, <-done)
+}
@@ -0,0 +1,103 @@
+package open_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/open"
+	"github.com/reddec/token-login/internal/types"
+)
+
+func TestCopy(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		testCopy(t, "sqlite://"+filepath.Join(t.TempDir(), "target.db"))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		testCopy(t, "postgres://test:test@"+pgAddr(t)+"/testdb?sslmode=disable")
+	})
+	t.Run("mysql", func(t *testing.T) {
+		testCopy(t, "mysql://test:test@"+mysqlAddr(t)+"/testdb")
+	})
+	t.Run("memory", func(t *testing.T) {
+		testCopy(t, "memory://")
+	})
+}
+
+func testCopy(t *testing.T, targetURL string) {
+	ctx := context.Background()
+	src, err := open.Open(ctx, "sqlite://"+filepath.Join(t.TempDir(), "source.db"), nil)
+	require.NoError(t, err)
+	defer src.Close()
+
+	// gap in IDs must survive the copy
+	removed, err := src.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "removed"})
+	require.NoError(t, err)
+	_, err = src.DeleteProject(ctx, "alice", removed.ID)
+	require.NoError(t, err)
+
+	def, err := src.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: ""})
+	require.NoError(t, err)
+	web, err := src.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "web", Hosts: []string{"example.com"}})
+	require.NoError(t, err)
+	require.NoError(t, src.AddProjectAlias(ctx, "alice", web.ID, "www"))
+
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	token, err := src.CreateToken(ctx, dbo.CreateTokenParams{
+		User:             "alice",
+		Hash:             key.Hash(),
+		HashSpec:         types.HashSpec{Algorithm: types.HashSHA3},
+		KeyID:            &kid,
+		Label:            "ci",
+		Paths:            []string{"/api/**"},
+		Meta:             types.Meta{"team": "core"},
+		ProjectID:        web.ID,
+		LinkedProjectIDs: []int64{def.ID},
+	})
+	require.NoError(t, err)
+	last := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, src.UpdateStats(ctx, map[int64]dbo.StatsEntry{token.ID: {Hits: 42, Last: last}}))
+	_, err = src.DisableToken(ctx, token.ID, "leaked", time.Now())
+	require.NoError(t, err)
+
+	dst, err := open.Open(ctx, targetURL, nil)
+	require.NoError(t, err)
+	defer dst.Close()
+
+	counts, err := dbo.Copy(ctx, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, dbo.RowCounts{Projects: 2, Aliases: 1, Tokens: 1, TokenProjects: 1}, counts)
+
+	// re-run replaces rows with the same IDs
+	_, err = dbo.Copy(ctx, src, dst)
+	require.NoError(t, err)
+
+	copied, err := dst.FindTokenByKeyID(ctx, kid)
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, copied.ID)
+	assert.Equal(t, web.ID, copied.ProjectID)
+	assert.Equal(t, []string{"www"}, copied.ProjectAliases)
+	assert.Equal(t, key.Hash(), copied.Hash)
+	assert.Equal(t, types.HashSHA3, copied.HashAlg)
+	assert.Equal(t, int64(42), copied.Requests)
+	assert.True(t, last.Equal(copied.LastAccessAt), "%v != %v", last, copied.LastAccessAt)
+	assert.Equal(t, "leaked", copied.DisabledReason)
+	assert.True(t, copied.Disabled())
+	require.Len(t, copied.LinkedProjects, 1)
+	assert.Equal(t, def.ID, copied.LinkedProjects[0].ID)
+
+	// new rows don't collide with copied IDs
+	created, err := dst.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "new"})
+	require.NoError(t, err)
+	assert.Greater(t, created.ID, web.ID)
+
+	_, err = dbo.Copy(ctx, src, dst)
+	require.ErrorIs(t, err, dbo.ErrRowCount)
+}
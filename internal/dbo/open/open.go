@@ -1,6 +1,6 @@
 // Package open provides the database initialization factory.
 // It parses the DSN scheme and dispatches to the engine-specific Open
-// functions in the sqlite and postgres packages.
+// functions in the sqlite, postgres, mysql and memory packages.
 package open
 
 import (
@@ -12,11 +12,16 @@ import (
 	"strings"
 
 	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/memory"
+	"github.com/reddec/token-login/internal/dbo/mysql"
 	"github.com/reddec/token-login/internal/dbo/postgres"
 	"github.com/reddec/token-login/internal/dbo/sqlite"
 )
 
-var errUnsupportedScheme = errors.New("unsupported database scheme")
+var (
+	errUnsupportedScheme = errors.New("unsupported database scheme")
+	errNoReplicas        = errors.New("read replicas are not supported")
+)
 
 // Open opens a database connection based on the URL scheme, runs pending
 // migrations, and returns a Store implementation.
@@ -24,6 +29,8 @@ var errUnsupportedScheme = errors.New("unsupported database scheme")
 // Supported schemes:
 //   - sqlite, sqlite3, file  → SQLite via modernc.org/sqlite
 //   - postgres               → PostgreSQL via pgx/v5
+//   - mysql                  → MySQL or MariaDB via go-sql-driver/mysql
+//   - memory                 → in-process store, see memory.Open (hook is not used)
 func Open(ctx context.Context, rawURL string, hook func(db *sql.DB)) (dbo.Store, error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -48,7 +55,27 @@ func Open(ctx context.Context, rawURL string, hook func(db *sql.DB)) (dbo.Store,
 		return sqlite.Open(ctx, rawURL, hook) //nolint:wrapcheck
 	case "postgres":
 		return postgres.Open(ctx, rawURL, hook) //nolint:wrapcheck
+	case "mysql":
+		return mysql.Open(ctx, rawURL, hook) //nolint:wrapcheck
+	case "memory":
+		return memory.Open(ctx, rawURL) //nolint:wrapcheck
 	default:
 		return nil, fmt.Errorf("unsupported database scheme %s: %w", u.Scheme, errUnsupportedScheme)
 	}
 }
+
+// OpenReplica opens a read replica of the database by URL. Migrations are not run, so the
+// primary must be opened by Open first. Only postgres and mysql schemes are supported.
+func OpenReplica(ctx context.Context, rawURL string, hook func(db *sql.DB)) (dbo.Store, error) {
+	scheme, _, _ := strings.Cut(rawURL, "://")
+	switch scheme {
+	case "postgres":
+		return postgres.OpenReplica(ctx, rawURL) //nolint:wrapcheck
+	case "mysql":
+		return mysql.OpenReplica(ctx, rawURL, hook) //nolint:wrapcheck
+	case "sqlite", "sqlite3", "file", "memory":
+		return nil, fmt.Errorf("scheme %s: %w", scheme, errNoReplicas)
+	default:
+		return nil, fmt.Errorf("unsupported database scheme %s: %w", scheme, errUnsupportedScheme)
+	}
+}
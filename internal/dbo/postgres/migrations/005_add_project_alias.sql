@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS project_alias
+(
+    id         BIGSERIAL   NOT NULL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    project_id BIGINT      NOT NULL REFERENCES project (id) ON DELETE CASCADE,
+    "user"     TEXT        NOT NULL,
+    slug       TEXT        NOT NULL
+);
+
+-- Aliases share the slug namespace of the owner, so the same user can not have
+-- two aliases with the same slug (clashes with live slugs are checked in code).
+CREATE UNIQUE INDEX IF NOT EXISTS project_alias_user_slug ON project_alias ("user", slug);
+CREATE INDEX IF NOT EXISTS project_alias_project ON project_alias (project_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS project_alias_project;
+DROP INDEX IF EXISTS project_alias_user_slug;
+DROP TABLE IF EXISTS project_alias;
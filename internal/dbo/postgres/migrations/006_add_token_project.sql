@@ -0,0 +1,20 @@
+-- +migrate Up
+-- Additional projects a token is valid for, next to its own token.project_id.
+CREATE TABLE IF NOT EXISTS token_project
+(
+    token_id   BIGINT NOT NULL REFERENCES token (id) ON DELETE CASCADE,
+    project_id BIGINT NOT NULL REFERENCES project (id) ON DELETE CASCADE,
+    PRIMARY KEY (token_id, project_id)
+);
+
+CREATE INDEX IF NOT EXISTS token_project_project ON token_project (project_id);
+
+CREATE VIEW token_project_view AS
+SELECT tp.token_id, tp.project_id, p.slug AS project_slug, p."user"
+FROM token_project tp
+JOIN project p ON tp.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_project_view;
+DROP INDEX IF EXISTS token_project_project;
+DROP TABLE IF EXISTS token_project;
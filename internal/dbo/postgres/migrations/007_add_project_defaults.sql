@@ -0,0 +1,29 @@
+-- +migrate Up
+-- Default access rules and headers inherited by tokens of the project.
+ALTER TABLE project ADD COLUMN hosts JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE project ADD COLUMN paths JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE project ADD COLUMN headers JSONB NOT NULL DEFAULT '[]';
+
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t."user", t.label,
+       t.hosts, t.paths, t.headers, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t."user", t.label,
+       t.hosts, t.paths, t.headers, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+ALTER TABLE project DROP COLUMN headers;
+ALTER TABLE project DROP COLUMN paths;
+ALTER TABLE project DROP COLUMN hosts;
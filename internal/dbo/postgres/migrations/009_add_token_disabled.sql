@@ -0,0 +1,29 @@
+-- +migrate Up
+-- Disabled tokens (for example, reported as leaked) are kept but rejected until the key is refreshed.
+ALTER TABLE token ADD COLUMN disabled_at TIMESTAMPTZ NULL;
+ALTER TABLE token ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '';
+
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t."user", t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers,
+       t.disabled_at, t.disabled_reason
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+-- +migrate Down
+DROP VIEW IF EXISTS token_view;
+
+CREATE VIEW token_view AS
+SELECT t.id, t.created_at, t.updated_at, t.key_id, t.hash, t."user", t.label,
+       t.hosts, t.paths, t.headers, t.meta, t.requests, t.last_access_at,
+       t.project_id, p.slug AS project_slug,
+       p.hosts AS project_hosts, p.paths AS project_paths, p.headers AS project_headers
+FROM token t
+JOIN project p ON t.project_id = p.id;
+
+ALTER TABLE token DROP COLUMN disabled_reason;
+ALTER TABLE token DROP COLUMN disabled_at;
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/reddec/token-login/internal/dbo"
+)
+
+// notifyChannel is the channel of token changes. Payload is "<op>:<token id>".
+const notifyChannel = "token_changes"
+
+const (
+	opUpdate = "update"
+	opRemove = "remove"
+)
+
+// notify publishes token changes, which are delivered only if the transaction of q is committed.
+func notify(ctx context.Context, q *Queries, op string, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := q.NotifyTokenChanges(ctx, NotifyTokenChangesParams{Op: op, Ids: ids}); err != nil {
+		return fmt.Errorf("notify token changes: %w", err)
+	}
+	return nil
+}
+
+// notifyProject publishes changes of own and linked tokens of the project.
+func notifyProject(ctx context.Context, q *Queries, projectID int64) error {
+	ids, err := q.ListTokenIDsByProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("list token ids: %w", err)
+	}
+	return notify(ctx, q, opUpdate, ids...)
+}
+
+// Listen subscribes to token changes on a dedicated connection, which is not returned to the pool.
+func (s *store) Listen(ctx context.Context, fn func(dbo.TokenEvent)) error {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	// the connection stays subscribed, so it must not be reused
+	raw := conn.Hijack()
+	defer raw.Close(context.Background())
+
+	if _, err := raw.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	for {
+		n, err := raw.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		event, err := parseEvent(n.Payload)
+		if err != nil {
+			slog.Warn("invalid token change notification", "payload", n.Payload, "error", err)
+			continue
+		}
+		fn(event)
+	}
+}
+
+func parseEvent(payload string) (dbo.TokenEvent, error) {
+	op, rawID, ok := strings.Cut(payload, ":")
+	if !ok || (op != opUpdate && op != opRemove) {
+		return dbo.TokenEvent{}, fmt.Errorf("unknown operation %q", op)
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return dbo.TokenEvent{}, fmt.Errorf("parse token id: %w", err)
+	}
+	return dbo.TokenEvent{ID: id, Removed: op == opRemove}, nil
+}
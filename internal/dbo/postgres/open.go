@@ -47,3 +47,17 @@ func Open(ctx context.Context, rawURL string, hook func(db *sql.DB)) (dbo.Store,
 
 	return NewStore(pool), nil
 }
+
+// OpenReplica opens a read-only PostgreSQL replica without running migrations,
+// which are applied through the primary.
+func OpenReplica(ctx context.Context, rawURL string) (dbo.Store, error) {
+	pool, err := pgxpool.New(ctx, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("open pgxpool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping postgres replica: %w", err)
+	}
+	return NewStore(pool), nil
+}
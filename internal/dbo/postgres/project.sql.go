@@ -7,22 +7,62 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/reddec/token-login/internal/types"
 )
 
+const countRows = `-- name: CountRows :one
+SELECT (SELECT COUNT(*) FROM project)       AS projects,
+       (SELECT COUNT(*) FROM project_alias) AS aliases,
+       (SELECT COUNT(*) FROM token)         AS tokens,
+       (SELECT COUNT(*) FROM token_project) AS token_projects
+`
+
+type CountRowsRow struct {
+	Projects      int64 `json:"projects"`
+	Aliases       int64 `json:"aliases"`
+	Tokens        int64 `json:"tokens"`
+	TokenProjects int64 `json:"token_projects"`
+}
+
+func (q *Queries) CountRows(ctx context.Context) (CountRowsRow, error) {
+	row := q.db.QueryRow(ctx, countRows)
+	var i CountRowsRow
+	err := row.Scan(
+		&i.Projects,
+		&i.Aliases,
+		&i.Tokens,
+		&i.TokenProjects,
+	)
+	return i, err
+}
+
 const createProject = `-- name: CreateProject :one
-INSERT INTO project ("user", slug, description)
-VALUES ($1, $2, $3)
-RETURNING id, created_at, updated_at, "user", slug, description
+INSERT INTO project ("user", slug, description, hosts, paths, headers)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at, updated_at, "user", slug, description, hosts, paths, headers
 `
 
 type CreateProjectParams struct {
-	User        string `json:"user"`
-	Slug        string `json:"slug"`
-	Description string `json:"description"`
+	User        string          `json:"user"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	Hosts       json.RawMessage `json:"hosts"`
+	Paths       json.RawMessage `json:"paths"`
+	Headers     types.Headers   `json:"headers"`
 }
 
 func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
-	row := q.db.QueryRow(ctx, createProject, arg.User, arg.Slug, arg.Description)
+	row := q.db.QueryRow(ctx, createProject,
+		arg.User,
+		arg.Slug,
+		arg.Description,
+		arg.Hosts,
+		arg.Paths,
+		arg.Headers,
+	)
 	var i Project
 	err := row.Scan(
 		&i.ID,
@@ -31,10 +71,29 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		&i.User,
 		&i.Slug,
 		&i.Description,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
 	)
 	return i, err
 }
 
+const createProjectAlias = `-- name: CreateProjectAlias :exec
+INSERT INTO project_alias (project_id, "user", slug)
+VALUES ($1, $2, $3)
+`
+
+type CreateProjectAliasParams struct {
+	ProjectID int64  `json:"project_id"`
+	User      string `json:"user"`
+	Slug      string `json:"slug"`
+}
+
+func (q *Queries) CreateProjectAlias(ctx context.Context, arg CreateProjectAliasParams) error {
+	_, err := q.db.Exec(ctx, createProjectAlias, arg.ProjectID, arg.User, arg.Slug)
+	return err
+}
+
 const deleteProject = `-- name: DeleteProject :execrows
 DELETE FROM project WHERE "user" = $1 AND id = $2
 `
@@ -52,8 +111,26 @@ func (q *Queries) DeleteProject(ctx context.Context, arg DeleteProjectParams) (i
 	return result.RowsAffected(), nil
 }
 
+const deleteProjectAlias = `-- name: DeleteProjectAlias :execrows
+DELETE FROM project_alias WHERE "user" = $1 AND project_id = $2 AND slug = $3
+`
+
+type DeleteProjectAliasParams struct {
+	User      string `json:"user"`
+	ProjectID int64  `json:"project_id"`
+	Slug      string `json:"slug"`
+}
+
+func (q *Queries) DeleteProjectAlias(ctx context.Context, arg DeleteProjectAliasParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProjectAlias, arg.User, arg.ProjectID, arg.Slug)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getProject = `-- name: GetProject :one
-SELECT id, created_at, updated_at, "user", slug, description FROM project WHERE "user" = $1 AND id = $2
+SELECT id, created_at, updated_at, "user", slug, description, hosts, paths, headers FROM project WHERE "user" = $1 AND id = $2
 `
 
 type GetProjectParams struct {
@@ -71,12 +148,45 @@ func (q *Queries) GetProject(ctx context.Context, arg GetProjectParams) (Project
 		&i.User,
 		&i.Slug,
 		&i.Description,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
 	)
 	return i, err
 }
 
+const listAllProjectAliases = `-- name: ListAllProjectAliases :many
+SELECT id, created_at, project_id, "user", slug FROM project_alias ORDER BY id ASC
+`
+
+func (q *Queries) ListAllProjectAliases(ctx context.Context) ([]ProjectAlias, error) {
+	rows, err := q.db.Query(ctx, listAllProjectAliases)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProjectAlias{}
+	for rows.Next() {
+		var i ProjectAlias
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.User,
+			&i.Slug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAllProjects = `-- name: ListAllProjects :many
-SELECT id, created_at, updated_at, "user", slug, description FROM project
+SELECT id, created_at, updated_at, "user", slug, description, hosts, paths, headers FROM project
 `
 
 func (q *Queries) ListAllProjects(ctx context.Context) ([]Project, error) {
@@ -95,6 +205,39 @@ func (q *Queries) ListAllProjects(ctx context.Context) ([]Project, error) {
 			&i.User,
 			&i.Slug,
 			&i.Description,
+			&i.Hosts,
+			&i.Paths,
+			&i.Headers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectAliases = `-- name: ListProjectAliases :many
+SELECT id, created_at, project_id, "user", slug FROM project_alias WHERE "user" = $1 ORDER BY id ASC
+`
+
+func (q *Queries) ListProjectAliases(ctx context.Context, user string) ([]ProjectAlias, error) {
+	rows, err := q.db.Query(ctx, listProjectAliases, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProjectAlias{}
+	for rows.Next() {
+		var i ProjectAlias
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.User,
+			&i.Slug,
 		); err != nil {
 			return nil, err
 		}
@@ -106,8 +249,71 @@ func (q *Queries) ListAllProjects(ctx context.Context) ([]Project, error) {
 	return items, nil
 }
 
+const listProjectAliasesByProject = `-- name: ListProjectAliasesByProject :many
+SELECT id, created_at, project_id, "user", slug FROM project_alias WHERE project_id = $1 ORDER BY id ASC
+`
+
+func (q *Queries) ListProjectAliasesByProject(ctx context.Context, projectID int64) ([]ProjectAlias, error) {
+	rows, err := q.db.Query(ctx, listProjectAliasesByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProjectAlias{}
+	for rows.Next() {
+		var i ProjectAlias
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ProjectID,
+			&i.User,
+			&i.Slug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectTokenIDs = `-- name: ListProjectTokenIDs :many
+SELECT t.id FROM token t JOIN project p ON t.project_id = p.id
+WHERE p."user" = $1 AND p.id = $2
+UNION
+SELECT tp.token_id FROM token_project tp JOIN project p ON tp.project_id = p.id
+WHERE p."user" = $1 AND p.id = $2
+`
+
+type ListProjectTokenIDsParams struct {
+	User string `json:"user"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) ListProjectTokenIDs(ctx context.Context, arg ListProjectTokenIDsParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listProjectTokenIDs, arg.User, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProjects = `-- name: ListProjects :many
-SELECT id, created_at, updated_at, "user", slug, description FROM project WHERE "user" = $1 ORDER BY id ASC
+SELECT id, created_at, updated_at, "user", slug, description, hosts, paths, headers FROM project WHERE "user" = $1 ORDER BY id ASC
 `
 
 func (q *Queries) ListProjects(ctx context.Context, user string) ([]Project, error) {
@@ -126,6 +332,9 @@ func (q *Queries) ListProjects(ctx context.Context, user string) ([]Project, err
 			&i.User,
 			&i.Slug,
 			&i.Description,
+			&i.Hosts,
+			&i.Paths,
+			&i.Headers,
 		); err != nil {
 			return nil, err
 		}
@@ -153,19 +362,135 @@ func (q *Queries) ProjectExists(ctx context.Context, arg ProjectExistsParams) (b
 	return ok, err
 }
 
+const resetSequences = `-- name: ResetSequences :exec
+SELECT setval(pg_get_serial_sequence('project', 'id'), COALESCE((SELECT MAX(id) FROM project), 0) + 1, false),
+       setval(pg_get_serial_sequence('project_alias', 'id'), COALESCE((SELECT MAX(id) FROM project_alias), 0) + 1, false),
+       setval(pg_get_serial_sequence('token', 'id'), COALESCE((SELECT MAX(id) FROM token), 0) + 1, false)
+`
+
+// Restored rows have explicit IDs, so sequences have to be moved past them.
+func (q *Queries) ResetSequences(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, resetSequences)
+	return err
+}
+
+const restoreProject = `-- name: RestoreProject :exec
+INSERT INTO project (id, created_at, updated_at, "user", slug, description, hosts, paths, headers)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, updated_at = excluded.updated_at, "user" = excluded."user", slug = excluded.slug,
+    description = excluded.description, hosts = excluded.hosts, paths = excluded.paths, headers = excluded.headers
+`
+
+type RestoreProjectParams struct {
+	ID          int64           `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	User        string          `json:"user"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	Hosts       json.RawMessage `json:"hosts"`
+	Paths       json.RawMessage `json:"paths"`
+	Headers     types.Headers   `json:"headers"`
+}
+
+// Insert or replace project with its original ID, used to copy databases.
+func (q *Queries) RestoreProject(ctx context.Context, arg RestoreProjectParams) error {
+	_, err := q.db.Exec(ctx, restoreProject,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.User,
+		arg.Slug,
+		arg.Description,
+		arg.Hosts,
+		arg.Paths,
+		arg.Headers,
+	)
+	return err
+}
+
+const restoreProjectAlias = `-- name: RestoreProjectAlias :exec
+INSERT INTO project_alias (id, created_at, project_id, "user", slug)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, project_id = excluded.project_id, "user" = excluded."user", slug = excluded.slug
+`
+
+type RestoreProjectAliasParams struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ProjectID int64     `json:"project_id"`
+	User      string    `json:"user"`
+	Slug      string    `json:"slug"`
+}
+
+func (q *Queries) RestoreProjectAlias(ctx context.Context, arg RestoreProjectAliasParams) error {
+	_, err := q.db.Exec(ctx, restoreProjectAlias,
+		arg.ID,
+		arg.CreatedAt,
+		arg.ProjectID,
+		arg.User,
+		arg.Slug,
+	)
+	return err
+}
+
+const slugInUse = `-- name: SlugInUse :one
+SELECT EXISTS(
+    SELECT 1 FROM project WHERE project."user" = $1 AND project.slug = $2
+    UNION ALL
+    SELECT 1 FROM project_alias WHERE project_alias."user" = $1 AND project_alias.slug = $2
+) AS ok
+`
+
+type SlugInUseParams struct {
+	User string `json:"user"`
+	Slug string `json:"slug"`
+}
+
+func (q *Queries) SlugInUse(ctx context.Context, arg SlugInUseParams) (bool, error) {
+	row := q.db.QueryRow(ctx, slugInUse, arg.User, arg.Slug)
+	var ok bool
+	err := row.Scan(&ok)
+	return ok, err
+}
+
+const touchProject = `-- name: TouchProject :exec
+UPDATE project SET updated_at = now() WHERE id = $1
+`
+
+// Marks project as changed, for example after changes of its aliases.
+func (q *Queries) TouchProject(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, touchProject, id)
+	return err
+}
+
 const updateProject = `-- name: UpdateProject :execrows
-UPDATE project SET description = $1, updated_at = now()
-WHERE "user" = $2 AND id = $3
+UPDATE project SET slug = $1, description = $2, hosts = $3, paths = $4, headers = $5, updated_at = now()
+WHERE "user" = $6 AND id = $7
 `
 
 type UpdateProjectParams struct {
-	Description string `json:"description"`
-	User        string `json:"user"`
-	ID          int64  `json:"id"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	Hosts       json.RawMessage `json:"hosts"`
+	Paths       json.RawMessage `json:"paths"`
+	Headers     types.Headers   `json:"headers"`
+	User        string          `json:"user"`
+	ID          int64           `json:"id"`
 }
 
 func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (int64, error) {
-	result, err := q.db.Exec(ctx, updateProject, arg.Description, arg.User, arg.ID)
+	result, err := q.db.Exec(ctx, updateProject,
+		arg.Slug,
+		arg.Description,
+		arg.Hosts,
+		arg.Paths,
+		arg.Headers,
+		arg.User,
+		arg.ID,
+	)
 	if err != nil {
 		return 0, err
 	}
@@ -8,13 +8,13 @@ SELECT * FROM project WHERE "user" = $1 ORDER BY id ASC;
 SELECT * FROM project;
 
 -- name: CreateProject :one
-INSERT INTO project ("user", slug, description)
-VALUES ($1, $2, $3)
+INSERT INTO project ("user", slug, description, hosts, paths, headers)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: UpdateProject :execrows
-UPDATE project SET description = $1, updated_at = now()
-WHERE "user" = $2 AND id = $3;
+UPDATE project SET slug = $1, description = $2, hosts = $3, paths = $4, headers = $5, updated_at = now()
+WHERE "user" = $6 AND id = $7;
 
 -- name: DeleteProject :execrows
 DELETE FROM project WHERE "user" = $1 AND id = $2;
@@ -22,3 +22,63 @@ DELETE FROM project WHERE "user" = $1 AND id = $2;
 -- name: ProjectExists :one
 SELECT EXISTS(SELECT 1 FROM project WHERE "user" = $1 AND id = $2) AS ok;
 
+
+-- name: SlugInUse :one
+SELECT EXISTS(
+    SELECT 1 FROM project WHERE project."user" = sqlc.arg('user') AND project.slug = sqlc.arg('slug')
+    UNION ALL
+    SELECT 1 FROM project_alias WHERE project_alias."user" = sqlc.arg('user') AND project_alias.slug = sqlc.arg('slug')
+) AS ok;
+
+-- name: ListProjectTokenIDs :many
+SELECT t.id FROM token t JOIN project p ON t.project_id = p.id
+WHERE p."user" = $1 AND p.id = $2
+UNION
+SELECT tp.token_id FROM token_project tp JOIN project p ON tp.project_id = p.id
+WHERE p."user" = $1 AND p.id = $2;
+
+-- name: ListProjectAliases :many
+SELECT * FROM project_alias WHERE "user" = $1 ORDER BY id ASC;
+
+-- name: ListProjectAliasesByProject :many
+SELECT * FROM project_alias WHERE project_id = $1 ORDER BY id ASC;
+
+-- name: ListAllProjectAliases :many
+SELECT * FROM project_alias ORDER BY id ASC;
+
+-- name: CreateProjectAlias :exec
+INSERT INTO project_alias (project_id, "user", slug)
+VALUES ($1, $2, $3);
+
+-- name: TouchProject :exec
+-- Marks project as changed, for example after changes of its aliases.
+UPDATE project SET updated_at = now() WHERE id = $1;
+
+-- name: DeleteProjectAlias :execrows
+DELETE FROM project_alias WHERE "user" = $1 AND project_id = $2 AND slug = $3;
+
+-- name: RestoreProject :exec
+-- Insert or replace project with its original ID, used to copy databases.
+INSERT INTO project (id, created_at, updated_at, "user", slug, description, hosts, paths, headers)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, updated_at = excluded.updated_at, "user" = excluded."user", slug = excluded.slug,
+    description = excluded.description, hosts = excluded.hosts, paths = excluded.paths, headers = excluded.headers;
+
+-- name: RestoreProjectAlias :exec
+INSERT INTO project_alias (id, created_at, project_id, "user", slug)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, project_id = excluded.project_id, "user" = excluded."user", slug = excluded.slug;
+
+-- name: CountRows :one
+SELECT (SELECT COUNT(*) FROM project)       AS projects,
+       (SELECT COUNT(*) FROM project_alias) AS aliases,
+       (SELECT COUNT(*) FROM token)         AS tokens,
+       (SELECT COUNT(*) FROM token_project) AS token_projects;
+
+-- name: ResetSequences :exec
+-- Restored rows have explicit IDs, so sequences have to be moved past them.
+SELECT setval(pg_get_serial_sequence('project', 'id'), COALESCE((SELECT MAX(id) FROM project), 0) + 1, false),
+       setval(pg_get_serial_sequence('project_alias', 'id'), COALESCE((SELECT MAX(id) FROM project_alias), 0) + 1, false),
+       setval(pg_get_serial_sequence('token', 'id'), COALESCE((SELECT MAX(id) FROM token), 0) + 1, false);
@@ -4,29 +4,92 @@ SELECT * FROM token_view WHERE "user" = $1 AND id = $2;
 -- name: GetTokenByID :one
 SELECT * FROM token_view WHERE id = $1;
 
--- name: ListTokens :many
-SELECT * FROM token_view WHERE "user" = $1 ORDER BY id DESC;
+-- name: GetTokenByKeyID :one
+SELECT * FROM token_view WHERE "user" = $1 AND key_id = $2;
+
+-- name: FindTokenByKeyID :one
+SELECT * FROM token_view WHERE key_id = $1;
 
--- name: ListTokensByUserAndProject :many
-SELECT * FROM token_view WHERE "user" = $1 AND project_id = $2 ORDER BY id DESC;
+-- name: ListTokens :many
+-- Empty/zero arguments disable the corresponding filter.
+-- meta is a JSON object, all pairs of which must be present in token meta.
+-- Keyset pagination: rows strictly after the cursor (after_id and the sort value) in the chosen order.
+-- Time is compared with one second precision; ties are resolved by id.
+SELECT token_view.*
+FROM token_view,
+     (SELECT sqlc.arg(sort)::text AS sort) opts
+WHERE token_view."user" = sqlc.arg('user')
+  AND (sqlc.arg(project_id)::bigint = 0
+    OR token_view.project_id = sqlc.arg(project_id)::bigint
+    OR token_view.id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id)::bigint))
+  AND token_view.meta @> sqlc.arg(meta)::jsonb
+  AND (sqlc.arg(search)::text = ''
+    OR strpos(lower(token_view.label), lower(sqlc.arg(search)::text)) > 0
+    OR strpos(upper(token_view.key_id), upper(sqlc.arg(search)::text)) = 1)
+  AND (sqlc.arg(host)::text = '' OR strpos(lower(token_view.hosts::text), lower(sqlc.arg(host)::text)) > 0)
+  AND (sqlc.arg(accessed_after)::bigint = 0
+    OR (token_view.requests > 0 AND extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint >= sqlc.arg(accessed_after)::bigint))
+  AND (sqlc.arg(accessed_before)::bigint = 0
+    OR (token_view.requests > 0 AND extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint < sqlc.arg(accessed_before)::bigint))
+  AND (sqlc.arg(unused_since)::bigint = 0
+    OR token_view.requests = 0 OR extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint < sqlc.arg(unused_since)::bigint)
+  AND (sqlc.arg(state)::text = ''
+    OR (sqlc.arg(state)::text = 'disabled') = (token_view.disabled_at IS NOT NULL))
+  AND (sqlc.arg(after_id)::bigint = 0 OR CASE opts.sort
+    WHEN 'created_asc' THEN token_view.id > sqlc.arg(after_id)::bigint
+    WHEN 'label_asc' THEN lower(token_view.label) > lower(sqlc.arg(after_label)::text)
+        OR (lower(token_view.label) = lower(sqlc.arg(after_label)::text) AND token_view.id > sqlc.arg(after_id)::bigint)
+    WHEN 'label_desc' THEN lower(token_view.label) < lower(sqlc.arg(after_label)::text)
+        OR (lower(token_view.label) = lower(sqlc.arg(after_label)::text) AND token_view.id < sqlc.arg(after_id)::bigint)
+    WHEN 'last_access_asc' THEN extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint > sqlc.arg(after_value)::bigint
+        OR (extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint = sqlc.arg(after_value)::bigint AND token_view.id > sqlc.arg(after_id)::bigint)
+    WHEN 'last_access_desc' THEN extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint < sqlc.arg(after_value)::bigint
+        OR (extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint = sqlc.arg(after_value)::bigint AND token_view.id < sqlc.arg(after_id)::bigint)
+    WHEN 'requests_asc' THEN token_view.requests > sqlc.arg(after_value)::bigint
+        OR (token_view.requests = sqlc.arg(after_value)::bigint AND token_view.id > sqlc.arg(after_id)::bigint)
+    WHEN 'requests_desc' THEN token_view.requests < sqlc.arg(after_value)::bigint
+        OR (token_view.requests = sqlc.arg(after_value)::bigint AND token_view.id < sqlc.arg(after_id)::bigint)
+    ELSE token_view.id < sqlc.arg(after_id)::bigint
+    END)
+ORDER BY CASE WHEN opts.sort = 'label_asc' THEN lower(token_view.label) END ASC,
+         CASE WHEN opts.sort = 'label_desc' THEN lower(token_view.label) END DESC,
+         CASE WHEN opts.sort = 'last_access_asc' THEN extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint END ASC,
+         CASE WHEN opts.sort = 'last_access_desc' THEN extract(epoch FROM date_trunc('second', token_view.last_access_at))::bigint END DESC,
+         CASE WHEN opts.sort = 'requests_asc' THEN token_view.requests END ASC,
+         CASE WHEN opts.sort = 'requests_desc' THEN token_view.requests END DESC,
+         CASE WHEN opts.sort IN ('created_asc', 'label_asc', 'last_access_asc', 'requests_asc') THEN token_view.id END ASC,
+         token_view.id DESC
+LIMIT sqlc.narg(max_rows)::bigint;
 
 -- name: ListAllTokens :many
 SELECT * FROM token_view;
 
 -- name: CreateToken :one
-INSERT INTO token (key_id, hash, "user", label, paths, hosts, headers, project_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO token (key_id, hash, hash_alg, pepper_id, "user", label, paths, hosts, headers, meta, project_id, key_format, key_prefix)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 RETURNING id;
 
 -- name: UpdateToken :execrows
 UPDATE token
-SET hosts = $1, paths = $2, label = $3, headers = $4, updated_at = now()
-WHERE "user" = $5 AND id = $6;
+SET hosts = $1, paths = $2, label = $3, headers = $4, meta = $5, project_id = $6, updated_at = now(), changed_at = now()
+WHERE "user" = $7 AND id = $8;
 
 -- name: RefreshToken :execrows
 UPDATE token
-SET hash = $1, key_id = $2, updated_at = now()
-WHERE "user" = $3 AND id = $4;
+SET hash = $1, hash_alg = $2, pepper_id = $3, key_id = $4, key_format = 'native', key_prefix = '', disabled_at = NULL, disabled_reason = '', updated_at = now(),
+    changed_at = now()
+WHERE "user" = $5 AND id = $6;
+
+-- name: RehashToken :execrows
+-- Compare-and-swap: the token is left as is if its hash was changed concurrently.
+UPDATE token
+SET hash = sqlc.arg(new_hash), hash_alg = sqlc.arg(hash_alg), pepper_id = sqlc.arg(pepper_id), changed_at = now()
+WHERE id = sqlc.arg(id) AND hash = sqlc.arg(old_hash);
+
+-- name: DisableToken :execrows
+UPDATE token
+SET disabled_at = $1, disabled_reason = $2, updated_at = now(), changed_at = now()
+WHERE id = $3 AND disabled_at IS NULL;
 
 -- name: DeleteToken :execrows
 DELETE FROM token WHERE "user" = $1 AND id = $2;
@@ -36,6 +99,75 @@ UPDATE token
 SET requests = requests + sqlc.arg(requests), last_access_at = sqlc.arg(last_access_at), updated_at = now()
 WHERE id = sqlc.arg(id);
 
+-- name: ListChangedTokens :many
+-- Tokens changed since the time, directly or by changes of their own or linked projects.
+SELECT token_view.*
+FROM token_view
+WHERE token_view.id IN (SELECT t.id FROM token t WHERE t.changed_at >= sqlc.arg(since))
+   OR token_view.project_id IN (SELECT p.id FROM project p WHERE p.updated_at >= sqlc.arg(since))
+   OR token_view.id IN (SELECT tp.token_id
+                        FROM token_project tp
+                                 JOIN project p ON p.id = tp.project_id
+                        WHERE p.updated_at >= sqlc.arg(since));
+
+-- name: ListTokenTombstones :many
+SELECT DISTINCT token_id FROM token_tombstone WHERE deleted_at >= sqlc.arg(since);
+
+-- name: CreateTokenTombstone :exec
+INSERT INTO token_tombstone (token_id) VALUES ($1);
+
+-- name: CreateProjectTokenTombstones :many
+-- Tombstones of own tokens of the project, which are deleted with it.
+INSERT INTO token_tombstone (token_id) SELECT t.id FROM token t WHERE t.project_id = sqlc.arg(project_id)
+RETURNING token_id;
+
+-- name: PurgeTokenTombstones :exec
+DELETE FROM token_tombstone WHERE deleted_at < sqlc.arg(before);
+
+-- name: TouchLinkedTokens :many
+-- Marks tokens linked to the project as changed, since they lose the link when the project is deleted.
+UPDATE token SET changed_at = now()
+WHERE id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id))
+RETURNING id;
+
+-- name: NotifyTokenChanges :exec
+-- Publishes changes of the tokens to listeners when the transaction is committed.
+SELECT pg_notify('token_changes', sqlc.arg(op)::text || ':' || changed.id)
+FROM unnest(sqlc.arg(ids)::bigint[]) AS changed(id);
+
 -- name: ListTokenIDsByProject :many
-SELECT id FROM token WHERE project_id = $1;
+SELECT token.id FROM token WHERE token.project_id = sqlc.arg(project_id)
+UNION
+SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id);
+
+-- name: ListTokenProjects :many
+SELECT * FROM token_project_view WHERE token_id = $1;
+
+-- name: ListTokenProjectsByUser :many
+SELECT * FROM token_project_view WHERE "user" = $1;
+
+-- name: ListAllTokenProjects :many
+SELECT * FROM token_project_view;
+
+-- name: AddTokenProject :exec
+INSERT INTO token_project (token_id, project_id) VALUES ($1, $2);
+
+-- name: ClearTokenProjects :exec
+DELETE FROM token_project WHERE token_id = $1;
+
+-- name: RemoveTokenProject :exec
+DELETE FROM token_project WHERE token_id = $1 AND project_id = $2;
+
 
+-- name: RestoreToken :exec
+-- Insert or replace token with its original ID, hash and stats, used to copy databases.
+INSERT INTO token (id, created_at, updated_at, key_id, hash, hash_alg, pepper_id, "user", label, hosts, paths, headers, meta,
+                   requests, last_access_at, project_id, disabled_at, disabled_reason, key_format, key_prefix)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, updated_at = excluded.updated_at, key_id = excluded.key_id, hash = excluded.hash,
+    hash_alg = excluded.hash_alg, pepper_id = excluded.pepper_id, "user" = excluded."user", label = excluded.label,
+    hosts = excluded.hosts, paths = excluded.paths, headers = excluded.headers, meta = excluded.meta,
+    requests = excluded.requests, last_access_at = excluded.last_access_at, project_id = excluded.project_id,
+    disabled_at = excluded.disabled_at, disabled_reason = excluded.disabled_reason,
+    key_format = excluded.key_format, key_prefix = excluded.key_prefix, changed_at = now();
@@ -0,0 +1,21 @@
+-- +migrate Up
+-- Time of the last change of token config, key or state, used to sync caches incrementally.
+-- Unlike updated_at it's not changed by usage stats. SQLite can not add a column with
+-- non-constant default, so the time is set by queries.
+ALTER TABLE token ADD COLUMN changed_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+CREATE INDEX IF NOT EXISTS token_changed_at ON token (changed_at);
+
+-- Deleted tokens, kept for a while so caches can drop them without full reload.
+CREATE TABLE IF NOT EXISTS token_tombstone
+(
+    id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    token_id   INTEGER  NOT NULL,
+    deleted_at DATETIME NOT NULL DEFAULT current_timestamp
+);
+CREATE INDEX IF NOT EXISTS token_tombstone_deleted_at ON token_tombstone (deleted_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS token_tombstone_deleted_at;
+DROP TABLE IF EXISTS token_tombstone;
+DROP INDEX IF EXISTS token_changed_at;
+ALTER TABLE token DROP COLUMN changed_at;
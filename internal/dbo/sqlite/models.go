@@ -11,43 +11,90 @@ import (
 )
 
 type Project struct {
-	ID          int64     `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	User        string    `json:"user"`
-	Slug        string    `json:"slug"`
-	Description string    `json:"description"`
+	ID          int64         `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	User        string        `json:"user"`
+	Slug        string        `json:"slug"`
+	Description string        `json:"description"`
+	Hosts       string        `json:"hosts"`
+	Paths       string        `json:"paths"`
+	Headers     types.Headers `json:"headers"`
+}
+
+type ProjectAlias struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ProjectID int64     `json:"project_id"`
+	User      string    `json:"user"`
+	Slug      string    `json:"slug"`
 }
 
 type Token struct {
-	ID           int64         `json:"id"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	KeyID        types.KeyID   `json:"key_id"`
-	Hash         []byte        `json:"hash"`
-	User         string        `json:"user"`
-	Label        string        `json:"label"`
-	Headers      types.Headers `json:"headers"`
-	Requests     int64         `json:"requests"`
-	LastAccessAt time.Time     `json:"last_access_at"`
-	ProjectID    int64         `json:"project_id"`
-	Hosts        string        `json:"hosts"`
-	Paths        string        `json:"paths"`
+	ID             int64         `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	KeyID          types.KeyID   `json:"key_id"`
+	Hash           []byte        `json:"hash"`
+	User           string        `json:"user"`
+	Label          string        `json:"label"`
+	Headers        types.Headers `json:"headers"`
+	Requests       int64         `json:"requests"`
+	LastAccessAt   time.Time     `json:"last_access_at"`
+	ProjectID      int64         `json:"project_id"`
+	Hosts          string        `json:"hosts"`
+	Paths          string        `json:"paths"`
+	Meta           types.Meta    `json:"meta"`
+	DisabledAt     *time.Time    `json:"disabled_at"`
+	DisabledReason string        `json:"disabled_reason"`
+	HashAlg        string        `json:"hash_alg"`
+	PepperID       string        `json:"pepper_id"`
+	KeyFormat      string        `json:"key_format"`
+	KeyPrefix      string        `json:"key_prefix"`
+	ChangedAt      time.Time     `json:"changed_at"`
+}
+
+type TokenProject struct {
+	TokenID   int64 `json:"token_id"`
+	ProjectID int64 `json:"project_id"`
+}
+
+type TokenProjectView struct {
+	TokenID     int64  `json:"token_id"`
+	ProjectID   int64  `json:"project_id"`
+	ProjectSlug string `json:"project_slug"`
+	User        string `json:"user"`
+}
+
+type TokenTombstone struct {
+	ID        int64     `json:"id"`
+	TokenID   int64     `json:"token_id"`
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
 type TokenView struct {
-	ID           int64         `json:"id"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	KeyID        types.KeyID   `json:"key_id"`
-	Hash         []byte        `json:"hash"`
-	User         string        `json:"user"`
-	Label        string        `json:"label"`
-	Hosts        string        `json:"hosts"`
-	Paths        string        `json:"paths"`
-	Headers      types.Headers `json:"headers"`
-	Requests     int64         `json:"requests"`
-	LastAccessAt time.Time     `json:"last_access_at"`
-	ProjectID    int64         `json:"project_id"`
-	ProjectSlug  string        `json:"project_slug"`
+	ID             int64         `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	KeyID          types.KeyID   `json:"key_id"`
+	Hash           []byte        `json:"hash"`
+	User           string        `json:"user"`
+	Label          string        `json:"label"`
+	Hosts          string        `json:"hosts"`
+	Paths          string        `json:"paths"`
+	Headers        types.Headers `json:"headers"`
+	Meta           types.Meta    `json:"meta"`
+	Requests       int64         `json:"requests"`
+	LastAccessAt   time.Time     `json:"last_access_at"`
+	ProjectID      int64         `json:"project_id"`
+	ProjectSlug    string        `json:"project_slug"`
+	ProjectHosts   string        `json:"project_hosts"`
+	ProjectPaths   string        `json:"project_paths"`
+	ProjectHeaders types.Headers `json:"project_headers"`
+	DisabledAt     *time.Time    `json:"disabled_at"`
+	DisabledReason string        `json:"disabled_reason"`
+	HashAlg        string        `json:"hash_alg"`
+	PepperID       string        `json:"pepper_id"`
+	KeyFormat      string        `json:"key_format"`
+	KeyPrefix      string        `json:"key_prefix"`
 }
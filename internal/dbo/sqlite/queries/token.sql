@@ -4,30 +4,94 @@ SELECT * FROM token_view WHERE user = ? AND id = ?;
 -- name: GetTokenByID :one
 SELECT * FROM token_view WHERE id = ?;
 
--- name: ListTokens :many
-SELECT * FROM token_view WHERE user = ? ORDER BY id DESC;
+-- name: GetTokenByKeyID :one
+SELECT * FROM token_view WHERE user = ? AND key_id = ?;
+
+-- name: FindTokenByKeyID :one
+SELECT * FROM token_view WHERE key_id = ?;
 
--- name: ListTokensByUserAndProject :many
-SELECT * FROM token_view WHERE user = ? AND project_id = ? ORDER BY id DESC;
+-- name: ListTokens :many
+-- Empty/zero arguments disable the corresponding filter.
+-- meta is a JSON object, all pairs of which must be present in token meta.
+-- Keyset pagination: rows strictly after the cursor (after_id and the sort value) in the chosen order.
+-- Time is compared with one second precision; ties are resolved by id.
+SELECT token_view.*
+FROM token_view,
+     (SELECT CAST(sqlc.arg(sort) AS TEXT) AS sort) opts
+WHERE token_view.user = sqlc.arg(user)
+  AND (CAST(sqlc.arg(project_id) AS INTEGER) = 0
+    OR token_view.project_id = sqlc.arg(project_id)
+    OR token_view.id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id)))
+  AND NOT EXISTS (SELECT 1 FROM json_each(CAST(sqlc.arg(meta) AS TEXT)) f
+                  WHERE json_extract(token_view.meta, '$."' || f.key || '"') IS NOT f.value)
+  AND (CAST(sqlc.arg(search) AS TEXT) = ''
+    OR instr(lower(token_view.label), lower(sqlc.arg(search))) > 0
+    OR instr(upper(token_view.key_id), upper(sqlc.arg(search))) = 1)
+  AND (CAST(sqlc.arg(host) AS TEXT) = '' OR instr(lower(token_view.hosts), lower(sqlc.arg(host))) > 0)
+  AND (CAST(sqlc.arg(accessed_after) AS INTEGER) = 0
+    OR (token_view.requests > 0 AND CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) >= sqlc.arg(accessed_after)))
+  AND (CAST(sqlc.arg(accessed_before) AS INTEGER) = 0
+    OR (token_view.requests > 0 AND CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) < sqlc.arg(accessed_before)))
+  AND (CAST(sqlc.arg(unused_since) AS INTEGER) = 0
+    OR token_view.requests = 0 OR CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) < sqlc.arg(unused_since))
+  AND (CAST(sqlc.arg(state) AS TEXT) = ''
+    OR (sqlc.arg(state) = 'disabled') = (token_view.disabled_at IS NOT NULL))
+  AND (CAST(sqlc.arg(after_id) AS INTEGER) = 0 OR CASE opts.sort
+    WHEN 'created_asc' THEN token_view.id > sqlc.arg(after_id)
+    WHEN 'label_asc' THEN lower(token_view.label) > lower(sqlc.arg(after_label))
+        OR (lower(token_view.label) = lower(sqlc.arg(after_label)) AND token_view.id > sqlc.arg(after_id))
+    WHEN 'label_desc' THEN lower(token_view.label) < lower(sqlc.arg(after_label))
+        OR (lower(token_view.label) = lower(sqlc.arg(after_label)) AND token_view.id < sqlc.arg(after_id))
+    WHEN 'last_access_asc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) > CAST(sqlc.arg(after_value) AS INTEGER)
+        OR (CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) = sqlc.arg(after_value) AND token_view.id > sqlc.arg(after_id))
+    WHEN 'last_access_desc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) < sqlc.arg(after_value)
+        OR (CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) = sqlc.arg(after_value) AND token_view.id < sqlc.arg(after_id))
+    WHEN 'requests_asc' THEN token_view.requests > sqlc.arg(after_value)
+        OR (token_view.requests = sqlc.arg(after_value) AND token_view.id > sqlc.arg(after_id))
+    WHEN 'requests_desc' THEN token_view.requests < sqlc.arg(after_value)
+        OR (token_view.requests = sqlc.arg(after_value) AND token_view.id < sqlc.arg(after_id))
+    ELSE token_view.id < sqlc.arg(after_id)
+    END)
+ORDER BY CASE WHEN opts.sort = 'label_asc' THEN lower(token_view.label) END ASC,
+         CASE WHEN opts.sort = 'label_desc' THEN lower(token_view.label) END DESC,
+         CASE WHEN opts.sort = 'last_access_asc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) END ASC,
+         CASE WHEN opts.sort = 'last_access_desc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) END DESC,
+         CASE WHEN opts.sort = 'requests_asc' THEN token_view.requests END ASC,
+         CASE WHEN opts.sort = 'requests_desc' THEN token_view.requests END DESC,
+         CASE WHEN opts.sort IN ('created_asc', 'label_asc', 'last_access_asc', 'requests_asc') THEN token_view.id END ASC,
+         token_view.id DESC
+LIMIT sqlc.arg(max_rows);
 
 -- name: ListAllTokens :many
 SELECT * FROM token_view;
 
 -- name: CreateToken :one
-INSERT INTO token (key_id, hash, user, label, paths, hosts, headers, project_id)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO token (key_id, hash, hash_alg, pepper_id, user, label, paths, hosts, headers, meta, project_id, key_format, key_prefix, changed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, current_timestamp)
 RETURNING id;
 
 -- name: UpdateToken :execrows
 UPDATE token
-SET hosts = ?, paths = ?, label = ?, headers = ?, updated_at = current_timestamp
+SET hosts = ?, paths = ?, label = ?, headers = ?, meta = ?, project_id = ?, updated_at = current_timestamp, changed_at = current_timestamp
 WHERE user = ? AND id = ?;
 
 -- name: RefreshToken :execrows
 UPDATE token
-SET hash = ?, key_id = ?, updated_at = current_timestamp
+SET hash = ?, hash_alg = ?, pepper_id = ?, key_id = ?, key_format = 'native', key_prefix = '', disabled_at = NULL, disabled_reason = '', updated_at = current_timestamp,
+    changed_at = current_timestamp
 WHERE user = ? AND id = ?;
 
+-- name: RehashToken :execrows
+-- Compare-and-swap: the token is left as is if its hash was changed concurrently.
+UPDATE token
+SET hash = sqlc.arg(new_hash), hash_alg = sqlc.arg(hash_alg), pepper_id = sqlc.arg(pepper_id), changed_at = current_timestamp
+WHERE id = sqlc.arg(id) AND hash = sqlc.arg(old_hash);
+
+-- name: DisableToken :execrows
+UPDATE token
+SET disabled_at = ?, disabled_reason = ?, updated_at = current_timestamp, changed_at = current_timestamp
+WHERE id = ? AND disabled_at IS NULL;
+
 -- name: DeleteToken :execrows
 DELETE FROM token WHERE user = ? AND id = ?;
 
@@ -36,6 +100,69 @@ UPDATE token
 SET requests = requests + sqlc.arg(requests), last_access_at = sqlc.arg(last_access_at), updated_at = current_timestamp
 WHERE id = sqlc.arg(id);
 
+-- name: ListChangedTokens :many
+-- Tokens changed since the time, directly or by changes of their own or linked projects.
+-- Time is compared with one second precision.
+SELECT token_view.*
+FROM token_view
+WHERE token_view.id IN (SELECT t.id FROM token t WHERE substr(t.changed_at, 1, 19) >= CAST(sqlc.arg(since) AS TEXT))
+   OR token_view.project_id IN (SELECT p.id FROM project p WHERE substr(p.updated_at, 1, 19) >= sqlc.arg(since))
+   OR token_view.id IN (SELECT tp.token_id
+                        FROM token_project tp
+                                 JOIN project p ON p.id = tp.project_id
+                        WHERE substr(p.updated_at, 1, 19) >= sqlc.arg(since));
+
+-- name: ListTokenTombstones :many
+SELECT DISTINCT token_id FROM token_tombstone WHERE substr(deleted_at, 1, 19) >= CAST(sqlc.arg(since) AS TEXT);
+
+-- name: CreateTokenTombstone :exec
+INSERT INTO token_tombstone (token_id) VALUES (?);
+
+-- name: CreateProjectTokenTombstones :exec
+-- Tombstones of own tokens of the project, which are deleted with it.
+INSERT INTO token_tombstone (token_id) SELECT t.id FROM token t WHERE t.project_id = sqlc.arg(project_id);
+
+-- name: PurgeTokenTombstones :exec
+DELETE FROM token_tombstone WHERE substr(deleted_at, 1, 19) < CAST(sqlc.arg(before) AS TEXT);
+
+-- name: TouchLinkedTokens :exec
+-- Marks tokens linked to the project as changed, since they lose the link when the project is deleted.
+UPDATE token SET changed_at = current_timestamp
+WHERE id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id));
+
 -- name: ListTokenIDsByProject :many
-SELECT id FROM token WHERE project_id = ?;
+SELECT token.id FROM token WHERE token.project_id = sqlc.arg(project_id)
+UNION
+SELECT tp.token_id FROM token_project tp WHERE tp.project_id = sqlc.arg(project_id);
+
+-- name: ListTokenProjects :many
+SELECT * FROM token_project_view WHERE token_id = ?;
+
+-- name: ListTokenProjectsByUser :many
+SELECT * FROM token_project_view WHERE user = ?;
+
+-- name: ListAllTokenProjects :many
+SELECT * FROM token_project_view;
+
+-- name: AddTokenProject :exec
+INSERT INTO token_project (token_id, project_id) VALUES (?, ?);
+
+-- name: ClearTokenProjects :exec
+DELETE FROM token_project WHERE token_id = ?;
+
+-- name: RemoveTokenProject :exec
+DELETE FROM token_project WHERE token_id = ? AND project_id = ?;
+
 
+-- name: RestoreToken :exec
+-- Insert or replace token with its original ID, hash and stats, used to copy databases.
+INSERT INTO token (id, created_at, updated_at, key_id, hash, hash_alg, pepper_id, user, label, hosts, paths, headers, meta,
+                   requests, last_access_at, project_id, disabled_at, disabled_reason, key_format, key_prefix, changed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, current_timestamp)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, updated_at = excluded.updated_at, key_id = excluded.key_id, hash = excluded.hash,
+    hash_alg = excluded.hash_alg, pepper_id = excluded.pepper_id, user = excluded.user, label = excluded.label,
+    hosts = excluded.hosts, paths = excluded.paths, headers = excluded.headers, meta = excluded.meta,
+    requests = excluded.requests, last_access_at = excluded.last_access_at, project_id = excluded.project_id,
+    disabled_at = excluded.disabled_at, disabled_reason = excluded.disabled_reason,
+    key_format = excluded.key_format, key_prefix = excluded.key_prefix, changed_at = excluded.changed_at;
@@ -12,39 +12,91 @@ import (
 	"github.com/reddec/token-login/internal/types"
 )
 
+const addTokenProject = `-- name: AddTokenProject :exec
+INSERT INTO token_project (token_id, project_id) VALUES (?, ?)
+`
+
+type AddTokenProjectParams struct {
+	TokenID   int64 `json:"token_id"`
+	ProjectID int64 `json:"project_id"`
+}
+
+func (q *Queries) AddTokenProject(ctx context.Context, arg AddTokenProjectParams) error {
+	_, err := q.db.ExecContext(ctx, addTokenProject, arg.TokenID, arg.ProjectID)
+	return err
+}
+
+const clearTokenProjects = `-- name: ClearTokenProjects :exec
+DELETE FROM token_project WHERE token_id = ?
+`
+
+func (q *Queries) ClearTokenProjects(ctx context.Context, tokenID int64) error {
+	_, err := q.db.ExecContext(ctx, clearTokenProjects, tokenID)
+	return err
+}
+
+const createProjectTokenTombstones = `-- name: CreateProjectTokenTombstones :exec
+INSERT INTO token_tombstone (token_id) SELECT t.id FROM token t WHERE t.project_id = ?1
+`
+
+// Tombstones of own tokens of the project, which are deleted with it.
+func (q *Queries) CreateProjectTokenTombstones(ctx context.Context, projectID int64) error {
+	_, err := q.db.ExecContext(ctx, createProjectTokenTombstones, projectID)
+	return err
+}
+
 const createToken = `-- name: CreateToken :one
-INSERT INTO token (key_id, hash, user, label, paths, hosts, headers, project_id)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO token (key_id, hash, hash_alg, pepper_id, user, label, paths, hosts, headers, meta, project_id, key_format, key_prefix, changed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, current_timestamp)
 RETURNING id
 `
 
 type CreateTokenParams struct {
 	KeyID     types.KeyID   `json:"key_id"`
 	Hash      []byte        `json:"hash"`
+	HashAlg   string        `json:"hash_alg"`
+	PepperID  string        `json:"pepper_id"`
 	User      string        `json:"user"`
 	Label     string        `json:"label"`
 	Paths     string        `json:"paths"`
 	Hosts     string        `json:"hosts"`
 	Headers   types.Headers `json:"headers"`
+	Meta      types.Meta    `json:"meta"`
 	ProjectID int64         `json:"project_id"`
+	KeyFormat string        `json:"key_format"`
+	KeyPrefix string        `json:"key_prefix"`
 }
 
 func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (int64, error) {
 	row := q.db.QueryRowContext(ctx, createToken,
 		arg.KeyID,
 		arg.Hash,
+		arg.HashAlg,
+		arg.PepperID,
 		arg.User,
 		arg.Label,
 		arg.Paths,
 		arg.Hosts,
 		arg.Headers,
+		arg.Meta,
 		arg.ProjectID,
+		arg.KeyFormat,
+		arg.KeyPrefix,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const createTokenTombstone = `-- name: CreateTokenTombstone :exec
+INSERT INTO token_tombstone (token_id) VALUES (?)
+`
+
+func (q *Queries) CreateTokenTombstone(ctx context.Context, tokenID int64) error {
+	_, err := q.db.ExecContext(ctx, createTokenTombstone, tokenID)
+	return err
+}
+
 const deleteToken = `-- name: DeleteToken :execrows
 DELETE FROM token WHERE user = ? AND id = ?
 `
@@ -62,8 +114,64 @@ func (q *Queries) DeleteToken(ctx context.Context, arg DeleteTokenParams) (int64
 	return result.RowsAffected()
 }
 
+const disableToken = `-- name: DisableToken :execrows
+UPDATE token
+SET disabled_at = ?, disabled_reason = ?, updated_at = current_timestamp, changed_at = current_timestamp
+WHERE id = ? AND disabled_at IS NULL
+`
+
+type DisableTokenParams struct {
+	DisabledAt     *time.Time `json:"disabled_at"`
+	DisabledReason string     `json:"disabled_reason"`
+	ID             int64      `json:"id"`
+}
+
+func (q *Queries) DisableToken(ctx context.Context, arg DisableTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, disableToken, arg.DisabledAt, arg.DisabledReason, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const findTokenByKeyID = `-- name: FindTokenByKeyID :one
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE key_id = ?
+`
+
+func (q *Queries) FindTokenByKeyID(ctx context.Context, keyID types.KeyID) (TokenView, error) {
+	row := q.db.QueryRowContext(ctx, findTokenByKeyID, keyID)
+	var i TokenView
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.KeyID,
+		&i.Hash,
+		&i.User,
+		&i.Label,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
+		&i.Meta,
+		&i.Requests,
+		&i.LastAccessAt,
+		&i.ProjectID,
+		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
+	)
+	return i, err
+}
+
 const getToken = `-- name: GetToken :one
-SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, requests, last_access_at, project_id, project_slug FROM token_view WHERE user = ? AND id = ?
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE user = ? AND id = ?
 `
 
 type GetTokenParams struct {
@@ -85,16 +193,26 @@ func (q *Queries) GetToken(ctx context.Context, arg GetTokenParams) (TokenView,
 		&i.Hosts,
 		&i.Paths,
 		&i.Headers,
+		&i.Meta,
 		&i.Requests,
 		&i.LastAccessAt,
 		&i.ProjectID,
 		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
 	)
 	return i, err
 }
 
 const getTokenByID = `-- name: GetTokenByID :one
-SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, requests, last_access_at, project_id, project_slug FROM token_view WHERE id = ?
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE id = ?
 `
 
 func (q *Queries) GetTokenByID(ctx context.Context, id int64) (TokenView, error) {
@@ -111,16 +229,99 @@ func (q *Queries) GetTokenByID(ctx context.Context, id int64) (TokenView, error)
 		&i.Hosts,
 		&i.Paths,
 		&i.Headers,
+		&i.Meta,
+		&i.Requests,
+		&i.LastAccessAt,
+		&i.ProjectID,
+		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
+	)
+	return i, err
+}
+
+const getTokenByKeyID = `-- name: GetTokenByKeyID :one
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view WHERE user = ? AND key_id = ?
+`
+
+type GetTokenByKeyIDParams struct {
+	User  string      `json:"user"`
+	KeyID types.KeyID `json:"key_id"`
+}
+
+func (q *Queries) GetTokenByKeyID(ctx context.Context, arg GetTokenByKeyIDParams) (TokenView, error) {
+	row := q.db.QueryRowContext(ctx, getTokenByKeyID, arg.User, arg.KeyID)
+	var i TokenView
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.KeyID,
+		&i.Hash,
+		&i.User,
+		&i.Label,
+		&i.Hosts,
+		&i.Paths,
+		&i.Headers,
+		&i.Meta,
 		&i.Requests,
 		&i.LastAccessAt,
 		&i.ProjectID,
 		&i.ProjectSlug,
+		&i.ProjectHosts,
+		&i.ProjectPaths,
+		&i.ProjectHeaders,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.HashAlg,
+		&i.PepperID,
+		&i.KeyFormat,
+		&i.KeyPrefix,
 	)
 	return i, err
 }
 
+const listAllTokenProjects = `-- name: ListAllTokenProjects :many
+SELECT token_id, project_id, project_slug, user FROM token_project_view
+`
+
+func (q *Queries) ListAllTokenProjects(ctx context.Context) ([]TokenProjectView, error) {
+	rows, err := q.db.QueryContext(ctx, listAllTokenProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenProjectView{}
+	for rows.Next() {
+		var i TokenProjectView
+		if err := rows.Scan(
+			&i.TokenID,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.User,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAllTokens = `-- name: ListAllTokens :many
-SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, requests, last_access_at, project_id, project_slug FROM token_view
+SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, meta, requests, last_access_at, project_id, project_slug, project_hosts, project_paths, project_headers, disabled_at, disabled_reason, hash_alg, pepper_id, key_format, key_prefix FROM token_view
 `
 
 func (q *Queries) ListAllTokens(ctx context.Context) ([]TokenView, error) {
@@ -143,10 +344,81 @@ func (q *Queries) ListAllTokens(ctx context.Context) ([]TokenView, error) {
 			&i.Hosts,
 			&i.Paths,
 			&i.Headers,
+			&i.Meta,
 			&i.Requests,
 			&i.LastAccessAt,
 			&i.ProjectID,
 			&i.ProjectSlug,
+			&i.ProjectHosts,
+			&i.ProjectPaths,
+			&i.ProjectHeaders,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.HashAlg,
+			&i.PepperID,
+			&i.KeyFormat,
+			&i.KeyPrefix,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChangedTokens = `-- name: ListChangedTokens :many
+SELECT token_view.id, token_view.created_at, token_view.updated_at, token_view.key_id, token_view.hash, token_view.user, token_view.label, token_view.hosts, token_view.paths, token_view.headers, token_view.meta, token_view.requests, token_view.last_access_at, token_view.project_id, token_view.project_slug, token_view.project_hosts, token_view.project_paths, token_view.project_headers, token_view.disabled_at, token_view.disabled_reason, token_view.hash_alg, token_view.pepper_id, token_view.key_format, token_view.key_prefix
+FROM token_view
+WHERE token_view.id IN (SELECT t.id FROM token t WHERE substr(t.changed_at, 1, 19) >= CAST(?1 AS TEXT))
+   OR token_view.project_id IN (SELECT p.id FROM project p WHERE substr(p.updated_at, 1, 19) >= ?1)
+   OR token_view.id IN (SELECT tp.token_id
+                        FROM token_project tp
+                                 JOIN project p ON p.id = tp.project_id
+                        WHERE substr(p.updated_at, 1, 19) >= ?1)
+`
+
+// Tokens changed since the time, directly or by changes of their own or linked projects.
+// Time is compared with one second precision.
+func (q *Queries) ListChangedTokens(ctx context.Context, since string) ([]TokenView, error) {
+	rows, err := q.db.QueryContext(ctx, listChangedTokens, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenView{}
+	for rows.Next() {
+		var i TokenView
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.KeyID,
+			&i.Hash,
+			&i.User,
+			&i.Label,
+			&i.Hosts,
+			&i.Paths,
+			&i.Headers,
+			&i.Meta,
+			&i.Requests,
+			&i.LastAccessAt,
+			&i.ProjectID,
+			&i.ProjectSlug,
+			&i.ProjectHosts,
+			&i.ProjectPaths,
+			&i.ProjectHeaders,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.HashAlg,
+			&i.PepperID,
+			&i.KeyFormat,
+			&i.KeyPrefix,
 		); err != nil {
 			return nil, err
 		}
@@ -162,7 +434,9 @@ func (q *Queries) ListAllTokens(ctx context.Context) ([]TokenView, error) {
 }
 
 const listTokenIDsByProject = `-- name: ListTokenIDsByProject :many
-SELECT id FROM token WHERE project_id = ?
+SELECT token.id FROM token WHERE token.project_id = ?1
+UNION
+SELECT tp.token_id FROM token_project tp WHERE tp.project_id = ?1
 `
 
 func (q *Queries) ListTokenIDsByProject(ctx context.Context, projectID int64) ([]int64, error) {
@@ -188,34 +462,56 @@ func (q *Queries) ListTokenIDsByProject(ctx context.Context, projectID int64) ([
 	return items, nil
 }
 
-const listTokens = `-- name: ListTokens :many
-SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, requests, last_access_at, project_id, project_slug FROM token_view WHERE user = ? ORDER BY id DESC
+const listTokenProjects = `-- name: ListTokenProjects :many
+SELECT token_id, project_id, project_slug, user FROM token_project_view WHERE token_id = ?
 `
 
-func (q *Queries) ListTokens(ctx context.Context, user string) ([]TokenView, error) {
-	rows, err := q.db.QueryContext(ctx, listTokens, user)
+func (q *Queries) ListTokenProjects(ctx context.Context, tokenID int64) ([]TokenProjectView, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenProjects, tokenID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []TokenView{}
+	items := []TokenProjectView{}
 	for rows.Next() {
-		var i TokenView
+		var i TokenProjectView
 		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.KeyID,
-			&i.Hash,
+			&i.TokenID,
+			&i.ProjectID,
+			&i.ProjectSlug,
 			&i.User,
-			&i.Label,
-			&i.Hosts,
-			&i.Paths,
-			&i.Headers,
-			&i.Requests,
-			&i.LastAccessAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTokenProjectsByUser = `-- name: ListTokenProjectsByUser :many
+SELECT token_id, project_id, project_slug, user FROM token_project_view WHERE user = ?
+`
+
+func (q *Queries) ListTokenProjectsByUser(ctx context.Context, user string) ([]TokenProjectView, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenProjectsByUser, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TokenProjectView{}
+	for rows.Next() {
+		var i TokenProjectView
+		if err := rows.Scan(
+			&i.TokenID,
 			&i.ProjectID,
 			&i.ProjectSlug,
+			&i.User,
 		); err != nil {
 			return nil, err
 		}
@@ -230,17 +526,120 @@ func (q *Queries) ListTokens(ctx context.Context, user string) ([]TokenView, err
 	return items, nil
 }
 
-const listTokensByUserAndProject = `-- name: ListTokensByUserAndProject :many
-SELECT id, created_at, updated_at, key_id, hash, user, label, hosts, paths, headers, requests, last_access_at, project_id, project_slug FROM token_view WHERE user = ? AND project_id = ? ORDER BY id DESC
+const listTokenTombstones = `-- name: ListTokenTombstones :many
+SELECT DISTINCT token_id FROM token_tombstone WHERE substr(deleted_at, 1, 19) >= CAST(?1 AS TEXT)
 `
 
-type ListTokensByUserAndProjectParams struct {
-	User      string `json:"user"`
-	ProjectID int64  `json:"project_id"`
+func (q *Queries) ListTokenTombstones(ctx context.Context, since string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listTokenTombstones, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var token_id int64
+		if err := rows.Scan(&token_id); err != nil {
+			return nil, err
+		}
+		items = append(items, token_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) ListTokensByUserAndProject(ctx context.Context, arg ListTokensByUserAndProjectParams) ([]TokenView, error) {
-	rows, err := q.db.QueryContext(ctx, listTokensByUserAndProject, arg.User, arg.ProjectID)
+const listTokens = `-- name: ListTokens :many
+SELECT token_view.id, token_view.created_at, token_view.updated_at, token_view.key_id, token_view.hash, token_view.user, token_view.label, token_view.hosts, token_view.paths, token_view.headers, token_view.meta, token_view.requests, token_view.last_access_at, token_view.project_id, token_view.project_slug, token_view.project_hosts, token_view.project_paths, token_view.project_headers, token_view.disabled_at, token_view.disabled_reason, token_view.hash_alg, token_view.pepper_id, token_view.key_format, token_view.key_prefix
+FROM token_view,
+     (SELECT CAST(?1 AS TEXT) AS sort) opts
+WHERE token_view.user = ?2
+  AND (CAST(?3 AS INTEGER) = 0
+    OR token_view.project_id = ?3
+    OR token_view.id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = ?3))
+  AND NOT EXISTS (SELECT 1 FROM json_each(CAST(?4 AS TEXT)) f
+                  WHERE json_extract(token_view.meta, '$."' || f.key || '"') IS NOT f.value)
+  AND (CAST(?5 AS TEXT) = ''
+    OR instr(lower(token_view.label), lower(?5)) > 0
+    OR instr(upper(token_view.key_id), upper(?5)) = 1)
+  AND (CAST(?6 AS TEXT) = '' OR instr(lower(token_view.hosts), lower(?6)) > 0)
+  AND (CAST(?7 AS INTEGER) = 0
+    OR (token_view.requests > 0 AND CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) >= ?7))
+  AND (CAST(?8 AS INTEGER) = 0
+    OR (token_view.requests > 0 AND CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) < ?8))
+  AND (CAST(?9 AS INTEGER) = 0
+    OR token_view.requests = 0 OR CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) < ?9)
+  AND (CAST(?10 AS TEXT) = ''
+    OR (?10 = 'disabled') = (token_view.disabled_at IS NOT NULL))
+  AND (CAST(?11 AS INTEGER) = 0 OR CASE opts.sort
+    WHEN 'created_asc' THEN token_view.id > ?11
+    WHEN 'label_asc' THEN lower(token_view.label) > lower(?12)
+        OR (lower(token_view.label) = lower(?12) AND token_view.id > ?11)
+    WHEN 'label_desc' THEN lower(token_view.label) < lower(?12)
+        OR (lower(token_view.label) = lower(?12) AND token_view.id < ?11)
+    WHEN 'last_access_asc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) > CAST(?13 AS INTEGER)
+        OR (CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) = ?13 AND token_view.id > ?11)
+    WHEN 'last_access_desc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) < ?13
+        OR (CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) = ?13 AND token_view.id < ?11)
+    WHEN 'requests_asc' THEN token_view.requests > ?13
+        OR (token_view.requests = ?13 AND token_view.id > ?11)
+    WHEN 'requests_desc' THEN token_view.requests < ?13
+        OR (token_view.requests = ?13 AND token_view.id < ?11)
+    ELSE token_view.id < ?11
+    END)
+ORDER BY CASE WHEN opts.sort = 'label_asc' THEN lower(token_view.label) END ASC,
+         CASE WHEN opts.sort = 'label_desc' THEN lower(token_view.label) END DESC,
+         CASE WHEN opts.sort = 'last_access_asc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) END ASC,
+         CASE WHEN opts.sort = 'last_access_desc' THEN CAST(strftime('%s', substr(token_view.last_access_at, 1, 19)) AS INTEGER) END DESC,
+         CASE WHEN opts.sort = 'requests_asc' THEN token_view.requests END ASC,
+         CASE WHEN opts.sort = 'requests_desc' THEN token_view.requests END DESC,
+         CASE WHEN opts.sort IN ('created_asc', 'label_asc', 'last_access_asc', 'requests_asc') THEN token_view.id END ASC,
+         token_view.id DESC
+LIMIT ?14
+`
+
+type ListTokensParams struct {
+	Sort           string `json:"sort"`
+	User           string `json:"user"`
+	ProjectID      int64  `json:"project_id"`
+	Meta           string `json:"meta"`
+	Search         string `json:"search"`
+	Host           string `json:"host"`
+	AccessedAfter  int64  `json:"accessed_after"`
+	AccessedBefore int64  `json:"accessed_before"`
+	UnusedSince    int64  `json:"unused_since"`
+	State          string `json:"state"`
+	AfterID        int64  `json:"after_id"`
+	AfterLabel     string `json:"after_label"`
+	AfterValue     int64  `json:"after_value"`
+	MaxRows        int64  `json:"max_rows"`
+}
+
+// Empty/zero arguments disable the corresponding filter.
+// meta is a JSON object, all pairs of which must be present in token meta.
+// Keyset pagination: rows strictly after the cursor (after_id and the sort value) in the chosen order.
+// Time is compared with one second precision; ties are resolved by id.
+func (q *Queries) ListTokens(ctx context.Context, arg ListTokensParams) ([]TokenView, error) {
+	rows, err := q.db.QueryContext(ctx, listTokens,
+		arg.Sort,
+		arg.User,
+		arg.ProjectID,
+		arg.Meta,
+		arg.Search,
+		arg.Host,
+		arg.AccessedAfter,
+		arg.AccessedBefore,
+		arg.UnusedSince,
+		arg.State,
+		arg.AfterID,
+		arg.AfterLabel,
+		arg.AfterValue,
+		arg.MaxRows,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -259,10 +658,20 @@ func (q *Queries) ListTokensByUserAndProject(ctx context.Context, arg ListTokens
 			&i.Hosts,
 			&i.Paths,
 			&i.Headers,
+			&i.Meta,
 			&i.Requests,
 			&i.LastAccessAt,
 			&i.ProjectID,
 			&i.ProjectSlug,
+			&i.ProjectHosts,
+			&i.ProjectPaths,
+			&i.ProjectHeaders,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.HashAlg,
+			&i.PepperID,
+			&i.KeyFormat,
+			&i.KeyPrefix,
 		); err != nil {
 			return nil, err
 		}
@@ -277,22 +686,36 @@ func (q *Queries) ListTokensByUserAndProject(ctx context.Context, arg ListTokens
 	return items, nil
 }
 
+const purgeTokenTombstones = `-- name: PurgeTokenTombstones :exec
+DELETE FROM token_tombstone WHERE substr(deleted_at, 1, 19) < CAST(?1 AS TEXT)
+`
+
+func (q *Queries) PurgeTokenTombstones(ctx context.Context, before string) error {
+	_, err := q.db.ExecContext(ctx, purgeTokenTombstones, before)
+	return err
+}
+
 const refreshToken = `-- name: RefreshToken :execrows
 UPDATE token
-SET hash = ?, key_id = ?, updated_at = current_timestamp
+SET hash = ?, hash_alg = ?, pepper_id = ?, key_id = ?, key_format = 'native', key_prefix = '', disabled_at = NULL, disabled_reason = '', updated_at = current_timestamp,
+    changed_at = current_timestamp
 WHERE user = ? AND id = ?
 `
 
 type RefreshTokenParams struct {
-	Hash  []byte      `json:"hash"`
-	KeyID types.KeyID `json:"key_id"`
-	User  string      `json:"user"`
-	ID    int64       `json:"id"`
+	Hash     []byte      `json:"hash"`
+	HashAlg  string      `json:"hash_alg"`
+	PepperID string      `json:"pepper_id"`
+	KeyID    types.KeyID `json:"key_id"`
+	User     string      `json:"user"`
+	ID       int64       `json:"id"`
 }
 
 func (q *Queries) RefreshToken(ctx context.Context, arg RefreshTokenParams) (int64, error) {
 	result, err := q.db.ExecContext(ctx, refreshToken,
 		arg.Hash,
+		arg.HashAlg,
+		arg.PepperID,
 		arg.KeyID,
 		arg.User,
 		arg.ID,
@@ -303,19 +726,138 @@ func (q *Queries) RefreshToken(ctx context.Context, arg RefreshTokenParams) (int
 	return result.RowsAffected()
 }
 
+const rehashToken = `-- name: RehashToken :execrows
+UPDATE token
+SET hash = ?1, hash_alg = ?2, pepper_id = ?3, changed_at = current_timestamp
+WHERE id = ?4 AND hash = ?5
+`
+
+type RehashTokenParams struct {
+	NewHash  []byte `json:"new_hash"`
+	HashAlg  string `json:"hash_alg"`
+	PepperID string `json:"pepper_id"`
+	ID       int64  `json:"id"`
+	OldHash  []byte `json:"old_hash"`
+}
+
+// Compare-and-swap: the token is left as is if its hash was changed concurrently.
+func (q *Queries) RehashToken(ctx context.Context, arg RehashTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashToken,
+		arg.NewHash,
+		arg.HashAlg,
+		arg.PepperID,
+		arg.ID,
+		arg.OldHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeTokenProject = `-- name: RemoveTokenProject :exec
+DELETE FROM token_project WHERE token_id = ? AND project_id = ?
+`
+
+type RemoveTokenProjectParams struct {
+	TokenID   int64 `json:"token_id"`
+	ProjectID int64 `json:"project_id"`
+}
+
+func (q *Queries) RemoveTokenProject(ctx context.Context, arg RemoveTokenProjectParams) error {
+	_, err := q.db.ExecContext(ctx, removeTokenProject, arg.TokenID, arg.ProjectID)
+	return err
+}
+
+const restoreToken = `-- name: RestoreToken :exec
+INSERT INTO token (id, created_at, updated_at, key_id, hash, hash_alg, pepper_id, user, label, hosts, paths, headers, meta,
+                   requests, last_access_at, project_id, disabled_at, disabled_reason, key_format, key_prefix, changed_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, current_timestamp)
+ON CONFLICT (id) DO UPDATE
+SET created_at = excluded.created_at, updated_at = excluded.updated_at, key_id = excluded.key_id, hash = excluded.hash,
+    hash_alg = excluded.hash_alg, pepper_id = excluded.pepper_id, user = excluded.user, label = excluded.label,
+    hosts = excluded.hosts, paths = excluded.paths, headers = excluded.headers, meta = excluded.meta,
+    requests = excluded.requests, last_access_at = excluded.last_access_at, project_id = excluded.project_id,
+    disabled_at = excluded.disabled_at, disabled_reason = excluded.disabled_reason,
+    key_format = excluded.key_format, key_prefix = excluded.key_prefix, changed_at = excluded.changed_at
+`
+
+type RestoreTokenParams struct {
+	ID             int64         `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	KeyID          types.KeyID   `json:"key_id"`
+	Hash           []byte        `json:"hash"`
+	HashAlg        string        `json:"hash_alg"`
+	PepperID       string        `json:"pepper_id"`
+	User           string        `json:"user"`
+	Label          string        `json:"label"`
+	Hosts          string        `json:"hosts"`
+	Paths          string        `json:"paths"`
+	Headers        types.Headers `json:"headers"`
+	Meta           types.Meta    `json:"meta"`
+	Requests       int64         `json:"requests"`
+	LastAccessAt   time.Time     `json:"last_access_at"`
+	ProjectID      int64         `json:"project_id"`
+	DisabledAt     *time.Time    `json:"disabled_at"`
+	DisabledReason string        `json:"disabled_reason"`
+	KeyFormat      string        `json:"key_format"`
+	KeyPrefix      string        `json:"key_prefix"`
+}
+
+// Insert or replace token with its original ID, hash and stats, used to copy databases.
+func (q *Queries) RestoreToken(ctx context.Context, arg RestoreTokenParams) error {
+	_, err := q.db.ExecContext(ctx, restoreToken,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.KeyID,
+		arg.Hash,
+		arg.HashAlg,
+		arg.PepperID,
+		arg.User,
+		arg.Label,
+		arg.Hosts,
+		arg.Paths,
+		arg.Headers,
+		arg.Meta,
+		arg.Requests,
+		arg.LastAccessAt,
+		arg.ProjectID,
+		arg.DisabledAt,
+		arg.DisabledReason,
+		arg.KeyFormat,
+		arg.KeyPrefix,
+	)
+	return err
+}
+
+const touchLinkedTokens = `-- name: TouchLinkedTokens :exec
+UPDATE token SET changed_at = current_timestamp
+WHERE id IN (SELECT tp.token_id FROM token_project tp WHERE tp.project_id = ?1)
+`
+
+// Marks tokens linked to the project as changed, since they lose the link when the project is deleted.
+func (q *Queries) TouchLinkedTokens(ctx context.Context, projectID int64) error {
+	_, err := q.db.ExecContext(ctx, touchLinkedTokens, projectID)
+	return err
+}
+
 const updateToken = `-- name: UpdateToken :execrows
 UPDATE token
-SET hosts = ?, paths = ?, label = ?, headers = ?, updated_at = current_timestamp
+SET hosts = ?, paths = ?, label = ?, headers = ?, meta = ?, project_id = ?, updated_at = current_timestamp, changed_at = current_timestamp
 WHERE user = ? AND id = ?
 `
 
 type UpdateTokenParams struct {
-	Hosts   string        `json:"hosts"`
-	Paths   string        `json:"paths"`
-	Label   string        `json:"label"`
-	Headers types.Headers `json:"headers"`
-	User    string        `json:"user"`
-	ID      int64         `json:"id"`
+	Hosts     string        `json:"hosts"`
+	Paths     string        `json:"paths"`
+	Label     string        `json:"label"`
+	Headers   types.Headers `json:"headers"`
+	Meta      types.Meta    `json:"meta"`
+	ProjectID int64         `json:"project_id"`
+	User      string        `json:"user"`
+	ID        int64         `json:"id"`
 }
 
 func (q *Queries) UpdateToken(ctx context.Context, arg UpdateTokenParams) (int64, error) {
@@ -324,6 +866,8 @@ func (q *Queries) UpdateToken(ctx context.Context, arg UpdateTokenParams) (int64
 		arg.Paths,
 		arg.Label,
 		arg.Headers,
+		arg.Meta,
+		arg.ProjectID,
 		arg.User,
 		arg.ID,
 	)
@@ -1,33 +1,152 @@
 // Package dbo provides a universal database access layer with engine-specific
-// implementations for SQLite and PostgreSQL via sqlc.
+// implementations for SQLite, PostgreSQL and MySQL via sqlc.
 //
 //go:generate go run github.com/sqlc-dev/sqlc/cmd/sqlc generate -f sqlc.yaml
 package dbo
 
 import (
 	"context"
+	"errors"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/reddec/token-login/internal/types"
 )
 
+// ErrSlugInUse is returned when a project slug clashes with an existing slug
+// or alias of the same user.
+var ErrSlugInUse = errors.New("slug already in use")
+
+// ErrNotFound is returned by lookups which distinguish a missing row from a failure.
+var ErrNotFound = errors.New("not found")
+
+// ErrBackupUnsupported is returned by Backup for databases which can't be copied to a file.
+var ErrBackupUnsupported = errors.New("backup is supported only for SQLite")
+
+// Backuper is implemented by stores which can write a consistent copy of the database
+// to a file without stopping writers (SQLite).
+type Backuper interface {
+	// Backup writes the copy to the path. The file must not exist.
+	Backup(ctx context.Context, path string) error
+}
+
+// Backup writes a consistent copy of the database to the path, if the store supports it.
+func Backup(ctx context.Context, store Store, path string) error {
+	b, ok := store.(Backuper)
+	if !ok {
+		return ErrBackupUnsupported
+	}
+	return b.Backup(ctx, path) //nolint:wrapcheck // wrapped by implementation
+}
+
+// TokenEvent is a committed change of the token, published to all instances by Notifier.
+type TokenEvent struct {
+	ID int64
+	// Removed is set if the token was deleted, otherwise it was created or changed.
+	Removed bool
+}
+
+// Notifier is implemented by stores which publish token changes to all instances
+// as soon as they are committed (PostgreSQL).
+type Notifier interface {
+	// Listen calls fn for every token change until the context is canceled or the connection is lost.
+	// Changes committed while not listening are not delivered.
+	Listen(ctx context.Context, fn func(TokenEvent)) error
+}
+
 // Token is the domain model for an access token.
 type Token struct {
-	ID           int64         `json:"id"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	KeyID        *types.KeyID  `json:"key_id"`
-	Hash         []byte        `json:"-"`
-	User         string        `json:"user"`
-	Label        string        `json:"label"`
-	Paths        []string      `json:"paths"`
-	Hosts        []string      `json:"hosts"`
-	Headers      types.Headers `json:"headers,omitempty"`
-	ProjectID    int64         `json:"project_id"`
-	ProjectSlug  string        `json:"project_slug,omitempty"`
-	Requests     int64         `json:"requests"`
-	LastAccessAt time.Time     `json:"last_access_at"`
+	ID             int64               `json:"id"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	KeyID          *types.KeyID        `json:"key_id"`
+	Hash           []byte              `json:"-"`
+	HashAlg        types.HashAlgorithm `json:"-"`
+	PepperID       string              `json:"-"`
+	User           string              `json:"user"`
+	Label          string              `json:"label"`
+	Paths          []string            `json:"paths"`
+	Hosts          []string            `json:"hosts"`
+	Headers        types.Headers       `json:"headers,omitempty"`
+	Meta           types.Meta          `json:"meta,omitempty"`
+	ProjectID      int64               `json:"project_id"`
+	ProjectSlug    string              `json:"project_slug,omitempty"`
+	ProjectAliases []string            `json:"project_aliases,omitempty"`
+	ProjectHosts   []string            `json:"project_hosts,omitempty"`
+	ProjectPaths   []string            `json:"project_paths,omitempty"`
+	ProjectHeaders types.Headers       `json:"project_headers,omitempty"`
+	LinkedProjects []ProjectRef        `json:"linked_projects,omitempty"`
+	Requests       int64               `json:"requests"`
+	LastAccessAt   time.Time           `json:"last_access_at"`
+	DisabledAt     *time.Time          `json:"disabled_at,omitempty"`
+	DisabledReason string              `json:"disabled_reason,omitempty"`
+	KeyFormat      types.KeyFormat     `json:"key_format"`
+	KeyPrefix      string              `json:"key_prefix,omitempty"`
+}
+
+// HashSpec describes how the stored hash was made.
+func (t *Token) HashSpec() types.HashSpec {
+	return types.HashSpec{Algorithm: t.HashAlg, PepperID: t.PepperID}
+}
+
+// Disabled reports whether the token was disabled (for example, reported as leaked).
+// Disabled tokens are rejected until the key is refreshed.
+func (t *Token) Disabled() bool {
+	return t.DisabledAt != nil
+}
+
+// EffectiveHosts returns the token hosts, or the project defaults if the token
+// has none.
+func (t *Token) EffectiveHosts() []string {
+	if len(t.Hosts) > 0 {
+		return t.Hosts
+	}
+	return t.ProjectHosts
+}
+
+// EffectivePaths returns the token paths, or the project defaults if the token
+// has none.
+func (t *Token) EffectivePaths() []string {
+	if len(t.Paths) > 0 {
+		return t.Paths
+	}
+	return t.ProjectPaths
+}
+
+// EffectiveHeaders returns the project default headers overridden (by name)
+// by the token headers.
+func (t *Token) EffectiveHeaders() types.Headers {
+	return t.ProjectHeaders.Merge(t.Headers)
+}
+
+// ProjectRef is a reference to an additional project the token is valid for.
+type ProjectRef struct {
+	ID      int64    `json:"id"`
+	Slug    string   `json:"slug"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// InProject reports whether the token may be used for the project slug,
+// either by its current value or by one of its aliases. Linked projects are
+// checked the same way.
+func (t *Token) InProject(slug string) bool {
+	_, ok := t.MatchProject(slug)
+	return ok
+}
+
+// MatchProject resolves the project slug (or alias) to the token's own or
+// linked project.
+func (t *Token) MatchProject(slug string) (ProjectRef, bool) {
+	if t.ProjectSlug == slug || slices.Contains(t.ProjectAliases, slug) {
+		return ProjectRef{ID: t.ProjectID, Slug: t.ProjectSlug, Aliases: t.ProjectAliases}, true
+	}
+	for _, p := range t.LinkedProjects {
+		if p.Slug == slug || slices.Contains(p.Aliases, slug) {
+			return p, true
+		}
+	}
+	return ProjectRef{}, false
 }
 
 // Project is the domain model for a project.
@@ -38,6 +157,11 @@ type Project struct {
 	User        string    `json:"user"`
 	Slug        string    `json:"slug"`
 	Description string    `json:"description"`
+	Aliases     []string  `json:"aliases,omitempty"`
+	// Hosts, Paths and Headers are defaults inherited by tokens of the project.
+	Hosts   []string      `json:"hosts,omitempty"`
+	Paths   []string      `json:"paths,omitempty"`
+	Headers types.Headers `json:"headers,omitempty"`
 }
 
 // StatsEntry holds accumulated request count and last access time.
@@ -50,22 +174,91 @@ type StatsEntry struct {
 type CreateTokenParams struct {
 	User      string
 	Hash      []byte
+	HashSpec  types.HashSpec
 	KeyID     *types.KeyID
 	Label     string
 	Hosts     []string
 	Paths     []string
 	Headers   types.Headers
+	Meta      types.Meta
 	ProjectID int64
+	// LinkedProjectIDs are additional projects the token is valid for.
+	LinkedProjectIDs []int64
+	// KeyFormat defaults to native. Imported keys also need the public KeyPrefix
+	// from which KeyID was derived.
+	KeyFormat types.KeyFormat
+	KeyPrefix string
 }
 
 // UpdateTokenParams contains the fields for updating a token's mutable config.
 type UpdateTokenParams struct {
-	User    string
-	ID      int64
-	Hosts   *[]string
-	Paths   *[]string
-	Label   *string
-	Headers *types.Headers
+	User      string
+	ID        int64
+	Hosts     *[]string
+	Paths     *[]string
+	Label     *string
+	Headers   *types.Headers
+	Meta      *types.Meta
+	ProjectID *int64
+	// LinkedProjectIDs, if set, replaces the list of additional projects.
+	LinkedProjectIDs *[]int64
+}
+
+// TokenSort is a field used to order tokens.
+type TokenSort string
+
+const (
+	SortCreated    TokenSort = "created"
+	SortLabel      TokenSort = "label"
+	SortLastAccess TokenSort = "last_access"
+	SortRequests   TokenSort = "requests"
+)
+
+// ListTokensParams filters, orders and paginates tokens of the user.
+// Zero values disable the corresponding filter.
+type ListTokensParams struct {
+	User string
+	// ProjectID limits results to tokens of the project (own or linked), if not zero.
+	ProjectID int64
+	// Meta limits results to tokens having all the key/value pairs.
+	Meta types.Meta
+	// Search matches a case-insensitive substring of the label or a key ID prefix.
+	Search string
+	// Host matches a case-insensitive substring of any host pattern.
+	Host string
+	// AccessedAfter and AccessedBefore limit results to used tokens last accessed in the range.
+	AccessedAfter  time.Time
+	AccessedBefore time.Time
+	// UnusedSince limits results to tokens not accessed since the time (or never).
+	UnusedSince time.Time
+	// Disabled limits results to disabled (true) or active (false) tokens, if set.
+	Disabled *bool
+	// Sort field, SortCreated by default. Order is descending unless Asc is set.
+	Sort TokenSort
+	Asc  bool
+	// After returns tokens strictly after the cursor in the chosen order.
+	After *TokenCursor
+	// Limit is the maximum number of tokens to return; zero means no limit.
+	Limit int
+}
+
+// TokenCursor is a position in an ordered list of tokens.
+// Last access time is compared with one second precision.
+type TokenCursor struct {
+	ID           int64
+	Label        string
+	LastAccessAt time.Time
+	Requests     int64
+}
+
+// Cursor returns position of the token for pagination.
+func (t *Token) Cursor() TokenCursor {
+	return TokenCursor{
+		ID:           t.ID,
+		Label:        t.Label,
+		LastAccessAt: t.LastAccessAt,
+		Requests:     t.Requests,
+	}
 }
 
 // CreateProjectParams contains the fields needed to create a new project.
@@ -73,13 +266,64 @@ type CreateProjectParams struct {
 	User        string
 	Slug        string
 	Description string
+	Hosts       []string
+	Paths       []string
+	Headers     types.Headers
 }
 
 // UpdateProjectParams contains the fields for updating a project.
+//
+// Changing the slug keeps the previous one as an alias until it is
+// explicitly removed by DeleteProjectAlias.
 type UpdateProjectParams struct {
 	User        string
 	ID          int64
-	Description string
+	Slug        *string
+	Description *string
+	Hosts       *[]string
+	Paths       *[]string
+	Headers     *types.Headers
+}
+
+// ProjectAlias is a previous slug of a project.
+type ProjectAlias struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ProjectID int64     `json:"project_id"`
+	User      string    `json:"user"`
+	Slug      string    `json:"slug"`
+}
+
+// Snapshot holds all rows of the database with their IDs, hashes and stats.
+// Aliases of projects are in Aliases, links of tokens - in Token.LinkedProjects.
+type Snapshot struct {
+	Projects []*Project
+	Aliases  []*ProjectAlias
+	Tokens   []*Token
+}
+
+// TombstoneRetention is how long deleted tokens are reported by ListTokenChanges.
+// Caches not synced for longer must reload all tokens.
+const TombstoneRetention = 24 * time.Hour
+
+// TokenChanges is a change feed of tokens, see Store.ListTokenChanges.
+type TokenChanges struct {
+	// Tokens changed or created since the time, including tokens affected by changes
+	// of their own or linked projects and aliases.
+	Tokens []*Token
+	// Deleted are IDs of tokens deleted since the time. IDs may be reused by new tokens,
+	// so deletions go before changes.
+	Deleted []int64
+	// At is the database time before the changes were listed, the start of the next window.
+	At time.Time
+}
+
+// RowCounts is the number of rows per table.
+type RowCounts struct {
+	Projects      int64 `json:"projects"`
+	Aliases       int64 `json:"aliases"`
+	Tokens        int64 `json:"tokens"`
+	TokenProjects int64 `json:"token_projects"`
 }
 
 // Store is the universal database access interface.
@@ -90,10 +334,19 @@ type Store interface {
 	CreateToken(ctx context.Context, p CreateTokenParams) (*Token, error)
 	GetToken(ctx context.Context, user string, id int64) (*Token, error)
 	GetTokenByID(ctx context.Context, id int64) (*Token, error)
-	ListTokens(ctx context.Context, user string, projectID int64) ([]*Token, error)
+	GetTokenByKeyID(ctx context.Context, user string, keyID types.KeyID) (*Token, error)
+	ListTokens(ctx context.Context, p ListTokensParams) ([]*Token, error)
 	UpdateToken(ctx context.Context, p UpdateTokenParams) (int64, error)
 	DeleteToken(ctx context.Context, user string, id int64) (int64, error)
-	RefreshToken(ctx context.Context, user string, id int64, hash []byte, keyID *types.KeyID) (int64, error)
+	RefreshToken(ctx context.Context, user string, id int64, hash []byte, spec types.HashSpec, keyID *types.KeyID) (int64, error)
+	// RehashToken replaces the hash only if it is still oldHash.
+	RehashToken(ctx context.Context, id int64, oldHash, newHash []byte, spec types.HashSpec) (int64, error)
+
+	// Leak handling — unscoped, the reporter is not the owner.
+	// FindTokenByKeyID returns ErrNotFound for unknown keys.
+	FindTokenByKeyID(ctx context.Context, keyID types.KeyID) (*Token, error)
+	// DisableToken disables active token as of the time, which is kept when tokens are copied.
+	DisableToken(ctx context.Context, id int64, reason string, at time.Time) (int64, error)
 
 	// Project CRUD — user-scoped.
 	CreateProject(ctx context.Context, p CreateProjectParams) (*Project, error)
@@ -102,11 +355,25 @@ type Store interface {
 	UpdateProject(ctx context.Context, p UpdateProjectParams) (int64, error)
 	DeleteProject(ctx context.Context, user string, id int64) ([]int64, error)
 	ProjectExists(ctx context.Context, user string, id int64) (bool, error)
+	ListProjectTokenIDs(ctx context.Context, user string, id int64) ([]int64, error)
+	DeleteProjectAlias(ctx context.Context, user string, id int64, slug string) (int64, error)
+	// AddProjectAlias returns ErrNotFound for unknown projects and ErrSlugInUse if the slug is taken.
+	AddProjectAlias(ctx context.Context, user string, id int64, slug string) error
 
 	// Cache operations — unfiltered, returns all rows.
 	ListAllTokens(ctx context.Context) ([]*Token, error)
 	ListAllProjects(ctx context.Context) ([]*Project, error)
+	// ListTokenChanges returns tokens changed since the time (all tokens for zero time) and tokens
+	// deleted since then (within TombstoneRetention). Changes are timed by the database clock when
+	// made, but become visible on commit, so windows of consecutive calls should overlap.
+	ListTokenChanges(ctx context.Context, since time.Time) (*TokenChanges, error)
 
 	// Stats — transactional batch update.
 	UpdateStats(ctx context.Context, stats map[int64]StatsEntry) error
+
+	// Copy between databases — unfiltered.
+	Snapshot(ctx context.Context) (*Snapshot, error)
+	// Restore inserts or replaces rows of the snapshot, keeping their IDs, in a single transaction.
+	Restore(ctx context.Context, snapshot *Snapshot) error
+	CountRows(ctx context.Context) (RowCounts, error)
 }
@@ -0,0 +1,94 @@
+package plumbing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/reddec/token-login/internal/dbo"
+)
+
+const (
+	backupPrefix     = "token-login-"
+	backupSuffix     = ".sqlite"
+	backupTimeFormat = "20060102T150405Z"
+)
+
+// Backup writes a consistent copy of the database to the directory, named by the time of backup.
+// The copy is written to a temporary file first, so the directory never has partial backups.
+func Backup(ctx context.Context, store dbo.Store, dir string, now time.Time) (string, error) {
+	name := backupPrefix + now.UTC().Format(backupTimeFormat) + backupSuffix
+	path := filepath.Join(dir, name)
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	_ = os.Remove(tmp) // leftover of interrupted backup
+	if err := dbo.Backup(ctx, store, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("rename backup: %w", err)
+	}
+	return path, nil
+}
+
+// PruneBackups removes all but the newest backups in the directory. Other files are not touched.
+func PruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), backupSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	// timestamps in names are sortable
+	slices.Sort(names)
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("remove old backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// RunBackups makes backup to the directory every interval and keeps only the newest backups
+// (all if keep is not positive).
+func RunBackups(ctx context.Context, store dbo.Store, dir string, interval time.Duration, keep int) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		slog.Error("failed create backup directory", "dir", dir, "error", err)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			path, err := Backup(ctx, store, dir, now)
+			if err != nil {
+				slog.Error("failed backup database", "error", err)
+				continue
+			}
+			slog.Info("database backup complete", "path", path)
+			if keep <= 0 {
+				continue
+			}
+			if err := PruneBackups(dir, keep); err != nil {
+				slog.Error("failed prune backups", "dir", dir, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package plumbing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/open"
+	"github.com/reddec/token-login/internal/plumbing"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	store, err := open.Open(ctx, "sqlite://"+filepath.Join(t.TempDir(), "db.sqlite"), nil)
+	require.NoError(t, err)
+	defer store.Close()
+	_, err = store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "web"})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600))
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var paths []string
+	for i := range 3 {
+		path, err := plumbing.Backup(ctx, store, dir, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+		paths = append(paths, path)
+	}
+	assert.Equal(t, filepath.Join(dir, "token-login-20260102T030405Z.sqlite"), paths[0])
+
+	restored, err := open.Open(ctx, "sqlite://"+paths[2], nil)
+	require.NoError(t, err)
+	defer restored.Close()
+	projects, err := restored.ListAllProjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, "web", projects[0].Slug)
+
+	require.NoError(t, plumbing.PruneBackups(dir, 2))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"notes.txt", filepath.Base(paths[1]), filepath.Base(paths[2])}, names)
+}
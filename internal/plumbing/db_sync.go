@@ -9,7 +9,16 @@ import (
 	"github.com/reddec/token-login/web"
 )
 
-func SyncStats(ctx context.Context, store dbo.Store, statsCh <-chan web.Hit, aggregate time.Duration) {
+// statsFlushTimeout limits writing of the last stats after stop.
+const statsFlushTimeout = 5 * time.Second
+
+// StatsWriter receives aggregated usage stats: the database or a buffer shared between instances.
+type StatsWriter interface {
+	UpdateStats(ctx context.Context, stats map[int64]dbo.StatsEntry) error
+}
+
+// SyncStats aggregates hits and writes them every interval. Stats left on stop are written as well.
+func SyncStats(ctx context.Context, store StatsWriter, statsCh <-chan web.Hit, aggregate time.Duration) {
 	ticker := time.NewTicker(aggregate)
 	defer ticker.Stop()
 
@@ -17,6 +26,14 @@ func SyncStats(ctx context.Context, store dbo.Store, statsCh <-chan web.Hit, agg
 	for {
 		select {
 		case <-ctx.Done():
+			if len(stats) == 0 {
+				return
+			}
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statsFlushTimeout)
+			if err := store.UpdateStats(flushCtx, stats); err != nil {
+				slog.Error("failed dump stats on stop", "error", err)
+			}
+			cancel()
 			return
 		case hit, ok := <-statsCh:
 			if !ok {
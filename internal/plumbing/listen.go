@@ -0,0 +1,45 @@
+package plumbing
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/reddec/token-login/internal/cache"
+	"github.com/reddec/token-login/internal/dbo"
+)
+
+const listenRetry = 5 * time.Second
+
+// ListenChanges applies token changes committed by all instances to the cache, so revoked tokens
+// stop working everywhere without waiting for polling. Lost subscription is restored after a delay,
+// and changes missed meanwhile are loaded from the store (the rest are left to polling).
+func ListenChanges(ctx context.Context, notifier dbo.Notifier, keys *cache.Cache) {
+	for {
+		err := notifier.Listen(ctx, func(event dbo.TokenEvent) {
+			applyTokenEvent(ctx, keys, event)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("token changes subscription lost", "error", err, "retry", listenRetry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetry):
+		}
+		if err := keys.SyncChanges(ctx); err != nil {
+			slog.Error("failed sync changed keys", "error", err)
+		}
+	}
+}
+
+func applyTokenEvent(ctx context.Context, keys *cache.Cache, event dbo.TokenEvent) {
+	if event.Removed {
+		keys.Drop(int(event.ID))
+		return
+	}
+	if err := keys.SyncKey(ctx, int(event.ID)); err != nil {
+		slog.Error("sync key failed", "id", event.ID, "err", err)
+	}
+}
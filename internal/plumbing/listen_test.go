@@ -0,0 +1,67 @@
+package plumbing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/cache"
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/memory"
+	"github.com/reddec/token-login/internal/plumbing"
+	"github.com/reddec/token-login/internal/types"
+)
+
+type chanNotifier chan dbo.TokenEvent
+
+func (n chanNotifier) Listen(ctx context.Context, fn func(dbo.TokenEvent)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-n:
+			fn(event)
+		}
+	}
+}
+
+func TestListenChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := memory.NewStore()
+	keys := cache.New(store)
+	require.NoError(t, keys.SyncKeys(ctx))
+
+	project, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: ""})
+	require.NoError(t, err)
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	token, err := store.CreateToken(ctx, dbo.CreateTokenParams{User: "alice", Hash: key.Hash(), KeyID: &kid, ProjectID: project.ID})
+	require.NoError(t, err)
+
+	events := make(chanNotifier)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		plumbing.ListenChanges(ctx, events, keys)
+	}()
+
+	events <- dbo.TokenEvent{ID: token.ID}
+	assert.Eventually(t, func() bool {
+		_, ok := keys.FindByKey(key)
+		return ok
+	}, time.Second, 10*time.Millisecond, "changed token is loaded")
+
+	events <- dbo.TokenEvent{ID: token.ID, Removed: true}
+	assert.Eventually(t, func() bool {
+		_, ok := keys.FindByKey(key)
+		return !ok
+	}, time.Second, 10*time.Millisecond, "removed token is dropped")
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,76 @@
+package plumbing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/reddec/token-login/internal/dbo"
+)
+
+const webhookTimeout = 10 * time.Second
+
+var errWebhookStatus = errors.New("unexpected webhook status")
+
+// DisabledEvent is posted to the webhook when a token is disabled.
+type DisabledEvent struct {
+	Event  string    `json:"event"`
+	ID     int64     `json:"id"`
+	User   string    `json:"user"`
+	KeyID  string    `json:"keyID,omitempty"`
+	Label  string    `json:"label"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// DisabledWebhook returns handler of disabled tokens, which posts DisabledEvent as JSON to the URL,
+// for example to notify the owner about a leaked key. Events are delivered in background once,
+// and failures are only logged.
+func DisabledWebhook(ctx context.Context, url string) func(token *dbo.Token, reason string) {
+	return func(token *dbo.Token, reason string) {
+		event := DisabledEvent{
+			Event:  "token.disabled",
+			ID:     token.ID,
+			User:   token.User,
+			Label:  token.Label,
+			Reason: reason,
+			At:     time.Now().UTC(),
+		}
+		if token.KeyID != nil {
+			event.KeyID = token.KeyID.String()
+		}
+		go func() {
+			if err := postEvent(ctx, url, event); err != nil {
+				slog.Error("failed deliver disabled token event", "id", event.ID, "error", err)
+			}
+		}()
+	}
+}
+
+func postEvent(ctx context.Context, url string, event any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post event: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 { //nolint:mnd
+		return fmt.Errorf("post event: %w: %s", errWebhookStatus, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package plumbing_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/plumbing"
+	"github.com/reddec/token-login/internal/types"
+)
+
+func TestDisabledWebhook(t *testing.T) {
+	events := make(chan plumbing.DisabledEvent, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event plumbing.DisabledEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hook.Close()
+
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	handler := plumbing.DisabledWebhook(context.Background(), hook.URL)
+	handler(&dbo.Token{ID: 7, User: "alice", KeyID: &kid, Label: "ci"}, "leaked: content")
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "token.disabled", event.Event)
+		assert.Equal(t, int64(7), event.ID)
+		assert.Equal(t, "alice", event.User)
+		assert.Equal(t, kid.String(), event.KeyID)
+		assert.Equal(t, "ci", event.Label)
+		assert.Equal(t, "leaked: content", event.Reason)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "event is not delivered")
+	}
+}
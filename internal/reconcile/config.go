@@ -0,0 +1,122 @@
+// Package reconcile converges projects and tokens in the store to a declarative config,
+// so token configuration can be reviewed and versioned like code.
+package reconcile
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/reddec/token-login/internal/transfer"
+	"github.com/reddec/token-login/internal/types"
+)
+
+// Version of the config format.
+const Version = 1
+
+var (
+	ErrVersion      = errors.New("unsupported config version")
+	errNoUser       = errors.New("user is required")
+	errNoSecret     = errors.New("either hash or keyEnv is required")
+	errBothSecrets  = errors.New("hash and keyEnv are mutually exclusive")
+	errNoEnv        = errors.New("environment variable is not set")
+	errKeyIDMissing = errors.New("key ID (or key prefix for imported keys) is required with hash")
+	errKeyIDChanged = errors.New("key ID doesn't match the key")
+	errSlugIsAlias  = errors.New("slug is an alias of another project; rename the project or remove the alias first")
+	errForeignKey   = errors.New("key ID is used by another user")
+)
+
+// Config lists all projects and tokens of the users mentioned in it. Projects and tokens
+// of these users which are not listed are deleted, except default projects.
+type Config struct {
+	Version  int                `json:"version"`
+	Projects []transfer.Project `json:"projects"`
+	Tokens   []Token            `json:"tokens"`
+}
+
+// Token config with its secret: either hash (with key ID or key prefix) or
+// the raw key from environment variable.
+type Token struct {
+	transfer.Token
+	// KeyEnv is the name of environment variable with the raw key.
+	KeyEnv string `json:"keyEnv,omitempty"`
+}
+
+// Load config from YAML or JSON.
+func Load(r io.Reader) (*Config, error) {
+	var cfg Config
+	// JSON is valid YAML
+	if err := transfer.Unmarshal(r, transfer.YAML, &cfg); err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Version != Version {
+		return nil, fmt.Errorf("%d: %w", cfg.Version, ErrVersion)
+	}
+	return &cfg, nil
+}
+
+// secret of declared token. Payload is known only for keys from environment.
+type secret struct {
+	kid     types.KeyID
+	hash    *transfer.Hash
+	payload []byte
+}
+
+// resolve key ID and hash of the token.
+func (t *Token) resolve(hasher *types.Hasher, lookupEnv func(string) (string, bool)) (*secret, error) {
+	switch {
+	case t.KeyEnv != "" && t.Hash != nil:
+		return nil, errBothSecrets
+	case t.KeyEnv != "":
+		raw, ok := lookupEnv(t.KeyEnv)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", t.KeyEnv, errNoEnv)
+		}
+		s := &secret{}
+		if t.KeyFormat == types.KeyImported {
+			if err := types.ValidateImportedKey(t.KeyPrefix, raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", t.KeyEnv, err)
+			}
+			s.kid = types.ImportedKeyID(t.KeyPrefix)
+			s.payload = []byte(raw)
+		} else {
+			key, err := types.ParseKey(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.KeyEnv, err)
+			}
+			s.kid = key.ID()
+			s.payload = key.Payload()
+		}
+		if t.KeyID != "" && t.KeyID != s.kid.String() {
+			return nil, errKeyIDChanged
+		}
+		spec := hasher.Preferred()
+		hash, err := hasher.Hash(spec, s.payload)
+		if err != nil {
+			return nil, fmt.Errorf("hash key: %w", err)
+		}
+		s.hash = &transfer.Hash{Algorithm: spec.Algorithm, PepperID: spec.PepperID, Value: hex.EncodeToString(hash)}
+		return s, nil
+	case t.Hash != nil:
+		s := &secret{hash: t.Hash}
+		switch {
+		case t.KeyFormat == types.KeyImported && t.KeyPrefix != "":
+			s.kid = types.ImportedKeyID(t.KeyPrefix)
+			if t.KeyID != "" && t.KeyID != s.kid.String() {
+				return nil, errKeyIDChanged
+			}
+		case t.KeyID != "":
+			kid, err := types.ParseKeyID(t.KeyID)
+			if err != nil {
+				return nil, fmt.Errorf("parse key ID: %w", err)
+			}
+			s.kid = kid
+		default:
+			return nil, errKeyIDMissing
+		}
+		return s, nil
+	default:
+		return nil, errNoSecret
+	}
+}
@@ -0,0 +1,359 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/transfer"
+	"github.com/reddec/token-login/internal/types"
+)
+
+// Options of planning.
+type Options struct {
+	// Hasher is used for keys from environment, DefaultHasher if not set.
+	Hasher *types.Hasher
+	// LookupEnv resolves keyEnv, os.LookupEnv if not set.
+	LookupEnv func(string) (string, bool)
+}
+
+// Action on a single project, alias or token.
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+// Change is a planned action. Fields lists changed fields of updated objects.
+type Change struct {
+	Action  Action   `json:"action"`
+	User    string   `json:"user"`
+	Project string   `json:"project,omitempty"`
+	Alias   string   `json:"alias,omitempty"`
+	KeyID   string   `json:"keyID,omitempty"`
+	Fields  []string `json:"fields,omitempty"`
+	id      int64
+}
+
+// Plan to converge the store to the config. Empty plan means the store is up to date.
+type Plan struct {
+	Projects []Change `json:"projects"`
+	Aliases  []Change `json:"aliases"`
+	Tokens   []Change `json:"tokens"`
+	// doc has created and updated projects and tokens.
+	doc *transfer.Document
+}
+
+// Empty reports whether nothing has to be changed.
+func (p *Plan) Empty() bool {
+	return len(p.Projects) == 0 && len(p.Aliases) == 0 && len(p.Tokens) == 0
+}
+
+// String renders the plan for humans: + create, ~ update, - delete.
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "no changes\n"
+	}
+	var sb strings.Builder
+	for _, c := range p.Projects {
+		fmt.Fprintf(&sb, "%s project %q of %q%s\n", c.Action.sign(), c.Project, c.User, c.fields())
+	}
+	for _, c := range p.Aliases {
+		fmt.Fprintf(&sb, "%s alias %q of project %q of %q\n", c.Action.sign(), c.Alias, c.Project, c.User)
+	}
+	for _, c := range p.Tokens {
+		fmt.Fprintf(&sb, "%s token %s of %q%s\n", c.Action.sign(), c.KeyID, c.User, c.fields())
+	}
+	return sb.String()
+}
+
+func (a Action) sign() string {
+	switch a {
+	case Create:
+		return "+"
+	case Update:
+		return "~"
+	default:
+		return "-"
+	}
+}
+
+func (c Change) fields() string {
+	if len(c.Fields) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(c.Fields, ", ") + ")"
+}
+
+type projectRef struct {
+	user string
+	slug string
+}
+
+// Diff the config against the store state. Nothing is changed.
+//
+//nolint:cyclop,gocognit,funlen // flat list of comparisons
+func Diff(ctx context.Context, store dbo.Store, cfg *Config, opts Options) (*Plan, error) {
+	if cfg.Version != Version {
+		return nil, fmt.Errorf("%d: %w", cfg.Version, ErrVersion)
+	}
+	if opts.Hasher == nil {
+		opts.Hasher = types.DefaultHasher
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+
+	projects, err := store.ListAllProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	tokens, err := store.ListAllTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	var errs []error
+	managed := make(map[string]bool)
+	for _, p := range cfg.Projects {
+		if p.User == "" {
+			errs = append(errs, fmt.Errorf("project %q: %w", p.Slug, errNoUser))
+		}
+		managed[p.User] = true
+	}
+	for _, t := range cfg.Tokens {
+		if t.User == "" {
+			errs = append(errs, fmt.Errorf("token %s: %w", t.KeyID, errNoUser))
+		}
+		managed[t.User] = true
+	}
+
+	bySlug := make(map[projectRef]*dbo.Project)
+	byAlias := make(map[projectRef]*dbo.Project)
+	for _, p := range projects {
+		bySlug[projectRef{p.User, p.Slug}] = p
+		for _, alias := range p.Aliases {
+			byAlias[projectRef{p.User, alias}] = p
+		}
+	}
+
+	plan := &Plan{doc: &transfer.Document{Version: transfer.Version}}
+	// ids of declared projects after apply; zero for new projects.
+	ids := make(map[projectRef]int64)
+	declared := make(map[int64]bool)
+	for _, p := range projects {
+		// tokens may refer to existing default projects without declaring them
+		if managed[p.User] && p.Slug == "" {
+			ids[projectRef{p.User, ""}] = p.ID
+		}
+	}
+	for _, p := range cfg.Projects {
+		ref := projectRef{p.User, p.Slug}
+		if owner := byAlias[ref]; owner != nil {
+			errs = append(errs, fmt.Errorf("project %q of %q (alias of %q): %w", p.Slug, p.User, owner.Slug, errSlugIsAlias))
+			continue
+		}
+		current := bySlug[ref]
+		ids[ref] = 0
+		for _, alias := range p.Aliases {
+			ids[projectRef{p.User, alias}] = 0
+		}
+		if current == nil {
+			plan.Projects = append(plan.Projects, Change{Action: Create, User: p.User, Project: p.Slug})
+			plan.doc.Projects = append(plan.doc.Projects, p)
+			continue
+		}
+		declared[current.ID] = true
+		ids[ref] = current.ID
+		for _, alias := range p.Aliases {
+			ids[projectRef{p.User, alias}] = current.ID
+		}
+
+		var fields []string
+		fields = appendIf(fields, "description", p.Description != current.Description)
+		fields = appendIf(fields, "hosts", !slices.Equal(p.Hosts, current.Hosts))
+		fields = appendIf(fields, "paths", !slices.Equal(p.Paths, current.Paths))
+		fields = appendIf(fields, "headers", !slices.Equal(p.Headers, current.Headers))
+		fields = appendIf(fields, "aliases", slices.ContainsFunc(p.Aliases, func(alias string) bool {
+			return !slices.Contains(current.Aliases, alias)
+		}))
+		for _, alias := range current.Aliases {
+			if !slices.Contains(p.Aliases, alias) {
+				plan.Aliases = append(plan.Aliases, Change{Action: Delete, User: p.User, Project: p.Slug, Alias: alias, id: current.ID})
+			}
+		}
+		if len(fields) > 0 {
+			plan.Projects = append(plan.Projects, Change{Action: Update, User: p.User, Project: p.Slug, Fields: fields, id: current.ID})
+			plan.doc.Projects = append(plan.doc.Projects, p)
+		}
+	}
+
+	byKeyID := make(map[types.KeyID]*dbo.Token, len(tokens))
+	for _, t := range tokens {
+		byKeyID[*t.KeyID] = t
+	}
+	keep := make(map[int64]bool)
+	for _, t := range cfg.Tokens {
+		s, err := t.resolve(opts.Hasher, opts.LookupEnv)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("token %s of %q: %w", t.KeyID, t.User, err))
+			continue
+		}
+		out := t.Token
+		out.KeyID = s.kid.String()
+		if out.KeyFormat == "" {
+			out.KeyFormat = types.KeyNative
+		}
+		current := byKeyID[s.kid]
+		if current == nil {
+			out.Hash = s.hash
+			plan.Tokens = append(plan.Tokens, Change{Action: Create, User: t.User, KeyID: out.KeyID})
+			plan.doc.Tokens = append(plan.doc.Tokens, out)
+			continue
+		}
+		if current.User != t.User {
+			errs = append(errs, fmt.Errorf("token %s of %q: %w", out.KeyID, t.User, errForeignKey))
+			continue
+		}
+		keep[current.ID] = true
+
+		var fields []string
+		fields = appendIf(fields, "label", t.Label != current.Label)
+		fields = appendIf(fields, "project", !sameProject(ids, projectRef{t.User, t.Project}, current.ProjectID))
+		fields = appendIf(fields, "linkedProjects", !sameLinked(ids, t.User, t.Project, t.LinkedProjects, current.LinkedProjects))
+		fields = appendIf(fields, "hosts", !slices.Equal(t.Hosts, current.Hosts))
+		fields = appendIf(fields, "paths", !slices.Equal(t.Paths, current.Paths))
+		fields = appendIf(fields, "headers", !slices.Equal(t.Headers, current.Headers))
+		fields = appendIf(fields, "meta", !maps.Equal(t.Meta, current.Meta))
+		if secretChanged(opts.Hasher, s, current) {
+			fields = append(fields, "secret")
+			out.Hash = s.hash
+		} else {
+			out.Hash = nil
+		}
+		if len(fields) > 0 {
+			plan.Tokens = append(plan.Tokens, Change{Action: Update, User: t.User, KeyID: out.KeyID, Fields: fields, id: current.ID})
+			plan.doc.Tokens = append(plan.doc.Tokens, out)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tokens {
+		if managed[t.User] && !keep[t.ID] {
+			plan.Tokens = append(plan.Tokens, Change{Action: Delete, User: t.User, KeyID: t.KeyID.String(), id: t.ID})
+		}
+	}
+	for _, p := range projects {
+		// default projects are created on demand, so they are never deleted
+		if managed[p.User] && p.Slug != "" && !declared[p.ID] {
+			plan.Projects = append(plan.Projects, Change{Action: Delete, User: p.User, Project: p.Slug, id: p.ID})
+		}
+	}
+	return plan, nil
+}
+
+// Apply the plan. Extra aliases are removed first, then projects and tokens are created
+// or updated, then undeclared tokens and projects are deleted. Apply is not atomic,
+// but re-running Diff and Apply after a failure converges the store.
+//
+// Running servers pick up the changes on the next cache sync.
+func Apply(ctx context.Context, store dbo.Store, plan *Plan, opts Options) error {
+	for _, c := range plan.Aliases {
+		if _, err := store.DeleteProjectAlias(ctx, c.User, c.id, c.Alias); err != nil {
+			return fmt.Errorf("delete alias %q of %q: %w", c.Alias, c.User, err)
+		}
+	}
+	if len(plan.doc.Projects) > 0 || len(plan.doc.Tokens) > 0 {
+		_, err := transfer.Import(ctx, store, plan.doc, transfer.ImportOptions{
+			Conflict: transfer.Overwrite,
+			Hasher:   opts.Hasher,
+		})
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+	}
+	for _, c := range plan.Tokens {
+		if c.Action != Delete {
+			continue
+		}
+		if _, err := store.DeleteToken(ctx, c.User, c.id); err != nil {
+			return fmt.Errorf("delete token %s of %q: %w", c.KeyID, c.User, err)
+		}
+	}
+	for _, c := range plan.Projects {
+		if c.Action != Delete {
+			continue
+		}
+		if _, err := store.DeleteProject(ctx, c.User, c.id); err != nil {
+			return fmt.Errorf("delete project %q of %q: %w", c.Project, c.User, err)
+		}
+	}
+	return nil
+}
+
+func appendIf(fields []string, name string, changed bool) []string {
+	if changed {
+		return append(fields, name)
+	}
+	return fields
+}
+
+// sameProject reports whether declared slug points to the project. New projects never match.
+func sameProject(ids map[projectRef]int64, ref projectRef, id int64) bool {
+	declared, ok := ids[ref]
+	return ok && declared == id
+}
+
+// sameLinked compares linked projects as sets, ignoring the main project like import does.
+func sameLinked(ids map[projectRef]int64, user, project string, slugs []string, current []dbo.ProjectRef) bool {
+	main := ids[projectRef{user, project}]
+	want := make(map[int64]bool)
+	for _, slug := range slugs {
+		id, ok := ids[projectRef{user, slug}]
+		if !ok || id == 0 {
+			return false
+		}
+		if id != main {
+			want[id] = true
+		}
+	}
+	if len(want) != len(current) {
+		return false
+	}
+	for _, ref := range current {
+		if !want[ref.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// secretChanged reports whether the stored hash doesn't match the declared secret.
+// Raw keys are verified, so upgraded hashes are not reverted; declared hashes are
+// compared only if made the same way as the stored one.
+func secretChanged(hasher *types.Hasher, s *secret, current *dbo.Token) bool {
+	if s.payload != nil {
+		return !hasher.Verify(current.HashSpec(), s.payload, current.Hash)
+	}
+	spec := types.HashSpec{Algorithm: s.hash.Algorithm, PepperID: s.hash.PepperID}
+	stored := current.HashSpec()
+	if stored.Algorithm == "" {
+		stored.Algorithm = types.HashSHA3
+	}
+	if spec != stored {
+		return false
+	}
+	hash, err := hex.DecodeString(s.hash.Value)
+	return err != nil || !bytes.Equal(hash, current.Hash)
+}
@@ -0,0 +1,178 @@
+package reconcile_test
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/open"
+	"github.com/reddec/token-login/internal/reconcile"
+	"github.com/reddec/token-login/internal/types"
+)
+
+const config = `
+version: 1
+projects:
+  - user: alice
+    slug: ""
+  - user: alice
+    slug: web
+    aliases: [www]
+    hosts: [web.example.com]
+tokens:
+  - user: alice
+    label: ci
+    project: web
+    linkedProjects: [""]
+    keyEnv: CI_KEY
+  - user: alice
+    label: legacy
+    project: www
+    keyFormat: imported
+    keyPrefix: sk_live_
+    hash:
+      algorithm: sha256
+      value: %HASH%
+`
+
+// updated config rotates the key, drops the alias, the linked project and the legacy token.
+const updated = `
+version: 1
+projects:
+  - user: alice
+    slug: ""
+  - user: alice
+    slug: web
+    description: site
+    hosts: [web.example.com]
+tokens:
+  - user: alice
+    label: deploy
+    project: web
+    keyEnv: CI_KEY
+`
+
+func openStore(t *testing.T) dbo.Store {
+	t.Helper()
+	store, err := open.Open(context.Background(), "sqlite://"+filepath.Join(t.TempDir(), "db.sqlite"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func load(t *testing.T, text string) *reconcile.Config {
+	t.Helper()
+	cfg, err := reconcile.Load(strings.NewReader(text))
+	require.NoError(t, err)
+	return cfg
+}
+
+// converge applies config and checks that the next plan is empty.
+func converge(t *testing.T, store dbo.Store, cfg *reconcile.Config, opts reconcile.Options) *reconcile.Plan {
+	t.Helper()
+	ctx := context.Background()
+	plan, err := reconcile.Diff(ctx, store, cfg, opts)
+	require.NoError(t, err)
+	require.NoError(t, reconcile.Apply(ctx, store, plan, opts))
+
+	next, err := reconcile.Diff(ctx, store, cfg, opts)
+	require.NoError(t, err)
+	assert.True(t, next.Empty(), next.String())
+	return plan
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	legacy := "sk_live_0123456789"
+	legacyHash, err := types.DefaultHasher.Hash(types.HashSpec{Algorithm: types.HashSHA256}, []byte(legacy))
+	require.NoError(t, err)
+
+	env := map[string]string{"CI_KEY": key.Format(types.DefaultKeyPrefix)}
+	opts := reconcile.Options{LookupEnv: func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}}
+	text := strings.ReplaceAll(config, "%HASH%", hex.EncodeToString(legacyHash))
+
+	// unmanaged user is never touched
+	bobProject, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "bob", Slug: "bob"})
+	require.NoError(t, err)
+
+	plan := converge(t, store, load(t, text), opts)
+	assert.Len(t, plan.Projects, 2)
+	assert.Len(t, plan.Tokens, 2)
+
+	token, err := store.FindTokenByKeyID(ctx, key.ID())
+	require.NoError(t, err)
+	assert.Equal(t, "ci", token.Label)
+	assert.Equal(t, "web", token.ProjectSlug)
+	assert.Equal(t, []string{"www"}, token.ProjectAliases)
+	require.Len(t, token.LinkedProjects, 1)
+	assert.True(t, types.DefaultHasher.Verify(token.HashSpec(), key.Payload(), token.Hash))
+
+	imported, err := store.FindTokenByKeyID(ctx, types.ImportedKeyID("sk_live_"))
+	require.NoError(t, err)
+	assert.Equal(t, types.KeyImported, imported.KeyFormat)
+	assert.Equal(t, token.ProjectID, imported.ProjectID, "alias resolves to the project")
+
+	t.Run("update and delete", func(t *testing.T) {
+		_, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: "manual"})
+		require.NoError(t, err)
+
+		rotated, err := types.NewKeyFor(key.ID())
+		require.NoError(t, err)
+		env["CI_KEY"] = rotated.Format(types.DefaultKeyPrefix)
+		plan := converge(t, store, load(t, updated), opts)
+		out := plan.String()
+		assert.Contains(t, out, `~ project "web" of "alice" (description)`)
+		assert.Contains(t, out, `- alias "www" of project "web" of "alice"`)
+		assert.Contains(t, out, `- project "manual" of "alice"`)
+		assert.Contains(t, out, "~ token "+key.ID().String()+` of "alice" (label, linkedProjects, secret)`)
+		assert.Contains(t, out, "- token "+imported.KeyID.String())
+
+		token, err := store.FindTokenByKeyID(ctx, key.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "deploy", token.Label)
+		assert.Empty(t, token.LinkedProjects)
+		assert.True(t, types.DefaultHasher.Verify(token.HashSpec(), rotated.Payload(), token.Hash))
+
+		_, err = store.FindTokenByKeyID(ctx, *imported.KeyID)
+		require.ErrorIs(t, err, dbo.ErrNotFound)
+
+		bob, err := store.GetProject(ctx, "bob", bobProject.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", bob.Slug)
+	})
+}
+
+func TestDiffErrors(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+
+	_, err := reconcile.Diff(ctx, store, load(t, `
+version: 1
+tokens:
+  - user: alice
+    project: ""
+    keyEnv: MISSING
+  - user: alice
+    project: ""
+    keyID: AAAAAAAAAAAAA
+`), reconcile.Options{LookupEnv: func(string) (string, bool) { return "", false }})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MISSING")
+	assert.Contains(t, err.Error(), "either hash or keyEnv is required")
+
+	_, err = reconcile.Load(strings.NewReader(`{"version": 2}`))
+	require.ErrorIs(t, err, reconcile.ErrVersion)
+}
@@ -0,0 +1,121 @@
+// Package redisbus publishes token changes to all instances using Redis pub/sub.
+package redisbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/reddec/token-login/internal/dbo"
+)
+
+// pingInterval keeps idle subscription alive and detects lost connections.
+const pingInterval = 30 * time.Second
+
+// New creates a bus on the channel from a redigo pool. Every bus has own identity,
+// and events published by it are not delivered back to it.
+func New(pool *redis.Pool, channel string) *Bus {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return &Bus{pool: pool, channel: channel, source: hex.EncodeToString(id[:])}
+}
+
+// Bus implements dbo.Notifier using Redis pub/sub. Unlike database notifications, events
+// are published after the changes are committed, so they are lost if the instance fails in between.
+type Bus struct {
+	pool    *redis.Pool
+	channel string
+	source  string
+}
+
+type message struct {
+	ID      int64  `json:"id"`
+	Removed bool   `json:"removed,omitempty"`
+	Source  string `json:"source"`
+}
+
+// Publish sends the event to all other instances listening the channel.
+func (b *Bus) Publish(ctx context.Context, event dbo.TokenEvent) error {
+	data, err := json.Marshal(message{ID: event.ID, Removed: event.Removed, Source: b.source})
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("get redis conn: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := redis.DoContext(conn, ctx, "PUBLISH", b.channel, data); err != nil {
+		return fmt.Errorf("redis PUBLISH: %w", err)
+	}
+	return nil
+}
+
+// Listen calls fn for every event published by other instances until the context is canceled
+// or the connection is lost. The subscription holds one connection of the pool.
+func (b *Bus) Listen(ctx context.Context, fn func(dbo.TokenEvent)) error {
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("get redis conn: %w", err)
+	}
+	defer conn.Close()
+
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(b.channel); err != nil {
+		return fmt.Errorf("redis SUBSCRIBE: %w", err)
+	}
+
+	// commands are sent from single goroutine, while replies are received in this one
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				_ = psc.Unsubscribe()
+				return
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		switch v := psc.ReceiveWithTimeout(2 * pingInterval).(type) {
+		case error:
+			if ctx.Err() != nil {
+				return ctx.Err() //nolint:wrapcheck // cancellation is not an error of the bus
+			}
+			return fmt.Errorf("redis receive: %w", v)
+		case redis.Subscription:
+			if v.Count == 0 {
+				// unsubscribed on cancel
+				return ctx.Err() //nolint:wrapcheck // cancellation is not an error of the bus
+			}
+		case redis.Message:
+			var msg message
+			if err := json.Unmarshal(v.Data, &msg); err != nil {
+				slog.Warn("invalid token change event", "channel", v.Channel, "error", err)
+				continue
+			}
+			if msg.Source == b.source {
+				// already applied by the publisher
+				continue
+			}
+			fn(dbo.TokenEvent{ID: msg.ID, Removed: msg.Removed})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package redisbus_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/redisbus"
+)
+
+func redisPool(t *testing.T) *redis.Pool {
+	t.Helper()
+	ctx := context.Background()
+	ctr, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:7",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Skipf("redis container unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+	host, err := ctr.Host(ctx)
+	require.NoError(t, err)
+	port, err := ctr.MappedPort(ctx, "6379/tcp")
+	require.NoError(t, err)
+	addr := net.JoinHostPort(host, port.Port())
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		},
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	return pool
+}
+
+func TestBus(t *testing.T) {
+	pool := redisPool(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := redisbus.New(pool, "tokens")
+	second := redisbus.New(pool, "tokens")
+	other := redisbus.New(pool, "other")
+
+	listen := func(bus *redisbus.Bus) (<-chan dbo.TokenEvent, <-chan error) {
+		events := make(chan dbo.TokenEvent, 16)
+		done := make(chan error, 1)
+		go func() {
+			done <- bus.Listen(ctx, func(event dbo.TokenEvent) { events <- event })
+		}()
+		return events, done
+	}
+	firstEvents, firstDone := listen(first)
+	secondEvents, secondDone := listen(second)
+	otherEvents, otherDone := listen(other)
+	// subscription is asynchronous
+	time.Sleep(500 * time.Millisecond)
+
+	require.NoError(t, first.Publish(ctx, dbo.TokenEvent{ID: 1}))
+	require.NoError(t, first.Publish(ctx, dbo.TokenEvent{ID: 2, Removed: true}))
+
+	for _, expected := range []dbo.TokenEvent{{ID: 1}, {ID: 2, Removed: true}} {
+		select {
+		case event := <-secondEvents:
+			assert.Equal(t, expected, event)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no event")
+		}
+	}
+
+	cancel()
+	require.ErrorIs(t, <-firstDone, context.Canceled)
+	require.ErrorIs(t, <-secondDone, context.Canceled)
+	require.ErrorIs(t, <-otherDone, context.Canceled)
+	assert.Empty(t, firstEvents, "own events are not delivered")
+	assert.Empty(t, otherEvents, "events of other channels are not delivered")
+}
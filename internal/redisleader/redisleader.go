@@ -0,0 +1,149 @@
+// Package redisleader elects a single instance to run a job, using a lock with TTL in Redis.
+package redisleader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+//nolint:gochecknoglobals // scripts are immutable
+var (
+	// renewScript prolongs the lock if it's still held by the instance.
+	renewScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+
+	// releaseScript removes the lock if it's still held by the instance.
+	releaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+)
+
+// New creates election of the lock key from a redigo pool. Leader must renew the lock within TTL,
+// which is also the longest time without a leader after the previous one has gone.
+func New(pool *redis.Pool, key string, ttl time.Duration) *Election {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return &Election{pool: pool, key: key, ttl: ttl, id: hex.EncodeToString(id[:])}
+}
+
+// Election of the leader among instances sharing the lock key.
+type Election struct {
+	pool *redis.Pool
+	key  string
+	ttl  time.Duration
+	id   string
+}
+
+// Run campaigns for leadership until the context is canceled, and calls fn every time the instance is elected.
+// The context of fn is canceled when the leadership is lost, and the lock is released when fn returns.
+// Exclusive leadership is not guaranteed: fn must stop soon after cancel, before the lock expires.
+func (e *Election) Run(ctx context.Context, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		elected, err := e.acquire(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			slog.Error("failed acquire leadership", "key", e.key, "error", err)
+		case elected:
+			slog.Info("elected as leader", "key", e.key)
+			e.lead(ctx, fn)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Election) lead(ctx context.Context, fn func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			if err := e.release(); err != nil {
+				slog.Error("failed release leadership", "key", e.key, "error", err)
+			}
+			return
+		case <-ticker.C:
+			renewed, err := e.renew(ctx)
+			if ctx.Err() != nil {
+				// stopping: fn returns soon, and the lock is released
+				continue
+			}
+			if err != nil || !renewed {
+				slog.Warn("leadership lost", "key", e.key, "error", err)
+				cancel()
+				<-done
+				return
+			}
+		}
+	}
+}
+
+func (e *Election) acquire(ctx context.Context) (bool, error) {
+	conn, err := e.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get redis conn: %w", err)
+	}
+	defer conn.Close()
+
+	_, err = redis.String(redis.DoContext(conn, ctx, "SET", e.key, e.id, "NX", "PX", e.ttl.Milliseconds()))
+	if errors.Is(err, redis.ErrNil) {
+		// held by another instance
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("redis SET: %w", err)
+	}
+	return true, nil
+}
+
+func (e *Election) renew(ctx context.Context) (bool, error) {
+	conn, err := e.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get redis conn: %w", err)
+	}
+	defer conn.Close()
+
+	ok, err := redis.Bool(renewScript.DoContext(ctx, conn, e.key, e.id, e.ttl.Milliseconds()))
+	if err != nil {
+		return false, fmt.Errorf("renew lock: %w", err)
+	}
+	return ok, nil
+}
+
+func (e *Election) release() error {
+	// the instance may be stopping, so the lock is released regardless of the context
+	conn := e.pool.Get()
+	defer conn.Close()
+
+	if _, err := releaseScript.Do(conn, e.key, e.id); err != nil {
+		return fmt.Errorf("release lock: %w", err)
+	}
+	return nil
+}
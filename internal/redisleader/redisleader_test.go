@@ -0,0 +1,100 @@
+package redisleader_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/reddec/token-login/internal/redisleader"
+)
+
+func redisPool(t *testing.T) *redis.Pool {
+	t.Helper()
+	ctx := context.Background()
+	ctr, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:7",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Skipf("redis container unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+	host, err := ctr.Host(ctx)
+	require.NoError(t, err)
+	port, err := ctr.MappedPort(ctx, "6379/tcp")
+	require.NoError(t, err)
+	addr := net.JoinHostPort(host, port.Port())
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		},
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	return pool
+}
+
+func TestElection(t *testing.T) {
+	pool := redisPool(t)
+	const key = "test:leader"
+	events := make(chan string, 16)
+	start := func(name string) (context.CancelFunc, <-chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		election := redisleader.New(pool, key, 300*time.Millisecond)
+		go func() {
+			defer close(done)
+			election.Run(ctx, func(ctx context.Context) {
+				events <- name
+				<-ctx.Done()
+				events <- name + " stopped"
+			})
+		}()
+		return cancel, done
+	}
+	next := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(3 * time.Second):
+			require.FailNow(t, "no event")
+			return ""
+		}
+	}
+
+	stopFirst, firstDone := start("first")
+	assert.Equal(t, "first", next())
+	stopSecond, secondDone := start("second")
+	defer func() {
+		stopSecond()
+		<-secondDone
+	}()
+	select {
+	case event := <-events:
+		require.FailNow(t, "unexpected event", event)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// stopped leader releases the lock
+	stopFirst()
+	<-firstDone
+	assert.Equal(t, "first stopped", next())
+	assert.Equal(t, "second", next())
+
+	// leader stops when the lock is taken over
+	conn := pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("SET", key, "other", "PX", 1000)
+	require.NoError(t, err)
+	assert.Equal(t, "second stopped", next())
+}
@@ -0,0 +1,144 @@
+// Package redisstats aggregates usage stats of all instances in Redis, so they are written
+// to the database by a single instance.
+package redisstats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/reddec/token-login/internal/dbo"
+)
+
+//nolint:gochecknoglobals // scripts are immutable
+var (
+	// addScript adds hits and keeps the latest access time (unix milliseconds) of every token.
+	// KEYS: hits, last. ARGV: triplets of token ID, hits, last access.
+	addScript = redis.NewScript(2, `
+for i = 1, #ARGV, 3 do
+	redis.call('HINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+	local last = tonumber(redis.call('HGET', KEYS[2], ARGV[i]) or '0')
+	if tonumber(ARGV[i + 2]) > last then
+		redis.call('HSET', KEYS[2], ARGV[i], ARGV[i + 2])
+	end
+end
+return 0`)
+
+	// takeScript moves stats to pending keys, unless pending stats of a failed flush are left,
+	// and returns pending hits and last access times.
+	// KEYS: hits, last, pending hits, pending last.
+	takeScript = redis.NewScript(4, `
+if redis.call('EXISTS', KEYS[3]) == 0 and redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('RENAME', KEYS[1], KEYS[3])
+	if redis.call('EXISTS', KEYS[2]) == 1 then
+		redis.call('RENAME', KEYS[2], KEYS[4])
+	end
+end
+return {redis.call('HGETALL', KEYS[3]), redis.call('HGETALL', KEYS[4])}`)
+)
+
+// New creates stats buffer with keys of the prefix from a redigo pool.
+func New(pool *redis.Pool, prefix string) *Stats {
+	return &Stats{
+		pool:        pool,
+		hits:        prefix + "hits",
+		last:        prefix + "last",
+		pendingHits: prefix + "hits:pending",
+		pendingLast: prefix + "last:pending",
+	}
+}
+
+// Stats is a shared buffer of usage stats. Every instance adds its stats by UpdateStats,
+// and only one of them writes all stats to the database by Flush.
+type Stats struct {
+	pool        *redis.Pool
+	hits        string
+	last        string
+	pendingHits string
+	pendingLast string
+}
+
+// UpdateStats atomically adds stats to the buffer.
+func (s *Stats) UpdateStats(ctx context.Context, stats map[int64]dbo.StatsEntry) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	args := make([]any, 0, 2+3*len(stats))
+	args = append(args, s.hits, s.last)
+	for id, entry := range stats {
+		args = append(args, id, entry.Hits, entry.Last.UnixMilli())
+	}
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("get redis conn: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := addScript.DoContext(ctx, conn, args...); err != nil {
+		return fmt.Errorf("add stats: %w", err)
+	}
+	return nil
+}
+
+// Flush writes buffered stats to the database. Stats are removed from the buffer only after they are written,
+// and failed flush is retried by the next one, so stats are not lost (but may be counted twice if the removal fails).
+func (s *Stats) Flush(ctx context.Context, store dbo.Store) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("get redis conn: %w", err)
+	}
+	defer conn.Close()
+
+	reply, err := redis.Values(takeScript.DoContext(ctx, conn, s.hits, s.last, s.pendingHits, s.pendingLast))
+	if err != nil {
+		return fmt.Errorf("take stats: %w", err)
+	}
+	hits, err := redis.Int64Map(reply[0], nil)
+	if err != nil {
+		return fmt.Errorf("parse hits: %w", err)
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+	last, err := redis.Int64Map(reply[1], nil)
+	if err != nil {
+		return fmt.Errorf("parse last access: %w", err)
+	}
+	stats := make(map[int64]dbo.StatsEntry, len(hits))
+	for key, n := range hits {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			slog.Warn("invalid token ID in stats", "id", key)
+			continue
+		}
+		stats[id] = dbo.StatsEntry{Hits: n, Last: time.UnixMilli(last[key])}
+	}
+	if err := store.UpdateStats(ctx, stats); err != nil {
+		return fmt.Errorf("update stats: %w", err)
+	}
+	if _, err := redis.DoContext(conn, ctx, "DEL", s.pendingHits, s.pendingLast); err != nil {
+		return fmt.Errorf("redis DEL: %w", err)
+	}
+	return nil
+}
+
+// RunFlush writes buffered stats to the database every interval, until the context is canceled.
+func (s *Stats) RunFlush(ctx context.Context, store dbo.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(ctx, store); err != nil {
+				slog.Error("failed dump shared stats to database", "error", err)
+			}
+		}
+	}
+}
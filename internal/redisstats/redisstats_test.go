@@ -0,0 +1,119 @@
+package redisstats_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/memory"
+	"github.com/reddec/token-login/internal/redisstats"
+	"github.com/reddec/token-login/internal/types"
+)
+
+func redisPool(t *testing.T) *redis.Pool {
+	t.Helper()
+	ctx := context.Background()
+	ctr, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:7",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Skipf("redis container unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = ctr.Terminate(ctx) })
+	host, err := ctr.Host(ctx)
+	require.NoError(t, err)
+	port, err := ctr.MappedPort(ctx, "6379/tcp")
+	require.NoError(t, err)
+	addr := net.JoinHostPort(host, port.Port())
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		},
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	return pool
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	pool := redisPool(t)
+	store := memory.NewStore()
+	project, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: ""})
+	require.NoError(t, err)
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	token, err := store.CreateToken(ctx, dbo.CreateTokenParams{User: "alice", Hash: key.Hash(), KeyID: &kid, ProjectID: project.ID})
+	require.NoError(t, err)
+
+	first := redisstats.New(pool, "test:stats:")
+	second := redisstats.New(pool, "test:stats:")
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, first.UpdateStats(ctx, map[int64]dbo.StatsEntry{token.ID: {Hits: 2, Last: now}}))
+	require.NoError(t, second.UpdateStats(ctx, map[int64]dbo.StatsEntry{token.ID: {Hits: 3, Last: now.Add(-time.Minute)}}))
+
+	require.NoError(t, first.Flush(ctx, store))
+	got, err := store.GetTokenByID(ctx, token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), got.Requests)
+	assert.True(t, now.Equal(got.LastAccessAt), "latest access is kept")
+
+	// flushed stats are not written again
+	require.NoError(t, second.Flush(ctx, store))
+	got, err = store.GetTokenByID(ctx, token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), got.Requests)
+}
+
+type failingStore struct {
+	dbo.Store
+}
+
+func (failingStore) UpdateStats(context.Context, map[int64]dbo.StatsEntry) error {
+	return errors.New("unavailable")
+}
+
+func TestStatsRetry(t *testing.T) {
+	ctx := context.Background()
+	pool := redisPool(t)
+	store := memory.NewStore()
+	project, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: ""})
+	require.NoError(t, err)
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	token, err := store.CreateToken(ctx, dbo.CreateTokenParams{User: "alice", Hash: key.Hash(), KeyID: &kid, ProjectID: project.ID})
+	require.NoError(t, err)
+	requests := func() int64 {
+		got, err := store.GetTokenByID(ctx, token.ID)
+		require.NoError(t, err)
+		return got.Requests
+	}
+
+	stats := redisstats.New(pool, "test:retry:")
+	require.NoError(t, stats.UpdateStats(ctx, map[int64]dbo.StatsEntry{token.ID: {Hits: 1, Last: time.Now()}}))
+	require.Error(t, stats.Flush(ctx, failingStore{store}))
+	require.NoError(t, stats.UpdateStats(ctx, map[int64]dbo.StatsEntry{token.ID: {Hits: 2, Last: time.Now()}}))
+
+	// stats of failed flush are written first
+	require.NoError(t, stats.Flush(ctx, store))
+	assert.Equal(t, int64(1), requests())
+	require.NoError(t, stats.Flush(ctx, store))
+	assert.Equal(t, int64(3), requests())
+	require.NoError(t, stats.Flush(ctx, store))
+	assert.Equal(t, int64(3), requests())
+}
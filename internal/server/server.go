@@ -2,8 +2,15 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/reddec/token-login/api"
 	"github.com/reddec/token-login/internal/dbo"
@@ -16,21 +23,69 @@ var (
 	errUnknownToken        = errors.New("unknown token")
 	errUnknownProject      = errors.New("unknown project")
 	errCannotDeleteDefault = errors.New("cannot delete default project")
+	errCannotRenameDefault = errors.New("cannot rename default project")
+	errInvalidMetaFilter   = errors.New("invalid meta filter, expected key=value")
+	errInvalidCursor       = errors.New("invalid cursor")
+	errKeyCollision        = errors.New("failed to generate unique key ID")
+	errKeyExists           = errors.New("key ID already in use")
+	errImportKeyOrHash     = errors.New("either key or hash should be set")
+	errImportHashSize      = errors.New("hash size doesn't match algorithm")
 )
 
 type (
-	UpdateHandler func(id int)
-	RemoveHandler func(id int)
+	UpdateHandler  func(id int)
+	RemoveHandler  func(id int)
+	DisableHandler func(token *dbo.Token, reason string)
 )
 
-func New(store dbo.Store) *Server {
-	return &Server{store: store}
+const (
+	maxDisableReason = 255
+	maxKeyAttempts   = 5
+)
+
+// Option customizes Server.
+type Option func(srv *Server)
+
+// WithKeyPrefix sets prefix of issued keys (types.DefaultKeyPrefix by default).
+// Keys with any prefix are accepted, so the prefix can be changed at any time.
+func WithKeyPrefix(prefix string) Option {
+	return func(srv *Server) {
+		srv.keyPrefix = prefix
+	}
+}
+
+// WithHasher sets hasher for new keys (types.DefaultHasher by default).
+// It must be the same as used by the cache.
+func WithHasher(hasher *types.Hasher) Option {
+	return func(srv *Server) {
+		srv.hasher = hasher
+	}
+}
+
+// WithReader sets store for listings and export, for example a read replica (the main store by default).
+// Changes and reads needed to make them always use the main store.
+func WithReader(store dbo.Store) Option {
+	return func(srv *Server) {
+		srv.reader = store
+	}
+}
+
+func New(store dbo.Store, opts ...Option) *Server {
+	srv := &Server{store: store, reader: store, keyPrefix: types.DefaultKeyPrefix, hasher: types.DefaultHasher}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
 }
 
 type Server struct {
-	store    dbo.Store
-	onUpdate []UpdateHandler
-	onRemove []RemoveHandler
+	store     dbo.Store
+	reader    dbo.Store
+	keyPrefix string
+	hasher    *types.Hasher
+	onUpdate  []UpdateHandler
+	onRemove  []RemoveHandler
+	onDisable []DisableHandler
 }
 
 func (srv *Server) OnUpdate(fn UpdateHandler) {
@@ -41,20 +96,183 @@ func (srv *Server) OnRemove(fn RemoveHandler) {
 	srv.onRemove = append(srv.onRemove, fn)
 }
 
+// OnDisable registers handler called after a token was disabled, for example
+// to notify the owner about a leaked key.
+func (srv *Server) OnDisable(fn DisableHandler) {
+	srv.onDisable = append(srv.onDisable, fn)
+}
+
+// ReportLeak disables the token of a publicly exposed key. It reports whether the
+// key belongs to a token: keys which are unknown or don't match the stored hash are ignored.
+// The call is not scoped by user, since the reporter is usually not the owner.
+func (srv *Server) ReportLeak(ctx context.Context, key types.Key, source string) (bool, error) {
+	t, err := srv.store.FindTokenByKeyID(ctx, key.ID())
+	if errors.Is(err, dbo.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("find token: %w", err)
+	}
+	if !srv.hasher.Verify(t.HashSpec(), key.Payload(), t.Hash) {
+		return false, nil
+	}
+	reason := "leaked"
+	if source != "" {
+		reason += ": " + source
+	}
+	if len(reason) > maxDisableReason {
+		reason = reason[:maxDisableReason]
+	}
+	changed, err := srv.store.DisableToken(ctx, t.ID, reason, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("disable token: %w", err)
+	}
+	if changed == 0 {
+		// already disabled
+		return true, nil
+	}
+	srv.notifyRemoved(int(t.ID))
+	srv.notifyDisabled(t, reason)
+	return true, nil
+}
+
 func (srv *Server) CreateToken(ctx context.Context, req *api.TokenConfig) (*api.Credential, error) {
-	key, err := types.NewKey()
+	key, err := srv.newKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := srv.hasher.Preferred()
+	hash, err := srv.hasher.Hash(spec, key.Payload())
 	if err != nil {
-		return nil, fmt.Errorf("generate key: %w", err)
+		return nil, fmt.Errorf("hash key: %w", err)
 	}
 
+	t, err := srv.createToken(ctx, req, secret{
+		keyID:  key.ID(),
+		hash:   hash,
+		spec:   spec,
+		format: types.KeyNative,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.Credential{
+		ID:  int(t.ID),
+		Key: key.Format(srv.keyPrefix),
+	}, nil
+}
+
+// ImportTokens creates tokens for existing keys. Keys are imported independently:
+// a rejected key is reported in its result and doesn't affect others.
+func (srv *Server) ImportTokens(ctx context.Context, req []api.ImportKey) ([]api.ImportResult, error) {
+	out := make([]api.ImportResult, 0, len(req))
+	for i := range req {
+		item := &req[i]
+		var res api.ImportResult
+		id, kid, err := srv.importToken(ctx, item)
+		if kid != nil {
+			res.KeyID = kid.String()
+		}
+		if err != nil {
+			res.Error = api.NewOptString(err.Error())
+		} else {
+			res.ID = api.NewOptInt(int(id))
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// importToken creates token for the existing key. Key ID is returned whenever it's known, even on failure.
+func (srv *Server) importToken(ctx context.Context, item *api.ImportKey) (int64, *types.KeyID, error) {
+	s, err := srv.importedSecret(item)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = srv.store.FindTokenByKeyID(ctx, s.keyID)
+	if err == nil {
+		return 0, &s.keyID, errKeyExists
+	}
+	if !errors.Is(err, dbo.ErrNotFound) {
+		return 0, &s.keyID, fmt.Errorf("check key ID: %w", err)
+	}
+	t, err := srv.createToken(ctx, &item.Config, s)
+	if err != nil {
+		return 0, &s.keyID, err
+	}
+	return t.ID, &s.keyID, nil
+}
+
+// importedSecret derives key ID and hash of the existing key.
+// Raw native keys are imported as is, anything else needs public prefix.
+func (srv *Server) importedSecret(item *api.ImportKey) (secret, error) {
+	raw, hasRaw := item.Key.Get()
+	hexHash, hasHash := item.Hash.Get()
+	prefix, hasPrefix := item.KeyPrefix.Get()
+	if hasRaw == hasHash {
+		return secret{}, errImportKeyOrHash
+	}
+
+	if hasRaw && !hasPrefix {
+		key, err := types.ParseKey(raw)
+		if err != nil {
+			return secret{}, fmt.Errorf("parse key (set key prefix for non-native keys): %w", err)
+		}
+		spec := srv.hasher.Preferred()
+		hash, err := srv.hasher.Hash(spec, key.Payload())
+		if err != nil {
+			return secret{}, fmt.Errorf("hash key: %w", err)
+		}
+		return secret{keyID: key.ID(), hash: hash, spec: spec, format: types.KeyNative}, nil
+	}
+
+	if hasRaw {
+		if err := types.ValidateImportedKey(prefix, raw); err != nil {
+			return secret{}, err
+		}
+		spec := srv.hasher.Preferred()
+		hash, err := srv.hasher.Hash(spec, []byte(raw))
+		if err != nil {
+			return secret{}, fmt.Errorf("hash key: %w", err)
+		}
+		return secret{keyID: types.ImportedKeyID(prefix), hash: hash, spec: spec, format: types.KeyImported, prefix: prefix}, nil
+	}
+
+	if err := types.ValidateImportedPrefix(prefix); err != nil {
+		return secret{}, err
+	}
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil {
+		return secret{}, fmt.Errorf("decode hash: %w", err)
+	}
+	spec := types.HashSpec{Algorithm: types.HashAlgorithm(item.HashAlgorithm.Or(api.ImportKeyHashAlgorithmSHA256))}
+	if len(hash) != spec.Algorithm.Size() {
+		return secret{}, errImportHashSize
+	}
+	return secret{keyID: types.ImportedKeyID(prefix), hash: hash, spec: spec, format: types.KeyImported, prefix: prefix}, nil
+}
+
+// secret is the stored part of the token key.
+type secret struct {
+	keyID  types.KeyID
+	hash   []byte
+	spec   types.HashSpec
+	format types.KeyFormat
+	prefix string
+}
+
+func (srv *Server) createToken(ctx context.Context, req *api.TokenConfig, s secret) (*dbo.Token, error) {
 	headers := parseHeaders(req.Headers)
-	_, err = types.NewAccessKey(key.Hash(), req.Hosts, req.Paths)
+	_, err := types.NewAccessKey(nil, req.Hosts, req.Paths)
 	if err != nil {
 		return nil, fmt.Errorf("validate key: %w", err)
 	}
+	if err := types.ValidateHeaders(headers); err != nil {
+		return nil, fmt.Errorf("validate headers: %w", err)
+	}
 
 	user := utils.GetUser(ctx)
-	kid := key.ID()
 
 	if req.ProjectId != 0 {
 		exists, err := srv.store.ProjectExists(ctx, user, int64(req.ProjectId))
@@ -66,24 +284,31 @@ func (srv *Server) CreateToken(ctx context.Context, req *api.TokenConfig) (*api.
 		}
 	}
 
+	linked, err := srv.linkedProjects(ctx, user, int64(req.ProjectId), req.LinkedProjectIds)
+	if err != nil {
+		return nil, err
+	}
+
 	t, err := srv.store.CreateToken(ctx, dbo.CreateTokenParams{
-		User:      user,
-		Hash:      key.Hash(),
-		KeyID:     &kid,
-		ProjectID: int64(req.ProjectId),
-		Label:     req.Label.Value,
-		Headers:   headers,
-		Hosts:     req.Hosts,
-		Paths:     req.Paths,
+		User:             user,
+		Hash:             s.hash,
+		HashSpec:         s.spec,
+		KeyID:            &s.keyID,
+		KeyFormat:        s.format,
+		KeyPrefix:        s.prefix,
+		ProjectID:        int64(req.ProjectId),
+		LinkedProjectIDs: linked,
+		Meta:             types.Meta(req.Meta.Value),
+		Label:            req.Label.Value,
+		Headers:          headers,
+		Hosts:            req.Hosts,
+		Paths:            req.Paths,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create token: %w", err)
 	}
 	srv.notifyUpdated(int(t.ID))
-	return &api.Credential{
-		ID:  int(t.ID),
-		Key: key.String(),
-	}, nil
+	return t, nil
 }
 
 func (srv *Server) DeleteToken(ctx context.Context, params api.DeleteTokenParams) error {
@@ -105,30 +330,117 @@ func (srv *Server) GetToken(ctx context.Context, params api.GetTokenParams) (*ap
 	return mapToken(t), nil
 }
 
-func (srv *Server) ListTokens(ctx context.Context, params api.ListTokensParams) ([]api.Token, error) {
-	var projectID int64
-	if p, ok := params.Project.Get(); ok {
-		projectID = int64(p)
+func (srv *Server) GetTokenByKey(ctx context.Context, params api.GetTokenByKeyParams) (*api.Token, error) {
+	kid, err := types.ParseKeyID(params.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("parse key ID: %w", err)
 	}
-	list, err := srv.store.ListTokens(ctx, utils.GetUser(ctx), projectID)
+	t, err := srv.store.GetTokenByKeyID(ctx, utils.GetUser(ctx), kid)
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+	return mapToken(t), nil
+}
+
+func (srv *Server) IdentifyToken(ctx context.Context, req *api.KeyLookup) (*api.TokenIdentity, error) {
+	key, err := types.ParseKey(strings.TrimSpace(req.Key))
+	if err != nil {
+		return nil, fmt.Errorf("parse key: %w", err)
+	}
+	t, err := srv.store.GetTokenByKeyID(ctx, utils.GetUser(ctx), key.ID())
+	if errors.Is(err, dbo.ErrNotFound) {
+		// unknown key ID is not told apart from wrong secret
+		return nil, errUnknownToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+	if !srv.hasher.Verify(t.HashSpec(), key.Payload(), t.Hash) {
+		return nil, errUnknownToken
+	}
+	return &api.TokenIdentity{
+		ID:          int(t.ID),
+		KeyID:       t.KeyID.String(),
+		Label:       t.Label,
+		ProjectId:   int(t.ProjectID),
+		ProjectSlug: t.ProjectSlug,
+	}, nil
+}
+
+func (srv *Server) ListTokens(ctx context.Context, params api.ListTokensParams) (*api.ListTokensOKHeaders, error) {
+	p := dbo.ListTokensParams{
+		User:   utils.GetUser(ctx),
+		Search: params.Q.Value,
+		Host:   params.Host.Value,
+		Sort:   mapTokenSort(params.Sort.Or(api.ListTokensSortCreated)),
+		Asc:    params.Order.Or(api.ListTokensOrderDesc) == api.ListTokensOrderAsc,
+	}
+	if v, ok := params.Project.Get(); ok {
+		p.ProjectID = int64(v)
+	}
+	if len(params.Meta) > 0 {
+		p.Meta = make(types.Meta, len(params.Meta))
+		for _, pair := range params.Meta {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok || k == "" {
+				return nil, fmt.Errorf("parse %q: %w", pair, errInvalidMetaFilter)
+			}
+			p.Meta[k] = v
+		}
+	}
+	if v, ok := params.AccessedAfter.Get(); ok {
+		p.AccessedAfter = v
+	}
+	if v, ok := params.AccessedBefore.Get(); ok {
+		p.AccessedBefore = v
+	}
+	if v, ok := params.UnusedSince.Get(); ok {
+		p.UnusedSince = v
+	}
+	if v, ok := params.Disabled.Get(); ok {
+		p.Disabled = &v
+	}
+	if v, ok := params.Cursor.Get(); ok {
+		after, err := decodeCursor(v, p.Sort, p.Asc)
+		if err != nil {
+			return nil, err
+		}
+		p.After = after
+	}
+	limit := params.Limit.Or(0)
+	if limit > 0 {
+		p.Limit = limit + 1 // one extra to detect the next page
+	}
+
+	list, err := srv.reader.ListTokens(ctx, p)
 	if err != nil {
 		return nil, fmt.Errorf("list tokens: %w", err)
 	}
-	out := make([]api.Token, 0, len(list))
+	var res api.ListTokensOKHeaders
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+		res.XNextCursor = api.NewOptString(encodeCursor(list[limit-1].Cursor(), p.Sort, p.Asc))
+	}
+	res.Response = make([]api.Token, 0, len(list))
 	for _, t := range list {
-		out = append(out, *mapToken(t))
+		res.Response = append(res.Response, *mapToken(t))
 	}
-	return out, nil
+	return &res, nil
 }
 
 func (srv *Server) RefreshToken(ctx context.Context, params api.RefreshTokenParams) (*api.Credential, error) {
-	key, err := types.NewKey()
+	key, err := srv.newKey(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("generate key: %w", err)
+		return nil, err
 	}
 	kid := key.ID()
+	spec := srv.hasher.Preferred()
+	hash, err := srv.hasher.Hash(spec, key.Payload())
+	if err != nil {
+		return nil, fmt.Errorf("hash key: %w", err)
+	}
 
-	changed, err := srv.store.RefreshToken(ctx, utils.GetUser(ctx), int64(params.Token), key.Hash(), &kid)
+	changed, err := srv.store.RefreshToken(ctx, utils.GetUser(ctx), int64(params.Token), hash, spec, &kid)
 	if err != nil {
 		return nil, fmt.Errorf("update token: %w", err)
 	}
@@ -138,13 +450,14 @@ func (srv *Server) RefreshToken(ctx context.Context, params api.RefreshTokenPara
 	srv.notifyUpdated(params.Token)
 	return &api.Credential{
 		ID:  params.Token,
-		Key: key.String(),
+		Key: key.Format(srv.keyPrefix),
 	}, nil
 }
 
 func (srv *Server) UpdateToken(ctx context.Context, req *api.TokenPatch, params api.UpdateTokenParams) error {
+	user := utils.GetUser(ctx)
 	p := dbo.UpdateTokenParams{
-		User: utils.GetUser(ctx),
+		User: user,
 		ID:   int64(params.Token),
 	}
 	if req.Hosts != nil {
@@ -158,8 +471,46 @@ func (srv *Server) UpdateToken(ctx context.Context, req *api.TokenPatch, params
 	}
 	if req.Headers != nil {
 		h := parseHeaders(req.Headers)
+		if err := types.ValidateHeaders(h); err != nil {
+			return fmt.Errorf("validate headers: %w", err)
+		}
 		p.Headers = &h
 	}
+	if v, ok := req.Meta.Get(); ok {
+		meta := types.Meta(v)
+		p.Meta = &meta
+	}
+	if v, ok := req.ProjectId.Get(); ok {
+		exists, err := srv.store.ProjectExists(ctx, user, int64(v))
+		if err != nil {
+			return fmt.Errorf("check project: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("project %d not found: %w", v, errProjectNotFound)
+		}
+		projectID := int64(v)
+		p.ProjectID = &projectID
+	}
+	if req.LinkedProjectIds != nil {
+		var primary int64
+		if p.ProjectID != nil {
+			primary = *p.ProjectID
+		} else {
+			current, err := srv.store.GetToken(ctx, user, p.ID)
+			if errors.Is(err, dbo.ErrNotFound) {
+				return errUnknownToken
+			}
+			if err != nil {
+				return fmt.Errorf("get token: %w", err)
+			}
+			primary = current.ProjectID
+		}
+		linked, err := srv.linkedProjects(ctx, user, primary, req.LinkedProjectIds)
+		if err != nil {
+			return err
+		}
+		p.LinkedProjectIDs = &linked
+	}
 
 	changed, err := srv.store.UpdateToken(ctx, p)
 	if err != nil {
@@ -172,6 +523,46 @@ func (srv *Server) UpdateToken(ctx context.Context, req *api.TokenPatch, params
 	return nil
 }
 
+// linkedProjects validates ownership of additional projects and removes
+// duplicates and the token's own project from the list.
+func (srv *Server) linkedProjects(ctx context.Context, user string, primary int64, ids []int) ([]int64, error) {
+	out := make([]int64, 0, len(ids))
+	for _, v := range ids {
+		id := int64(v)
+		if id == primary || slices.Contains(out, id) {
+			continue
+		}
+		exists, err := srv.store.ProjectExists(ctx, user, id)
+		if err != nil {
+			return nil, fmt.Errorf("check project: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("project %d not found: %w", v, errProjectNotFound)
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+// newKey generates key with key ID which is not used by any token yet.
+func (srv *Server) newKey(ctx context.Context) (types.Key, error) {
+	for range maxKeyAttempts {
+		key, err := types.NewKey()
+		if err != nil {
+			return key, fmt.Errorf("generate key: %w", err)
+		}
+		_, err = srv.store.FindTokenByKeyID(ctx, key.ID())
+		if errors.Is(err, dbo.ErrNotFound) {
+			return key, nil
+		}
+		if err != nil {
+			return key, fmt.Errorf("check key ID: %w", err)
+		}
+		slog.Warn("key ID collision, regenerating", "key", key.ID())
+	}
+	return types.Key{}, errKeyCollision
+}
+
 func (srv *Server) notifyUpdated(id int) {
 	for _, h := range srv.onUpdate {
 		h(id)
@@ -184,8 +575,27 @@ func (srv *Server) notifyRemoved(id int) {
 	}
 }
 
+func (srv *Server) notifyDisabled(t *dbo.Token, reason string) {
+	for _, h := range srv.onDisable {
+		h(t, reason)
+	}
+}
+
+// notifyProjectUpdated re-syncs all tokens of the project, since every token
+// keeps a copy of its project slug, aliases and defaults.
+func (srv *Server) notifyProjectUpdated(ctx context.Context, user string, projectID int64) error {
+	tokenIDs, err := srv.store.ListProjectTokenIDs(ctx, user, projectID)
+	if err != nil {
+		return fmt.Errorf("list project tokens: %w", err)
+	}
+	for _, tid := range tokenIDs {
+		srv.notifyUpdated(int(tid))
+	}
+	return nil
+}
+
 func (srv *Server) ListProjects(ctx context.Context) ([]api.Project, error) {
-	list, err := srv.store.ListProjects(ctx, utils.GetUser(ctx))
+	list, err := srv.reader.ListProjects(ctx, utils.GetUser(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("list projects: %w", err)
 	}
@@ -197,10 +607,20 @@ func (srv *Server) ListProjects(ctx context.Context) ([]api.Project, error) {
 }
 
 func (srv *Server) CreateProject(ctx context.Context, req *api.ProjectConfig) (*api.Project, error) {
+	if _, err := types.NewAccessKey(nil, req.Hosts, req.Paths); err != nil {
+		return nil, fmt.Errorf("validate defaults: %w", err)
+	}
+	headers := parseHeaders(req.Headers)
+	if err := types.ValidateHeaders(headers); err != nil {
+		return nil, fmt.Errorf("validate headers: %w", err)
+	}
 	p, err := srv.store.CreateProject(ctx, dbo.CreateProjectParams{
 		User:        utils.GetUser(ctx),
 		Slug:        req.Slug,
 		Description: req.Description.Or(""),
+		Hosts:       req.Hosts,
+		Paths:       req.Paths,
+		Headers:     headers,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create project: %w", err)
@@ -217,20 +637,68 @@ func (srv *Server) GetProject(ctx context.Context, params api.GetProjectParams)
 }
 
 func (srv *Server) UpdateProject(ctx context.Context, req *api.ProjectPatch, params api.UpdateProjectParams) error {
-	changed, err := srv.store.UpdateProject(ctx, dbo.UpdateProjectParams{
-		User:        utils.GetUser(ctx),
-		ID:          int64(params.Project),
-		Description: req.Description.Or(""),
-	})
+	user := utils.GetUser(ctx)
+	p := dbo.UpdateProjectParams{
+		User: user,
+		ID:   int64(params.Project),
+	}
+	if v, ok := req.Description.Get(); ok {
+		p.Description = &v
+	}
+	if v, ok := req.Slug.Get(); ok {
+		current, err := srv.store.GetProject(ctx, user, p.ID)
+		if err != nil {
+			return fmt.Errorf("get project: %w", err)
+		}
+		if current.Slug == "" || v == "" {
+			return errCannotRenameDefault
+		}
+		p.Slug = &v
+	}
+	if req.Hosts != nil {
+		p.Hosts = &req.Hosts
+	}
+	if req.Paths != nil {
+		p.Paths = &req.Paths
+	}
+	if req.Headers != nil {
+		h := parseHeaders(req.Headers)
+		if err := types.ValidateHeaders(h); err != nil {
+			return fmt.Errorf("validate headers: %w", err)
+		}
+		p.Headers = &h
+	}
+	if req.Hosts != nil || req.Paths != nil {
+		if _, err := types.NewAccessKey(nil, req.Hosts, req.Paths); err != nil {
+			return fmt.Errorf("validate defaults: %w", err)
+		}
+	}
+
+	changed, err := srv.store.UpdateProject(ctx, p)
 	if err != nil {
 		return fmt.Errorf("update project: %w", err)
 	}
 	if changed == 0 {
 		return errUnknownProject
 	}
+	if p.Slug != nil || p.Hosts != nil || p.Paths != nil || p.Headers != nil {
+		return srv.notifyProjectUpdated(ctx, user, p.ID)
+	}
 	return nil
 }
 
+func (srv *Server) DeleteProjectAlias(ctx context.Context, params api.DeleteProjectAliasParams) error {
+	user := utils.GetUser(ctx)
+	removed, err := srv.store.DeleteProjectAlias(ctx, user, int64(params.Project), params.Alias)
+	if err != nil {
+		return fmt.Errorf("delete project alias: %w", err)
+	}
+	if removed == 0 {
+		return nil
+	}
+	return srv.notifyProjectUpdated(ctx, user, int64(params.Project))
+}
+
 func (srv *Server) DeleteProject(ctx context.Context, params api.DeleteProjectParams) error {
 	user := utils.GetUser(ctx)
 	p, err := srv.store.GetProject(ctx, user, int64(params.Project))
@@ -251,6 +719,66 @@ func (srv *Server) DeleteProject(ctx context.Context, params api.DeleteProjectPa
 	return nil
 }
 
+// cursor is a serialized dbo.TokenCursor, bound to the sort options.
+type cursor struct {
+	Sort       string `json:"s"`
+	ID         int64  `json:"i"`
+	Label      string `json:"l,omitempty"`
+	LastAccess int64  `json:"t,omitempty"`
+	Requests   int64  `json:"r,omitempty"`
+}
+
+func encodeCursor(c dbo.TokenCursor, sort dbo.TokenSort, asc bool) string {
+	data, _ := json.Marshal(cursor{
+		Sort:       cursorSort(sort, asc),
+		ID:         c.ID,
+		Label:      c.Label,
+		LastAccess: c.LastAccessAt.Unix(),
+		Requests:   c.Requests,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string, sort dbo.TokenSort, asc bool) (*dbo.TokenCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decode cursor: %w", errInvalidCursor)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse cursor: %w", errInvalidCursor)
+	}
+	if c.Sort != cursorSort(sort, asc) {
+		return nil, fmt.Errorf("cursor for different sort order: %w", errInvalidCursor)
+	}
+	return &dbo.TokenCursor{
+		ID:           c.ID,
+		Label:        c.Label,
+		LastAccessAt: time.Unix(c.LastAccess, 0),
+		Requests:     c.Requests,
+	}, nil
+}
+
+func cursorSort(sort dbo.TokenSort, asc bool) string {
+	if asc {
+		return string(sort) + ":asc"
+	}
+	return string(sort) + ":desc"
+}
+
+func mapTokenSort(v api.ListTokensSort) dbo.TokenSort {
+	switch v {
+	case api.ListTokensSortLabel:
+		return dbo.SortLabel
+	case api.ListTokensSortLastAccess:
+		return dbo.SortLastAccess
+	case api.ListTokensSortRequests:
+		return dbo.SortRequests
+	default:
+		return dbo.SortCreated
+	}
+}
+
 func parseHeaders(v []api.NameValue) types.Headers {
 	out := make(types.Headers, 0, len(v))
 	for _, it := range v {
@@ -263,7 +791,7 @@ func parseHeaders(v []api.NameValue) types.Headers {
 }
 
 func mapToken(t *dbo.Token) *api.Token {
-	return &api.Token{
+	out := &api.Token{
 		ID:        int(t.ID),
 		CreatedAt: t.CreatedAt,
 		UpdatedAt: t.UpdatedAt,
@@ -271,16 +799,49 @@ func mapToken(t *dbo.Token) *api.Token {
 			Value: t.LastAccessAt,
 			Set:   !t.LastAccessAt.IsZero(),
 		},
-		KeyID:       t.KeyID.String(),
-		User:        t.User,
-		Label:       t.Label,
-		Hosts:       t.Hosts,
-		Paths:       t.Paths,
-		Headers:     mapHeaders(t.Headers),
-		Requests:    t.Requests,
-		ProjectId:   int(t.ProjectID),
-		ProjectSlug: t.ProjectSlug,
+		KeyID:          t.KeyID.String(),
+		User:           t.User,
+		Label:          t.Label,
+		Hosts:          t.Hosts,
+		Paths:          t.Paths,
+		Headers:        mapHeaders(t.Headers),
+		Requests:       t.Requests,
+		Meta:           mapMeta(t.Meta),
+		ProjectId:      int(t.ProjectID),
+		ProjectSlug:    t.ProjectSlug,
+		LinkedProjects: mapProjectRefs(t.LinkedProjects),
+		Effective: api.EffectiveRules{
+			Hosts:   t.EffectiveHosts(),
+			Paths:   t.EffectivePaths(),
+			Headers: mapHeaders(t.EffectiveHeaders()),
+		},
+	}
+	if t.KeyFormat == types.KeyImported {
+		out.KeyPrefix = api.NewOptString(t.KeyPrefix)
+	}
+	if t.Disabled() {
+		out.DisabledAt = api.NewOptDateTime(*t.DisabledAt)
+		out.DisabledReason = api.NewOptString(t.DisabledReason)
+	}
+	return out
+}
+
+func mapMeta(v types.Meta) api.Meta {
+	if v == nil {
+		return api.Meta{}
 	}
+	return api.Meta(v)
+}
+
+func mapProjectRefs(v []dbo.ProjectRef) []api.ProjectRef {
+	out := make([]api.ProjectRef, 0, len(v))
+	for _, p := range v {
+		out = append(out, api.ProjectRef{
+			ID:   int(p.ID),
+			Slug: p.Slug,
+		})
+	}
+	return out
 }
 
 func mapProject(p *dbo.Project) *api.Project {
@@ -290,6 +851,10 @@ func mapProject(p *dbo.Project) *api.Project {
 		UpdatedAt:   p.UpdatedAt,
 		Slug:        p.Slug,
 		Description: p.Description,
+		Aliases:     p.Aliases,
+		Hosts:       p.Hosts,
+		Paths:       p.Paths,
+		Headers:     mapHeaders(p.Headers),
 	}
 }
 
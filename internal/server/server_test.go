@@ -2,16 +2,23 @@ package server_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/reddec/token-login/api"
+	"github.com/reddec/token-login/internal/cache"
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/memory"
 	"github.com/reddec/token-login/internal/dbo/open"
 	"github.com/reddec/token-login/internal/server"
+	"github.com/reddec/token-login/internal/types"
 	"github.com/reddec/token-login/internal/utils"
 )
 
@@ -34,6 +41,15 @@ func defaultProjectFor(t *testing.T, srv *server.Server, ctx context.Context) in
 	return p.ID
 }
 
+// listTokens returns tokens of the page without response headers.
+func listTokens(srv *server.Server, ctx context.Context, params api.ListTokensParams) ([]api.Token, error) {
+	res, err := srv.ListTokens(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return res.Response, nil
+}
+
 func TestNew(t *testing.T) {
 	ctx := context.Background()
 	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
@@ -90,7 +106,7 @@ func TestNew(t *testing.T) {
 	require.NotEmpty(t, secret3.ID)
 
 	t.Run("alice can not see bob and vice versa", func(t *testing.T) {
-		aliceTokens, err := srv.ListTokens(aliceCtx, api.ListTokensParams{})
+		aliceTokens, err := listTokens(srv, aliceCtx, api.ListTokensParams{})
 		require.NoError(t, err)
 		require.Len(t, aliceTokens, 2)
 		require.Equal(t, "l1", aliceTokens[1].Label)
@@ -103,7 +119,7 @@ func TestNew(t *testing.T) {
 		assert.Equal(t, "x", aliceTokens[0].Headers[1].Name)
 		assert.Equal(t, "y", aliceTokens[0].Headers[1].Value)
 
-		bobTokens, err := srv.ListTokens(bobCtx, api.ListTokensParams{})
+		bobTokens, err := listTokens(srv, bobCtx, api.ListTokensParams{})
 		require.NoError(t, err)
 		require.Len(t, bobTokens, 1)
 		require.Equal(t, "l3", bobTokens[0].Label)
@@ -175,6 +191,19 @@ func TestNew(t *testing.T) {
 		assert.Equal(t, "new-val", tok.Headers[0].Value)
 	})
 
+	t.Run("update token with invalid header template", func(t *testing.T) {
+		err := srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			Headers: []api.NameValue{{Name: "X-Label", Value: "{{.Token.Nope}}"}},
+		}, api.UpdateTokenParams{Token: secret1.ID})
+		require.Error(t, err)
+
+		_, err = srv.CreateToken(aliceCtx, &api.TokenConfig{
+			ProjectId: aliceDefault,
+			Headers:   []api.NameValue{{Name: "X-Label", Value: "{{.Token.Label"}},
+		})
+		require.Error(t, err)
+	})
+
 	t.Run("update non-existent token", func(t *testing.T) {
 		err := srv.UpdateToken(aliceCtx, &api.TokenPatch{
 			Label: api.NewOptString("nope"),
@@ -447,6 +476,59 @@ func TestTokenAssignedToProject(t *testing.T) {
 		})
 		require.Error(t, err)
 	})
+
+	t.Run("token can be moved to another project", func(t *testing.T) {
+		keys := cache.New(client)
+		srv.OnUpdate(func(id int) {
+			require.NoError(t, keys.SyncKey(ctx, id))
+		})
+
+		target, err := srv.CreateProject(userCtx, &api.ProjectConfig{Slug: "target"})
+		require.NoError(t, err)
+
+		cred, err := srv.CreateToken(userCtx, &api.TokenConfig{
+			Label:     api.NewOptString("movable"),
+			ProjectId: defaultID,
+		})
+		require.NoError(t, err)
+
+		err = srv.UpdateToken(userCtx, &api.TokenPatch{
+			ProjectId: api.NewOptInt(target.ID),
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, tok.ProjectId)
+		assert.Equal(t, "target", tok.ProjectSlug)
+		assert.Equal(t, "movable", tok.Label)
+
+		key, err := types.ParseKey(cred.Key)
+		require.NoError(t, err)
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.Equal(t, "target", cached.DBToken.ProjectSlug)
+	})
+
+	t.Run("token can not be moved to another user's project", func(t *testing.T) {
+		otherCtx := utils.WithUser(ctx, "other")
+		otherDefault := defaultProjectFor(t, srv, otherCtx)
+
+		cred, err := srv.CreateToken(userCtx, &api.TokenConfig{
+			Label:     api.NewOptString("stay"),
+			ProjectId: defaultID,
+		})
+		require.NoError(t, err)
+
+		err = srv.UpdateToken(userCtx, &api.TokenPatch{
+			ProjectId: api.NewOptInt(otherDefault),
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.Error(t, err)
+
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Equal(t, defaultID, tok.ProjectId)
+	})
 }
 
 func TestLastAccessAtSerializationBug(t *testing.T) {
@@ -486,3 +568,1244 @@ func TestLastAccessAtSerializationBug(t *testing.T) {
 	require.True(t, hasField,
 		"lastAccessAt field must be present in JSON when value is non-zero")
 }
+
+func TestProjectRename(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	keys := cache.New(client)
+	srv.OnUpdate(func(id int) {
+		require.NoError(t, keys.SyncKey(ctx, id))
+	})
+	defaultID := defaultProjectFor(t, srv, userCtx)
+
+	project, err := srv.CreateProject(userCtx, &api.ProjectConfig{
+		Slug:        "old-name",
+		Description: api.NewOptString("keep me"),
+	})
+	require.NoError(t, err)
+	cred, err := srv.CreateToken(userCtx, &api.TokenConfig{
+		Label:     api.NewOptString("renamed"),
+		ProjectId: project.ID,
+	})
+	require.NoError(t, err)
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+
+	t.Run("rename keeps old slug as alias", func(t *testing.T) {
+		err := srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("new-name"),
+		}, api.UpdateProjectParams{Project: project.ID})
+		require.NoError(t, err)
+
+		p, err := srv.GetProject(userCtx, api.GetProjectParams{Project: project.ID})
+		require.NoError(t, err)
+		assert.Equal(t, "new-name", p.Slug)
+		assert.Equal(t, "keep me", p.Description)
+		assert.Equal(t, []string{"old-name"}, p.Aliases)
+
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.Equal(t, "new-name", cached.DBToken.ProjectSlug)
+		assert.True(t, cached.DBToken.InProject("new-name"))
+		assert.True(t, cached.DBToken.InProject("old-name"))
+		assert.False(t, cached.DBToken.InProject(""))
+	})
+
+	t.Run("alias can not be reused by another project", func(t *testing.T) {
+		_, err := srv.CreateProject(userCtx, &api.ProjectConfig{Slug: "old-name"})
+		require.ErrorIs(t, err, dbo.ErrSlugInUse)
+	})
+
+	t.Run("rename to existing slug fails", func(t *testing.T) {
+		other, err := srv.CreateProject(userCtx, &api.ProjectConfig{Slug: "other"})
+		require.NoError(t, err)
+
+		err = srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("new-name"),
+		}, api.UpdateProjectParams{Project: other.ID})
+		require.ErrorIs(t, err, dbo.ErrSlugInUse)
+
+		err = srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("old-name"),
+		}, api.UpdateProjectParams{Project: other.ID})
+		require.ErrorIs(t, err, dbo.ErrSlugInUse)
+	})
+
+	t.Run("default project can not be renamed", func(t *testing.T) {
+		err := srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("not-default"),
+		}, api.UpdateProjectParams{Project: defaultID})
+		require.Error(t, err)
+	})
+
+	t.Run("removed alias is no longer accepted", func(t *testing.T) {
+		err := srv.DeleteProjectAlias(userCtx, api.DeleteProjectAliasParams{Project: project.ID, Alias: "old-name"})
+		require.NoError(t, err)
+
+		p, err := srv.GetProject(userCtx, api.GetProjectParams{Project: project.ID})
+		require.NoError(t, err)
+		assert.Empty(t, p.Aliases)
+
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.False(t, cached.DBToken.InProject("old-name"))
+	})
+
+	t.Run("renaming back to alias restores it", func(t *testing.T) {
+		err := srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("newest-name"),
+		}, api.UpdateProjectParams{Project: project.ID})
+		require.NoError(t, err)
+
+		err = srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("new-name"),
+		}, api.UpdateProjectParams{Project: project.ID})
+		require.NoError(t, err)
+
+		p, err := srv.GetProject(userCtx, api.GetProjectParams{Project: project.ID})
+		require.NoError(t, err)
+		assert.Equal(t, "new-name", p.Slug)
+		assert.Equal(t, []string{"newest-name"}, p.Aliases)
+	})
+}
+
+func TestTokenLinkedProjects(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	aliceCtx := utils.WithUser(ctx, "alice")
+	bobCtx := utils.WithUser(ctx, "bob")
+	srv := server.New(client)
+	keys := cache.New(client)
+	srv.OnUpdate(func(id int) {
+		require.NoError(t, keys.SyncKey(ctx, id))
+	})
+
+	home, err := srv.CreateProject(aliceCtx, &api.ProjectConfig{Slug: "home"})
+	require.NoError(t, err)
+	first, err := srv.CreateProject(aliceCtx, &api.ProjectConfig{Slug: "first"})
+	require.NoError(t, err)
+	second, err := srv.CreateProject(aliceCtx, &api.ProjectConfig{Slug: "second"})
+	require.NoError(t, err)
+	bobProject, err := srv.CreateProject(bobCtx, &api.ProjectConfig{Slug: "bob-project"})
+	require.NoError(t, err)
+
+	cred, err := srv.CreateToken(aliceCtx, &api.TokenConfig{
+		Label:            api.NewOptString("shared"),
+		ProjectId:        home.ID,
+		LinkedProjectIds: []int{first.ID, first.ID, home.ID},
+	})
+	require.NoError(t, err)
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+
+	t.Run("token is created with linked projects", func(t *testing.T) {
+		tok, err := srv.GetToken(aliceCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Equal(t, home.ID, tok.ProjectId)
+		assert.Equal(t, []api.ProjectRef{{ID: first.ID, Slug: "first"}}, tok.LinkedProjects)
+
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, cached.DBToken.InProject("home"))
+		assert.True(t, cached.DBToken.InProject("first"))
+		assert.False(t, cached.DBToken.InProject("second"))
+	})
+
+	t.Run("token is listed in linked project", func(t *testing.T) {
+		list, err := listTokens(srv, aliceCtx, api.ListTokensParams{Project: api.NewOptInt(first.ID)})
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, cred.ID, list[0].ID)
+
+		list, err = listTokens(srv, aliceCtx, api.ListTokensParams{Project: api.NewOptInt(second.ID)})
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("linked projects can be replaced", func(t *testing.T) {
+		err := srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			LinkedProjectIds: []int{second.ID},
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.False(t, cached.DBToken.InProject("first"))
+		assert.True(t, cached.DBToken.InProject("second"))
+	})
+
+	t.Run("linked project rename keeps alias", func(t *testing.T) {
+		err := srv.UpdateProject(aliceCtx, &api.ProjectPatch{
+			Slug: api.NewOptString("second-renamed"),
+		}, api.UpdateProjectParams{Project: second.ID})
+		require.NoError(t, err)
+
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, cached.DBToken.InProject("second-renamed"))
+		assert.True(t, cached.DBToken.InProject("second"))
+	})
+
+	t.Run("another user's project can not be linked", func(t *testing.T) {
+		err := srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			LinkedProjectIds: []int{bobProject.ID},
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.Error(t, err)
+
+		_, err = srv.CreateToken(aliceCtx, &api.TokenConfig{
+			ProjectId:        home.ID,
+			LinkedProjectIds: []int{bobProject.ID},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("deleting linked project unlinks token", func(t *testing.T) {
+		err := srv.DeleteProject(aliceCtx, api.DeleteProjectParams{Project: second.ID})
+		require.NoError(t, err)
+
+		tok, err := srv.GetToken(aliceCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Equal(t, home.ID, tok.ProjectId)
+		assert.Empty(t, tok.LinkedProjects)
+
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.False(t, cached.DBToken.InProject("second-renamed"))
+	})
+
+	t.Run("primary project is not linked", func(t *testing.T) {
+		err := srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			LinkedProjectIds: []int{home.ID, first.ID},
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		tok, err := srv.GetToken(aliceCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Equal(t, []api.ProjectRef{{ID: first.ID, Slug: "first"}}, tok.LinkedProjects, "current primary is skipped")
+
+		err = srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			ProjectId: api.NewOptInt(first.ID),
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		tok, err = srv.GetToken(aliceCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, tok.ProjectId)
+		assert.Empty(t, tok.LinkedProjects, "new primary is unlinked")
+
+		err = srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			ProjectId: api.NewOptInt(home.ID),
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+	})
+
+	t.Run("empty list clears linked projects", func(t *testing.T) {
+		err := srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			LinkedProjectIds: []int{first.ID},
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		err = srv.UpdateToken(aliceCtx, &api.TokenPatch{
+			LinkedProjectIds: []int{},
+		}, api.UpdateTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+
+		tok, err := srv.GetToken(aliceCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Empty(t, tok.LinkedProjects)
+	})
+}
+
+func TestProjectDefaults(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	keys := cache.New(client)
+	srv.OnUpdate(func(id int) {
+		require.NoError(t, keys.SyncKey(ctx, id))
+	})
+
+	project, err := srv.CreateProject(userCtx, &api.ProjectConfig{
+		Slug:  "defaults",
+		Hosts: []string{"*.example.com"},
+		Paths: []string{"/api/**"},
+		Headers: []api.NameValue{
+			{Name: "X-Team", Value: "core"},
+			{Name: "X-Env", Value: "prod"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.example.com"}, project.Hosts)
+	assert.Equal(t, []string{"/api/**"}, project.Paths)
+
+	inherited, err := srv.CreateToken(userCtx, &api.TokenConfig{
+		ProjectId: project.ID,
+		Headers:   []api.NameValue{{Name: "X-Env", Value: "dev"}},
+	})
+	require.NoError(t, err)
+	overridden, err := srv.CreateToken(userCtx, &api.TokenConfig{
+		ProjectId: project.ID,
+		Hosts:     []string{"internal.example.com"},
+		Paths:     []string{"/admin/**"},
+	})
+	require.NoError(t, err)
+
+	t.Run("token without own rules inherits project defaults", func(t *testing.T) {
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: inherited.ID})
+		require.NoError(t, err)
+		assert.Empty(t, tok.Hosts)
+		assert.Equal(t, []string{"*.example.com"}, tok.Effective.Hosts)
+		assert.Equal(t, []string{"/api/**"}, tok.Effective.Paths)
+		assert.Equal(t, []api.NameValue{
+			{Name: "X-Team", Value: "core"},
+			{Name: "X-Env", Value: "dev"},
+		}, tok.Effective.Headers)
+
+		key, err := types.ParseKey(inherited.Key)
+		require.NoError(t, err)
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, cached.AccessKey.Valid("www.example.com", "/api/v1", key.Payload()))
+		assert.False(t, cached.AccessKey.Valid("www.example.org", "/api/v1", key.Payload()))
+		assert.False(t, cached.AccessKey.Valid("www.example.com", "/admin", key.Payload()))
+	})
+
+	t.Run("token rules override project defaults", func(t *testing.T) {
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: overridden.ID})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"internal.example.com"}, tok.Effective.Hosts)
+		assert.Equal(t, []string{"/admin/**"}, tok.Effective.Paths)
+	})
+
+	t.Run("changing defaults re-syncs tokens", func(t *testing.T) {
+		err := srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Hosts: []string{"*.example.org"},
+		}, api.UpdateProjectParams{Project: project.ID})
+		require.NoError(t, err)
+
+		p, err := srv.GetProject(userCtx, api.GetProjectParams{Project: project.ID})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"*.example.org"}, p.Hosts)
+		assert.Equal(t, []string{"/api/**"}, p.Paths)
+
+		key, err := types.ParseKey(inherited.Key)
+		require.NoError(t, err)
+		cached, ok := keys.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, cached.AccessKey.Valid("www.example.org", "/api/v1", key.Payload()))
+		assert.False(t, cached.AccessKey.Valid("www.example.com", "/api/v1", key.Payload()))
+	})
+
+	t.Run("invalid defaults are rejected", func(t *testing.T) {
+		err := srv.UpdateProject(userCtx, &api.ProjectPatch{
+			Paths: []string{"/api/["},
+		}, api.UpdateProjectParams{Project: project.ID})
+		require.Error(t, err)
+	})
+}
+
+func TestTokenMeta(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	defaultID := defaultProjectFor(t, srv, userCtx)
+
+	core, err := srv.CreateToken(userCtx, &api.TokenConfig{
+		ProjectId: defaultID,
+		Meta:      api.NewOptMeta(api.Meta{"team": "core", "tenant": "42"}),
+	})
+	require.NoError(t, err)
+	web, err := srv.CreateToken(userCtx, &api.TokenConfig{
+		ProjectId: defaultID,
+		Meta:      api.NewOptMeta(api.Meta{"team": "web"}),
+	})
+	require.NoError(t, err)
+	plain, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+	require.NoError(t, err)
+
+	ids := func(list []api.Token) []int {
+		var out []int
+		for _, tok := range list {
+			out = append(out, tok.ID)
+		}
+		return out
+	}
+
+	t.Run("meta is returned", func(t *testing.T) {
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: core.ID})
+		require.NoError(t, err)
+		assert.Equal(t, api.Meta{"team": "core", "tenant": "42"}, tok.Meta)
+
+		tok, err = srv.GetToken(userCtx, api.GetTokenParams{Token: plain.ID})
+		require.NoError(t, err)
+		assert.Empty(t, tok.Meta)
+	})
+
+	t.Run("filter by single pair", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Meta: []string{"team=web"}})
+		require.NoError(t, err)
+		assert.Equal(t, []int{web.ID}, ids(list))
+	})
+
+	t.Run("filter by all pairs", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Meta: []string{"team=core", "tenant=42"}})
+		require.NoError(t, err)
+		assert.Equal(t, []int{core.ID}, ids(list))
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{Meta: []string{"team=core", "tenant=43"}})
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("filter with project", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{
+			Project: api.NewOptInt(defaultID),
+			Meta:    []string{"team=core"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{core.ID}, ids(list))
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		_, err := listTokens(srv, userCtx, api.ListTokensParams{Meta: []string{"team"}})
+		require.Error(t, err)
+	})
+
+	t.Run("meta can be replaced", func(t *testing.T) {
+		err := srv.UpdateToken(userCtx, &api.TokenPatch{
+			Meta: api.NewOptMeta(api.Meta{"team": "web"}),
+		}, api.UpdateTokenParams{Token: plain.ID})
+		require.NoError(t, err)
+
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Meta: []string{"team=web"}})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{web.ID, plain.ID}, ids(list))
+	})
+}
+
+func TestTokenListing(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	defaultID := defaultProjectFor(t, srv, userCtx)
+
+	create := func(label string, hosts ...string) int {
+		cred, err := srv.CreateToken(userCtx, &api.TokenConfig{
+			ProjectId: defaultID,
+			Label:     api.NewOptString(label),
+			Hosts:     hosts,
+		})
+		require.NoError(t, err)
+		return cred.ID
+	}
+	alpha := create("Alpha service", "api.example.com")
+	beta := create("beta job")
+	gamma := create("Gamma", "*.internal.org")
+	delta := create("delta")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, client.UpdateStats(ctx, map[int64]dbo.StatsEntry{
+		int64(alpha): {Hits: 5, Last: now.Add(-48 * time.Hour)},
+		int64(gamma): {Hits: 10, Last: now.Add(-time.Hour)},
+		int64(delta): {Hits: 1, Last: now.Add(-10 * 24 * time.Hour)},
+	}))
+
+	ids := func(list []api.Token) []int {
+		var out []int
+		for _, tok := range list {
+			out = append(out, tok.ID)
+		}
+		return out
+	}
+
+	t.Run("search by label", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Q: api.NewOptString("ALPHA")})
+		require.NoError(t, err)
+		assert.Equal(t, []int{alpha}, ids(list))
+	})
+
+	t.Run("search by key id prefix", func(t *testing.T) {
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: beta})
+		require.NoError(t, err)
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Q: api.NewOptString(tok.KeyID[:8])})
+		require.NoError(t, err)
+		assert.Contains(t, ids(list), beta)
+	})
+
+	t.Run("filter by host", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Host: api.NewOptString("internal")})
+		require.NoError(t, err)
+		assert.Equal(t, []int{gamma}, ids(list))
+	})
+
+	t.Run("filter by last access", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{AccessedAfter: api.NewOptDateTime(now.Add(-72 * time.Hour))})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{alpha, gamma}, ids(list))
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{AccessedBefore: api.NewOptDateTime(now.Add(-24 * time.Hour))})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{alpha, delta}, ids(list))
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{UnusedSince: api.NewOptDateTime(now.Add(-7 * 24 * time.Hour))})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{beta, delta}, ids(list))
+	})
+
+	t.Run("sort", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{})
+		require.NoError(t, err)
+		assert.Equal(t, []int{delta, gamma, beta, alpha}, ids(list))
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{
+			Sort:  api.NewOptListTokensSort(api.ListTokensSortLabel),
+			Order: api.NewOptListTokensOrder(api.ListTokensOrderAsc),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{alpha, beta, delta, gamma}, ids(list))
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{
+			Sort: api.NewOptListTokensSort(api.ListTokensSortRequests),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{gamma, alpha, delta, beta}, ids(list))
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{
+			Sort: api.NewOptListTokensSort(api.ListTokensSortLastAccess),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{gamma, alpha, delta}, ids(list)[1:])
+	})
+
+	paginate := func(t *testing.T, params api.ListTokensParams) [][]int {
+		var pages [][]int
+		params.Limit = api.NewOptInt(3)
+		for {
+			res, err := srv.ListTokens(userCtx, params)
+			require.NoError(t, err)
+			pages = append(pages, ids(res.Response))
+			next, ok := res.XNextCursor.Get()
+			if !ok {
+				return pages
+			}
+			params.Cursor = api.NewOptString(next)
+			require.Less(t, len(pages), 10)
+		}
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		pages := paginate(t, api.ListTokensParams{})
+		assert.Equal(t, [][]int{{delta, gamma, beta}, {alpha}}, pages)
+
+		pages = paginate(t, api.ListTokensParams{
+			Sort:  api.NewOptListTokensSort(api.ListTokensSortLabel),
+			Order: api.NewOptListTokensOrder(api.ListTokensOrderAsc),
+		})
+		assert.Equal(t, [][]int{{alpha, beta, delta}, {gamma}}, pages)
+
+		pages = paginate(t, api.ListTokensParams{
+			Sort: api.NewOptListTokensSort(api.ListTokensSortRequests),
+		})
+		assert.Equal(t, [][]int{{gamma, alpha, delta}, {beta}}, pages)
+	})
+
+	t.Run("exact page has no cursor", func(t *testing.T) {
+		res, err := srv.ListTokens(userCtx, api.ListTokensParams{Limit: api.NewOptInt(4)})
+		require.NoError(t, err)
+		assert.Len(t, res.Response, 4)
+		assert.False(t, res.XNextCursor.Set)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := srv.ListTokens(userCtx, api.ListTokensParams{Cursor: api.NewOptString("garbage!")})
+		require.Error(t, err)
+
+		res, err := srv.ListTokens(userCtx, api.ListTokensParams{Limit: api.NewOptInt(1)})
+		require.NoError(t, err)
+		_, err = srv.ListTokens(userCtx, api.ListTokensParams{
+			Sort:   api.NewOptListTokensSort(api.ListTokensSortLabel),
+			Cursor: res.XNextCursor,
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestTokenLookupByKey(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	aliceCtx := utils.WithUser(ctx, "alice")
+	bobCtx := utils.WithUser(ctx, "bob")
+	srv := server.New(client)
+
+	cred, err := srv.CreateToken(aliceCtx, &api.TokenConfig{
+		ProjectId: defaultProjectFor(t, srv, aliceCtx),
+		Label:     api.NewOptString("leaky"),
+	})
+	require.NoError(t, err)
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+	kid := key.ID().String()
+
+	t.Run("by key id", func(t *testing.T) {
+		tok, err := srv.GetTokenByKey(aliceCtx, api.GetTokenByKeyParams{KeyID: kid})
+		require.NoError(t, err)
+		assert.Equal(t, cred.ID, tok.ID)
+
+		tok, err = srv.GetTokenByKey(aliceCtx, api.GetTokenByKeyParams{KeyID: strings.ToLower(kid)})
+		require.NoError(t, err)
+		assert.Equal(t, cred.ID, tok.ID)
+	})
+
+	t.Run("by key id of another user", func(t *testing.T) {
+		_, err := srv.GetTokenByKey(bobCtx, api.GetTokenByKeyParams{KeyID: kid})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid key id", func(t *testing.T) {
+		_, err := srv.GetTokenByKey(aliceCtx, api.GetTokenByKeyParams{KeyID: "not-a-key"})
+		require.Error(t, err)
+	})
+
+	t.Run("identify full key", func(t *testing.T) {
+		res, err := srv.IdentifyToken(aliceCtx, &api.KeyLookup{Key: " " + cred.Key + "\n"})
+		require.NoError(t, err)
+		assert.Equal(t, cred.ID, res.ID)
+		assert.Equal(t, kid, res.KeyID)
+		assert.Equal(t, "leaky", res.Label)
+	})
+
+	t.Run("identify key of another user", func(t *testing.T) {
+		_, err := srv.IdentifyToken(bobCtx, &api.KeyLookup{Key: cred.Key})
+		require.Error(t, err)
+	})
+
+	t.Run("identify key with wrong secret", func(t *testing.T) {
+		forged := key
+		forged[len(forged)-1] ^= 0xFF
+		_, err := srv.IdentifyToken(aliceCtx, &api.KeyLookup{Key: forged.String()})
+		require.Error(t, err)
+	})
+
+	t.Run("unknown key and wrong secret are not told apart", func(t *testing.T) {
+		forged := key
+		forged[len(forged)-1] ^= 0xFF
+		_, wrongSecret := srv.IdentifyToken(aliceCtx, &api.KeyLookup{Key: forged.String()})
+		require.Error(t, wrongSecret)
+
+		unknown, err := types.NewKey()
+		require.NoError(t, err)
+		_, unknownKey := srv.IdentifyToken(aliceCtx, &api.KeyLookup{Key: unknown.String()})
+		require.Error(t, unknownKey)
+		assert.Equal(t, wrongSecret.Error(), unknownKey.Error())
+
+		_, otherUser := srv.IdentifyToken(bobCtx, &api.KeyLookup{Key: cred.Key})
+		require.Error(t, otherUser)
+		assert.Equal(t, wrongSecret.Error(), otherUser.Error())
+	})
+
+	t.Run("identify refreshed key", func(t *testing.T) {
+		fresh, err := srv.RefreshToken(aliceCtx, api.RefreshTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		_, err = srv.IdentifyToken(aliceCtx, &api.KeyLookup{Key: cred.Key})
+		require.Error(t, err)
+		res, err := srv.IdentifyToken(aliceCtx, &api.KeyLookup{Key: fresh.Key})
+		require.NoError(t, err)
+		assert.Equal(t, cred.ID, res.ID)
+	})
+}
+
+func TestReportLeak(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	defaultID := defaultProjectFor(t, srv, userCtx)
+
+	keysCache := cache.New(client)
+	srv.OnRemove(keysCache.Drop)
+	var removed []int
+	srv.OnRemove(func(id int) {
+		removed = append(removed, id)
+	})
+	var disabled []string
+	srv.OnDisable(func(token *dbo.Token, reason string) {
+		disabled = append(disabled, token.User+" "+reason)
+	})
+
+	cred, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+	require.NoError(t, err)
+	other, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+	require.NoError(t, err)
+	require.NoError(t, keysCache.SyncKeys(ctx))
+
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+	_, ok := keysCache.FindByKey(key)
+	require.True(t, ok)
+
+	t.Run("unknown key is ignored", func(t *testing.T) {
+		unknown, err := types.NewKey()
+		require.NoError(t, err)
+		found, err := srv.ReportLeak(ctx, unknown, "")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("wrong secret is ignored", func(t *testing.T) {
+		forged := key
+		forged[len(forged)-1] ^= 0xFF
+		found, err := srv.ReportLeak(ctx, forged, "")
+		require.NoError(t, err)
+		assert.False(t, found)
+		assert.Empty(t, removed)
+	})
+
+	t.Run("leaked key is disabled", func(t *testing.T) {
+		found, err := srv.ReportLeak(ctx, key, "github https://github.com/acme/app/blob/main/.env")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []int{cred.ID}, removed)
+		assert.Equal(t, []string{"tester leaked: github https://github.com/acme/app/blob/main/.env"}, disabled)
+
+		_, ok := keysCache.FindByKey(key)
+		assert.False(t, ok)
+
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.True(t, tok.DisabledAt.Set)
+		assert.Contains(t, tok.DisabledReason.Value, "github")
+	})
+
+	t.Run("repeated report does not notify", func(t *testing.T) {
+		found, err := srv.ReportLeak(ctx, key, "")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Len(t, removed, 1)
+		assert.Len(t, disabled, 1)
+	})
+
+	t.Run("disabled tokens are not cached", func(t *testing.T) {
+		require.NoError(t, keysCache.SyncKeys(ctx))
+		_, ok := keysCache.FindByKey(key)
+		assert.False(t, ok)
+
+		require.NoError(t, keysCache.SyncKey(ctx, cred.ID))
+		_, ok = keysCache.FindByKey(key)
+		assert.False(t, ok)
+	})
+
+	t.Run("filter by disabled", func(t *testing.T) {
+		list, err := listTokens(srv, userCtx, api.ListTokensParams{Disabled: api.NewOptBool(true)})
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, cred.ID, list[0].ID)
+
+		list, err = listTokens(srv, userCtx, api.ListTokensParams{Disabled: api.NewOptBool(false)})
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, other.ID, list[0].ID)
+	})
+
+	t.Run("refresh enables token", func(t *testing.T) {
+		fresh, err := srv.RefreshToken(userCtx, api.RefreshTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+
+		tok, err := srv.GetToken(userCtx, api.GetTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.False(t, tok.DisabledAt.Set)
+
+		require.NoError(t, keysCache.SyncKeys(ctx))
+		freshKey, err := types.ParseKey(fresh.Key)
+		require.NoError(t, err)
+		_, ok := keysCache.FindByKey(freshKey)
+		assert.True(t, ok)
+	})
+}
+
+func TestKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client, server.WithKeyPrefix("acme"))
+	defaultID := defaultProjectFor(t, srv, userCtx)
+
+	cred, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(cred.Key, "acme_"))
+
+	fresh, err := srv.RefreshToken(userCtx, api.RefreshTokenParams{Token: cred.ID})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(fresh.Key, "acme_"))
+
+	// keys are identified regardless of the prefix
+	key, err := types.ParseKey(fresh.Key)
+	require.NoError(t, err)
+	res, err := srv.IdentifyToken(userCtx, &api.KeyLookup{Key: key.String()})
+	require.NoError(t, err)
+	assert.Equal(t, cred.ID, res.ID)
+}
+
+func TestHashUpgrade(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	legacy := server.New(client)
+	defaultID := defaultProjectFor(t, legacy, userCtx)
+
+	cred, err := legacy.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+	require.NoError(t, err)
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+
+	stored, err := client.GetTokenByID(ctx, int64(cred.ID))
+	require.NoError(t, err)
+	assert.Equal(t, types.HashSHA3, stored.HashAlg)
+
+	pepper := types.Pepper{ID: "1", Secret: []byte("pepper")}
+	hasher, err := types.NewHasher(types.HashHMAC, &pepper)
+	require.NoError(t, err)
+	srv := server.New(client, server.WithHasher(hasher))
+	keysCache := cache.New(client, cache.WithHasher(hasher))
+	require.NoError(t, keysCache.SyncKeys(ctx))
+
+	t.Run("outdated hash is still valid", func(t *testing.T) {
+		token, ok := keysCache.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, token.AccessKey.Valid("example.com", "/", key.Payload()))
+
+		res, err := srv.IdentifyToken(userCtx, &api.KeyLookup{Key: cred.Key})
+		require.NoError(t, err)
+		assert.Equal(t, cred.ID, res.ID)
+	})
+
+	t.Run("rehash on use", func(t *testing.T) {
+		token, ok := keysCache.FindByKey(key)
+		require.True(t, ok)
+		require.NoError(t, keysCache.Rehash(ctx, token, key.Payload()))
+
+		stored, err := client.GetTokenByID(ctx, int64(cred.ID))
+		require.NoError(t, err)
+		assert.Equal(t, types.HashHMAC, stored.HashAlg)
+		assert.Equal(t, "1", stored.PepperID)
+		assert.NotEqual(t, key.Hash(), stored.Hash)
+
+		token, ok = keysCache.FindByKey(key)
+		require.True(t, ok)
+		assert.Equal(t, types.HashHMAC, token.DBToken.HashAlg)
+		assert.True(t, token.AccessKey.Valid("example.com", "/", key.Payload()))
+
+		require.NoError(t, keysCache.SyncKeys(ctx))
+		token, ok = keysCache.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, token.AccessKey.Valid("example.com", "/", key.Payload()))
+	})
+
+	t.Run("stale rehash is ignored", func(t *testing.T) {
+		stale := &cache.Token{DBToken: &dbo.Token{ID: stored.ID, KeyID: stored.KeyID, Hash: stored.Hash, HashAlg: types.HashSHA3}}
+		require.NoError(t, keysCache.Rehash(ctx, stale, key.Payload()))
+
+		token, ok := keysCache.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, token.AccessKey.Valid("example.com", "/", key.Payload()))
+	})
+
+	t.Run("new keys use preferred hash", func(t *testing.T) {
+		fresh, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+		require.NoError(t, err)
+		stored, err := client.GetTokenByID(ctx, int64(fresh.ID))
+		require.NoError(t, err)
+		assert.Equal(t, types.HashHMAC, stored.HashAlg)
+	})
+
+	t.Run("pepper rotation", func(t *testing.T) {
+		rotated, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "2", Secret: []byte("new pepper")}, pepper)
+		require.NoError(t, err)
+		rotatedCache := cache.New(client, cache.WithHasher(rotated))
+		require.NoError(t, rotatedCache.SyncKeys(ctx))
+
+		token, ok := rotatedCache.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, token.AccessKey.Valid("example.com", "/", key.Payload()))
+		require.NoError(t, rotatedCache.Rehash(ctx, token, key.Payload()))
+
+		stored, err := client.GetTokenByID(ctx, int64(cred.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "2", stored.PepperID)
+
+		// the previous pepper is no longer needed for this token
+		current, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "2", Secret: []byte("new pepper")})
+		require.NoError(t, err)
+		currentCache := cache.New(client, cache.WithHasher(current))
+		require.NoError(t, currentCache.SyncKeys(ctx))
+		token, ok = currentCache.FindByKey(key)
+		require.True(t, ok)
+		assert.True(t, token.AccessKey.Valid("example.com", "/", key.Payload()))
+	})
+
+	t.Run("tokens with unknown pepper are skipped", func(t *testing.T) {
+		unknown, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "3", Secret: []byte("another")})
+		require.NoError(t, err)
+		unknownCache := cache.New(client, cache.WithHasher(unknown))
+		require.NoError(t, unknownCache.SyncKeys(ctx))
+		_, ok := unknownCache.FindByKey(key)
+		assert.False(t, ok)
+	})
+
+	t.Run("scheduled rehash runs in background", func(t *testing.T) {
+		next, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "4", Secret: []byte("next")}, types.Pepper{ID: "2", Secret: []byte("new pepper")})
+		require.NoError(t, err)
+		nextCache := cache.New(client, cache.WithHasher(next))
+		require.NoError(t, nextCache.SyncKeys(ctx))
+		token, ok := nextCache.FindByKey(key)
+		require.True(t, ok)
+
+		nextCache.ScheduleRehash(token, key.Payload())
+		stored, err := client.GetTokenByID(ctx, int64(cred.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "2", stored.PepperID, "not upgraded in request")
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go nextCache.RunRehash(runCtx)
+		assert.Eventually(t, func() bool {
+			stored, err := client.GetTokenByID(ctx, int64(cred.ID))
+			return err == nil && stored.PepperID == "4"
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			token, ok := nextCache.FindByKey(key)
+			return ok && token.DBToken.PepperID == "4"
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+// collidingStore reports that generated key IDs are already used for the first collisions lookups.
+type collidingStore struct {
+	dbo.Store
+	collisions int
+}
+
+func (s *collidingStore) FindTokenByKeyID(ctx context.Context, keyID types.KeyID) (*dbo.Token, error) {
+	if s.collisions > 0 {
+		s.collisions--
+		return &dbo.Token{KeyID: &keyID}, nil
+	}
+	return s.Store.FindTokenByKeyID(ctx, keyID)
+}
+
+func TestKeyIDCollision(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	store := &collidingStore{Store: client}
+	srv := server.New(store)
+	defaultID := defaultProjectFor(t, srv, userCtx)
+
+	t.Run("regenerate on collision", func(t *testing.T) {
+		store.collisions = 2
+		cred, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+		require.NoError(t, err)
+		assert.Zero(t, store.collisions)
+
+		store.collisions = 2
+		_, err = srv.RefreshToken(userCtx, api.RefreshTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		assert.Zero(t, store.collisions)
+	})
+
+	t.Run("give up after attempts", func(t *testing.T) {
+		store.collisions = 100
+		_, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+		require.Error(t, err)
+	})
+
+	t.Run("refreshed key replaces cached one", func(t *testing.T) {
+		store.collisions = 0
+		keysCache := cache.New(client)
+		cred, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: defaultID})
+		require.NoError(t, err)
+		require.NoError(t, keysCache.SyncKeys(ctx))
+		oldKey, err := types.ParseKey(cred.Key)
+		require.NoError(t, err)
+
+		fresh, err := srv.RefreshToken(userCtx, api.RefreshTokenParams{Token: cred.ID})
+		require.NoError(t, err)
+		require.NoError(t, keysCache.SyncKey(ctx, cred.ID))
+		newKey, err := types.ParseKey(fresh.Key)
+		require.NoError(t, err)
+
+		_, ok := keysCache.FindByKey(oldKey)
+		assert.False(t, ok)
+		_, ok = keysCache.FindByKey(newKey)
+		assert.True(t, ok)
+	})
+}
+
+func TestImportTokens(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "sqlite://:memory:?cache=shared", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	defaultID := defaultProjectFor(t, srv, userCtx)
+	config := api.TokenConfig{ProjectId: defaultID, Label: api.NewOptString("legacy")}
+
+	native, err := types.NewKey()
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte("gw_hashed.secret"))
+
+	results, err := srv.ImportTokens(userCtx, []api.ImportKey{
+		{Config: config, Key: api.NewOptString(native.String())},
+		{Config: config, Key: api.NewOptString("gw_raw0.secret"), KeyPrefix: api.NewOptString("gw_raw0")},
+		{Config: config, Hash: api.NewOptString(hex.EncodeToString(sum[:])), KeyPrefix: api.NewOptString("gw_hashed"),
+			HashAlgorithm: api.NewOptImportKeyHashAlgorithm(api.ImportKeyHashAlgorithmSHA256)},
+		// rejected
+		{Config: config, Key: api.NewOptString("gw_raw0.other"), KeyPrefix: api.NewOptString("gw_raw0")},
+		{Config: config, Key: api.NewOptString("gw_x.secret")},
+		{Config: config, Key: api.NewOptString("gw_raw1.secret"), Hash: api.NewOptString("00"), KeyPrefix: api.NewOptString("gw_raw1")},
+		{Config: config, Key: api.NewOptString("gw_raw1.secret"), KeyPrefix: api.NewOptString("other")},
+		{Config: config, Hash: api.NewOptString("00"), KeyPrefix: api.NewOptString("gw_raw1")},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 8)
+	for i, res := range results[:3] {
+		require.False(t, res.Error.Set, "%d: %s", i, res.Error.Value)
+		require.True(t, res.ID.Set)
+	}
+	assert.Equal(t, native.ID().String(), results[0].KeyID)
+	assert.Equal(t, types.ImportedKeyID("gw_raw0").String(), results[1].KeyID)
+	assert.Equal(t, types.ImportedKeyID("gw_raw0").String(), results[3].KeyID, "key ID is reported for duplicates")
+	for i, res := range results[3:] {
+		assert.True(t, res.Error.Set, i+3)
+		assert.False(t, res.ID.Set, i+3)
+	}
+
+	stored, err := srv.GetToken(userCtx, api.GetTokenParams{Token: results[1].ID.Value})
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", stored.Label)
+	assert.Equal(t, "gw_raw0", stored.KeyPrefix.Value)
+	stored, err = srv.GetToken(userCtx, api.GetTokenParams{Token: results[0].ID.Value})
+	require.NoError(t, err)
+	assert.False(t, stored.KeyPrefix.Set)
+
+	keysCache := cache.New(client)
+	require.NoError(t, keysCache.SyncKeys(ctx))
+
+	t.Run("imported keys are accepted", func(t *testing.T) {
+		token, ok := keysCache.FindByKey(native)
+		require.True(t, ok)
+		assert.Equal(t, int64(results[0].ID.Value), token.DBToken.ID)
+
+		token, ok = keysCache.FindImported("gw_raw0.secret")
+		require.True(t, ok)
+		assert.Equal(t, int64(results[1].ID.Value), token.DBToken.ID)
+
+		token, ok = keysCache.FindImported("gw_hashed.secret")
+		require.True(t, ok)
+		assert.Equal(t, int64(results[2].ID.Value), token.DBToken.ID)
+
+		_, ok = keysCache.FindImported("gw_raw0.other")
+		assert.False(t, ok)
+	})
+
+	t.Run("pre-computed hash is upgraded on use", func(t *testing.T) {
+		token, ok := keysCache.FindImported("gw_hashed.secret")
+		require.True(t, ok)
+		require.NoError(t, keysCache.Rehash(ctx, token, []byte("gw_hashed.secret")))
+
+		upgraded, err := client.GetTokenByID(ctx, int64(results[2].ID.Value))
+		require.NoError(t, err)
+		assert.Equal(t, types.HashSHA3, upgraded.HashAlg)
+		_, ok = keysCache.FindImported("gw_hashed.secret")
+		assert.True(t, ok)
+	})
+
+	t.Run("refresh issues native key", func(t *testing.T) {
+		cred, err := srv.RefreshToken(userCtx, api.RefreshTokenParams{Token: results[1].ID.Value})
+		require.NoError(t, err)
+		require.NoError(t, keysCache.SyncKey(ctx, cred.ID))
+
+		_, ok := keysCache.FindImported("gw_raw0.secret")
+		assert.False(t, ok)
+		key, err := types.ParseKey(cred.Key)
+		require.NoError(t, err)
+		_, ok = keysCache.FindByKey(key)
+		assert.True(t, ok)
+
+		refreshed, err := client.GetTokenByID(ctx, int64(cred.ID))
+		require.NoError(t, err)
+		assert.Equal(t, types.KeyNative, refreshed.KeyFormat)
+		assert.Empty(t, refreshed.KeyPrefix)
+	})
+}
+
+func TestDocumentTransfer(t *testing.T) {
+	ctx := context.Background()
+	src, err := open.Open(ctx, "sqlite://"+t.TempDir()+"/src.db", nil)
+	require.NoError(t, err)
+	defer src.Close()
+	dst, err := open.Open(ctx, "sqlite://"+t.TempDir()+"/dst.db", nil)
+	require.NoError(t, err)
+	defer dst.Close()
+
+	aliceCtx := utils.WithUser(ctx, "alice")
+	srcSrv := server.New(src)
+	defaultID := defaultProjectFor(t, srcSrv, aliceCtx)
+	cred, err := srcSrv.CreateToken(aliceCtx, &api.TokenConfig{ProjectId: defaultID, Label: api.NewOptString("ci")})
+	require.NoError(t, err)
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+
+	doc, err := srcSrv.ExportDocument(aliceCtx, api.ExportDocumentParams{Hashes: api.NewOptBool(true)})
+	require.NoError(t, err)
+	require.Len(t, doc.Tokens, 1)
+	assert.True(t, doc.Tokens[0].Hash.Set)
+
+	var updated []int
+	dstSrv := server.New(dst)
+	dstSrv.OnUpdate(func(id int) { updated = append(updated, id) })
+	bobCtx := utils.WithUser(ctx, "bob")
+
+	report, err := dstSrv.ImportDocument(bobCtx, doc, api.ImportDocumentParams{DryRun: api.NewOptBool(true)})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Empty(t, updated)
+
+	report, err = dstSrv.ImportDocument(bobCtx, doc, api.ImportDocumentParams{})
+	require.NoError(t, err)
+	require.Len(t, report.Tokens, 1)
+	assert.Equal(t, api.ImportChangeOpCreate, report.Tokens[0].Op)
+	assert.Equal(t, []int{report.Tokens[0].ID.Value}, updated)
+
+	token, err := dstSrv.GetTokenByKey(bobCtx, api.GetTokenByKeyParams{KeyID: key.ID().String()})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", token.User)
+	assert.Equal(t, "ci", token.Label)
+
+	_, err = dstSrv.ImportDocument(bobCtx, doc, api.ImportDocumentParams{})
+	require.Error(t, err, "conflicts fail by default")
+	report, err = dstSrv.ImportDocument(bobCtx, doc, api.ImportDocumentParams{Conflict: api.NewOptImportDocumentConflict(api.ImportDocumentConflictSkip)})
+	require.NoError(t, err)
+	assert.Equal(t, api.ImportChangeOpSkip, report.Tokens[0].Op)
+}
+
+func TestReader(t *testing.T) {
+	ctx := context.Background()
+	primary, err := open.Open(ctx, "memory://", nil)
+	require.NoError(t, err)
+	defer primary.Close()
+	replica := memory.NewStore()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(primary, server.WithReader(replica))
+	keys := cache.New(primary, cache.WithReader(replica))
+	srv.OnUpdate(func(id int) {
+		require.NoError(t, keys.SyncKey(ctx, id))
+	})
+	project, err := srv.CreateProject(userCtx, &api.ProjectConfig{Slug: ""})
+	require.NoError(t, err)
+	cred, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: project.ID})
+	require.NoError(t, err)
+	key, err := types.ParseKey(cred.Key)
+	require.NoError(t, err)
+
+	// replica is behind: listings are empty, while changes are already visible
+	list, err := listTokens(srv, userCtx, api.ListTokensParams{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	projects, err := srv.ListProjects(userCtx)
+	require.NoError(t, err)
+	assert.Empty(t, projects)
+	_, err = srv.GetToken(userCtx, api.GetTokenParams{Token: cred.ID})
+	require.NoError(t, err)
+	_, ok := keys.FindByKey(key)
+	assert.True(t, ok, "token is synced from primary")
+
+	require.NoError(t, keys.SyncKeys(ctx))
+	_, ok = keys.FindByKey(key)
+	assert.False(t, ok, "full sync uses replica")
+
+	snapshot, err := primary.Snapshot(ctx)
+	require.NoError(t, err)
+	require.NoError(t, replica.Restore(ctx, snapshot))
+
+	list, err = listTokens(srv, userCtx, api.ListTokensParams{})
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	require.NoError(t, keys.SyncKeys(ctx))
+	_, ok = keys.FindByKey(key)
+	assert.True(t, ok)
+}
+
+func TestSyncChanges(t *testing.T) {
+	ctx := context.Background()
+	client, err := open.Open(ctx, "memory://", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	userCtx := utils.WithUser(ctx, "tester")
+	srv := server.New(client)
+	// another instance is not notified about changes
+	keys := cache.New(client)
+	require.NoError(t, keys.SyncChanges(ctx), "first sync is full")
+
+	project, err := srv.CreateProject(userCtx, &api.ProjectConfig{Slug: ""})
+	require.NoError(t, err)
+	kept, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: project.ID, Label: api.NewOptString("kept")})
+	require.NoError(t, err)
+	deleted, err := srv.CreateToken(userCtx, &api.TokenConfig{ProjectId: project.ID})
+	require.NoError(t, err)
+	keptKey, err := types.ParseKey(kept.Key)
+	require.NoError(t, err)
+	deletedKey, err := types.ParseKey(deleted.Key)
+	require.NoError(t, err)
+
+	require.NoError(t, keys.SyncChanges(ctx))
+	_, ok := keys.FindByKey(keptKey)
+	assert.True(t, ok)
+	_, ok = keys.FindByKey(deletedKey)
+	assert.True(t, ok)
+
+	require.NoError(t, srv.UpdateToken(userCtx, &api.TokenPatch{Label: api.NewOptString("changed")}, api.UpdateTokenParams{Token: kept.ID}))
+	require.NoError(t, srv.DeleteToken(userCtx, api.DeleteTokenParams{Token: deleted.ID}))
+
+	require.NoError(t, keys.SyncChanges(ctx))
+	cached, ok := keys.FindByKey(keptKey)
+	require.True(t, ok)
+	assert.Equal(t, "changed", cached.DBToken.Label)
+	_, ok = keys.FindByKey(deletedKey)
+	assert.False(t, ok, "deleted token is dropped")
+}
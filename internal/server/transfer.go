@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/reddec/token-login/api"
+	"github.com/reddec/token-login/internal/transfer"
+	"github.com/reddec/token-login/internal/types"
+	"github.com/reddec/token-login/internal/utils"
+)
+
+func (srv *Server) ExportDocument(ctx context.Context, params api.ExportDocumentParams) (*api.Document, error) {
+	doc, err := transfer.Export(ctx, srv.reader, transfer.ExportOptions{
+		User:   utils.GetUser(ctx),
+		Hashes: params.Hashes.Value,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	return mapDocument(doc), nil
+}
+
+// ImportDocument imports the document on behalf of the user, regardless of owners in the document.
+func (srv *Server) ImportDocument(ctx context.Context, req *api.Document, params api.ImportDocumentParams) (*api.ImportReport, error) {
+	user := utils.GetUser(ctx)
+	report, err := transfer.Import(ctx, srv.store, parseDocument(req), transfer.ImportOptions{
+		User:      user,
+		Conflict:  transfer.Strategy(params.Conflict.Or(api.ImportDocumentConflictFail)),
+		DryRun:    params.DryRun.Value,
+		Hasher:    srv.hasher,
+		KeyPrefix: srv.keyPrefix,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("import: %w", err)
+	}
+	if !report.DryRun {
+		for _, c := range report.Projects {
+			if c.Op == transfer.OpUpdate {
+				if err := srv.notifyProjectUpdated(ctx, user, c.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, c := range report.Tokens {
+			if c.Op != transfer.OpSkip {
+				srv.notifyUpdated(int(c.ID))
+			}
+		}
+	}
+	return mapImportReport(report), nil
+}
+
+func mapDocument(doc *transfer.Document) *api.Document {
+	out := &api.Document{
+		Version:    doc.Version,
+		ExportedAt: api.NewOptDateTime(doc.ExportedAt),
+		Projects:   make([]api.DocumentProject, 0, len(doc.Projects)),
+		Tokens:     make([]api.DocumentToken, 0, len(doc.Tokens)),
+	}
+	for _, p := range doc.Projects {
+		out.Projects = append(out.Projects, api.DocumentProject{
+			User:        api.NewOptString(p.User),
+			Slug:        p.Slug,
+			Description: api.NewOptString(p.Description),
+			Aliases:     p.Aliases,
+			Hosts:       p.Hosts,
+			Paths:       p.Paths,
+			Headers:     mapHeaders(p.Headers),
+		})
+	}
+	for _, t := range doc.Tokens {
+		token := api.DocumentToken{
+			User:           api.NewOptString(t.User),
+			KeyID:          t.KeyID,
+			Label:          api.NewOptString(t.Label),
+			Project:        t.Project,
+			LinkedProjects: t.LinkedProjects,
+			Hosts:          t.Hosts,
+			Paths:          t.Paths,
+			Headers:        mapHeaders(t.Headers),
+			Meta:           api.NewOptMeta(mapMeta(t.Meta)),
+		}
+		if t.KeyFormat != "" {
+			token.KeyFormat = api.NewOptDocumentTokenKeyFormat(api.DocumentTokenKeyFormat(t.KeyFormat))
+		}
+		if t.KeyPrefix != "" {
+			token.KeyPrefix = api.NewOptString(t.KeyPrefix)
+		}
+		if t.Hash != nil {
+			token.Hash = api.NewOptDocumentHash(api.DocumentHash{
+				Algorithm: string(t.Hash.Algorithm),
+				PepperID:  api.NewOptString(t.Hash.PepperID),
+				Value:     t.Hash.Value,
+			})
+		}
+		if t.Disabled != nil {
+			token.Disabled = api.NewOptDocumentDisabled(api.DocumentDisabled{
+				At:     t.Disabled.At,
+				Reason: api.NewOptString(t.Disabled.Reason),
+			})
+		}
+		out.Tokens = append(out.Tokens, token)
+	}
+	return out
+}
+
+func parseDocument(doc *api.Document) *transfer.Document {
+	out := &transfer.Document{
+		Version:    doc.Version,
+		ExportedAt: doc.ExportedAt.Value,
+		Projects:   make([]transfer.Project, 0, len(doc.Projects)),
+		Tokens:     make([]transfer.Token, 0, len(doc.Tokens)),
+	}
+	for _, p := range doc.Projects {
+		out.Projects = append(out.Projects, transfer.Project{
+			User:        p.User.Value,
+			Slug:        p.Slug,
+			Description: p.Description.Value,
+			Aliases:     p.Aliases,
+			Hosts:       p.Hosts,
+			Paths:       p.Paths,
+			Headers:     parseHeaders(p.Headers),
+		})
+	}
+	for _, t := range doc.Tokens {
+		token := transfer.Token{
+			User:           t.User.Value,
+			KeyID:          t.KeyID,
+			KeyFormat:      types.KeyFormat(t.KeyFormat.Value),
+			KeyPrefix:      t.KeyPrefix.Value,
+			Label:          t.Label.Value,
+			Project:        t.Project,
+			LinkedProjects: t.LinkedProjects,
+			Hosts:          t.Hosts,
+			Paths:          t.Paths,
+			Headers:        parseHeaders(t.Headers),
+			Meta:           types.Meta(t.Meta.Value),
+		}
+		if h, ok := t.Hash.Get(); ok {
+			token.Hash = &transfer.Hash{
+				Algorithm: types.HashAlgorithm(h.Algorithm),
+				PepperID:  h.PepperID.Value,
+				Value:     h.Value,
+			}
+		}
+		if d, ok := t.Disabled.Get(); ok {
+			token.Disabled = &transfer.Disabled{At: d.At, Reason: d.Reason.Value}
+		}
+		out.Tokens = append(out.Tokens, token)
+	}
+	return out
+}
+
+func mapImportReport(report *transfer.Report) *api.ImportReport {
+	out := &api.ImportReport{
+		DryRun:   report.DryRun,
+		Projects: make([]api.ImportChange, 0, len(report.Projects)),
+		Tokens:   make([]api.ImportChange, 0, len(report.Tokens)),
+	}
+	for _, c := range report.Projects {
+		change := mapImportChange(c)
+		change.Project = api.NewOptString(c.Project)
+		out.Projects = append(out.Projects, change)
+	}
+	for _, c := range report.Tokens {
+		change := mapImportChange(c)
+		change.KeyID = api.NewOptString(c.KeyID)
+		if c.Key != "" {
+			change.Key = api.NewOptString(c.Key)
+		}
+		out.Tokens = append(out.Tokens, change)
+	}
+	return out
+}
+
+func mapImportChange(c transfer.Change) api.ImportChange {
+	out := api.ImportChange{
+		Op:   api.ImportChangeOp(c.Op),
+		User: c.User,
+	}
+	if c.ID != 0 {
+		out.ID = api.NewOptInt(int(c.ID))
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+// Package transfer exports and imports projects and tokens as a versioned document,
+// for backups and cloning environments. Secrets are never exported; hashes only on request.
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/reddec/token-login/internal/types"
+)
+
+// Version of the document format.
+const Version = 1
+
+var (
+	ErrVersion     = errors.New("unsupported document version")
+	ErrConflict    = errors.New("conflicts with existing data")
+	errFormat      = errors.New("unknown document format")
+	errUnknownSlug = errors.New("unknown project")
+	errDuplicated  = errors.New("duplicated in document")
+
+	errImportedNoHash = errors.New("imported key can't be restored without hash")
+	errKeyIDPrefix    = errors.New("key ID doesn't match key prefix")
+	errHashSize       = errors.New("hash size doesn't match algorithm")
+)
+
+// Format of the serialized document.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// Document is a snapshot of projects and tokens. Projects are referenced by user and slug,
+// tokens are identified by key ID, so the document can be imported to another database.
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Projects   []Project `json:"projects"`
+	Tokens     []Token   `json:"tokens"`
+}
+
+// Project with its defaults. Empty slug is the default project of the user.
+type Project struct {
+	User        string        `json:"user"`
+	Slug        string        `json:"slug"`
+	Description string        `json:"description,omitempty"`
+	Aliases     []string      `json:"aliases,omitempty"`
+	Hosts       []string      `json:"hosts,omitempty"`
+	Paths       []string      `json:"paths,omitempty"`
+	Headers     types.Headers `json:"headers,omitempty"`
+}
+
+// Token config. Tokens without hash get a new secret (with the same key ID) on import.
+type Token struct {
+	User           string          `json:"user"`
+	KeyID          string          `json:"keyID"`
+	KeyFormat      types.KeyFormat `json:"keyFormat,omitempty"`
+	KeyPrefix      string          `json:"keyPrefix,omitempty"`
+	Label          string          `json:"label,omitempty"`
+	Project        string          `json:"project"`
+	LinkedProjects []string        `json:"linkedProjects,omitempty"`
+	Hosts          []string        `json:"hosts,omitempty"`
+	Paths          []string        `json:"paths,omitempty"`
+	Headers        types.Headers   `json:"headers,omitempty"`
+	Meta           types.Meta      `json:"meta,omitempty"`
+	Hash           *Hash           `json:"hash,omitempty"`
+	// Disabled is set for disabled (for example, leaked) tokens. They stay disabled after import
+	// unless they get a new secret.
+	Disabled *Disabled `json:"disabled,omitempty"`
+}
+
+// Disabled state of the token.
+type Disabled struct {
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Hash of the token key.
+type Hash struct {
+	Algorithm types.HashAlgorithm `json:"algorithm"`
+	PepperID  string              `json:"pepperID,omitempty"`
+	// Value is hex-encoded.
+	Value string `json:"value"`
+}
+
+// Encode document in the format.
+func (doc *Document) Encode(w io.Writer, format Format) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal document: %w", err)
+	}
+	switch format {
+	case JSON:
+		data = append(data, '\n')
+	case YAML:
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return fmt.Errorf("convert to yaml: %w", err)
+		}
+	default:
+		return fmt.Errorf("%q: %w", format, errFormat)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write document: %w", err)
+	}
+	return nil
+}
+
+// Decode document in the format and check its version.
+func Decode(r io.Reader, format Format) (*Document, error) {
+	var doc Document
+	if err := Unmarshal(r, format, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != Version {
+		return nil, fmt.Errorf("%d: %w", doc.Version, ErrVersion)
+	}
+	return &doc, nil
+}
+
+// Unmarshal JSON or YAML into v. Field names are the same for both formats (JSON tags).
+func Unmarshal(r io.Reader, format Format, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read document: %w", err)
+	}
+	switch format {
+	case JSON:
+	case YAML:
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return fmt.Errorf("convert from yaml: %w", err)
+		}
+	default:
+		return fmt.Errorf("%q: %w", format, errFormat)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshal document: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package transfer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/types"
+)
+
+// ExportOptions select what is exported.
+type ExportOptions struct {
+	// User limits export to projects and tokens of the user; empty means all users.
+	User string
+	// Hashes adds key hashes, so restored tokens keep their keys.
+	Hashes bool
+}
+
+// Export projects and tokens from the store.
+func Export(ctx context.Context, store dbo.Store, opts ExportOptions) (*Document, error) {
+	projects, err := listProjects(ctx, store, opts.User)
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	tokens, err := listTokens(ctx, store, opts.User)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	doc := &Document{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Projects:   make([]Project, 0, len(projects)),
+		Tokens:     make([]Token, 0, len(tokens)),
+	}
+	for _, p := range projects {
+		doc.Projects = append(doc.Projects, Project{
+			User:        p.User,
+			Slug:        p.Slug,
+			Description: p.Description,
+			Aliases:     p.Aliases,
+			Hosts:       p.Hosts,
+			Paths:       p.Paths,
+			Headers:     p.Headers,
+		})
+	}
+	for _, t := range tokens {
+		out := Token{
+			User:      t.User,
+			KeyID:     t.KeyID.String(),
+			KeyFormat: t.KeyFormat,
+			KeyPrefix: t.KeyPrefix,
+			Label:     t.Label,
+			Project:   t.ProjectSlug,
+			Hosts:     t.Hosts,
+			Paths:     t.Paths,
+			Headers:   t.Headers,
+			Meta:      t.Meta,
+		}
+		if t.DisabledAt != nil {
+			out.Disabled = &Disabled{At: *t.DisabledAt, Reason: t.DisabledReason}
+		}
+		for _, ref := range t.LinkedProjects {
+			out.LinkedProjects = append(out.LinkedProjects, ref.Slug)
+		}
+		if opts.Hashes {
+			alg := t.HashAlg
+			if alg == "" {
+				alg = types.HashSHA3
+			}
+			out.Hash = &Hash{
+				Algorithm: alg,
+				PepperID:  t.PepperID,
+				Value:     hex.EncodeToString(t.Hash),
+			}
+		}
+		doc.Tokens = append(doc.Tokens, out)
+	}
+	return doc, nil
+}
+
+// listProjects of the user, or of all users if the user is empty.
+func listProjects(ctx context.Context, store dbo.Store, user string) ([]*dbo.Project, error) {
+	if user == "" {
+		return store.ListAllProjects(ctx) //nolint:wrapcheck // the caller adds context
+	}
+	return store.ListProjects(ctx, user) //nolint:wrapcheck // the caller adds context
+}
+
+// listTokens of the user in order of creation, or of all users if the user is empty.
+func listTokens(ctx context.Context, store dbo.Store, user string) ([]*dbo.Token, error) {
+	if user == "" {
+		return store.ListAllTokens(ctx) //nolint:wrapcheck // the caller adds context
+	}
+	return store.ListTokens(ctx, dbo.ListTokensParams{User: user, Asc: true}) //nolint:wrapcheck // the caller adds context
+}
@@ -0,0 +1,460 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/types"
+)
+
+// Strategy for projects and tokens which already exist.
+type Strategy string
+
+const (
+	// Fail aborts import before any change if anything already exists.
+	Fail Strategy = "fail"
+	// Skip leaves existing projects and tokens as is.
+	Skip Strategy = "skip"
+	// Overwrite replaces config of existing projects and tokens. Extra aliases are kept.
+	Overwrite Strategy = "overwrite"
+)
+
+// Op is an import action for a single project or token.
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpSkip   Op = "skip"
+)
+
+// ImportOptions control import.
+type ImportOptions struct {
+	// User overrides owner of all projects and tokens, if set.
+	User string
+	// Conflict strategy, Fail by default.
+	Conflict Strategy
+	// DryRun only plans changes.
+	DryRun bool
+	// Hasher and KeyPrefix are used for new secrets of tokens without hash.
+	// Imported hashes must be verifiable by the Hasher.
+	Hasher    *types.Hasher
+	KeyPrefix string
+	// AnyPepper accepts keyed hashes with unknown peppers, for stores which don't verify keys.
+	AnyPepper bool
+}
+
+// Change is planned (dry-run) or made for a project or a token.
+type Change struct {
+	Op   Op     `json:"op"`
+	User string `json:"user"`
+	// Project slug for project changes.
+	Project string `json:"project,omitempty"`
+	// KeyID for token changes.
+	KeyID string `json:"keyID,omitempty"`
+	// ID of the row; unknown for new rows in dry-run.
+	ID int64 `json:"id,omitempty"`
+	// Key is a new secret of token imported without hash. It's shown only once.
+	Key string `json:"key,omitempty"`
+}
+
+// Report of import in the document order.
+type Report struct {
+	DryRun   bool     `json:"dryRun"`
+	Projects []Change `json:"projects"`
+	Tokens   []Change `json:"tokens"`
+}
+
+// Import projects and tokens into the store. The whole document is validated and
+// conflicts are resolved before any change, so invalid documents (or conflicts with
+// the Fail strategy) leave the store untouched. Import is not atomic otherwise,
+// but it's safe to re-run with the Skip or Overwrite strategy.
+//
+// Running servers pick up the changes on the next cache sync.
+func Import(ctx context.Context, store dbo.Store, doc *Document, opts ImportOptions) (*Report, error) {
+	if doc.Version != Version {
+		return nil, fmt.Errorf("%d: %w", doc.Version, ErrVersion)
+	}
+	if opts.Conflict == "" {
+		opts.Conflict = Fail
+	}
+	if opts.Hasher == nil {
+		opts.Hasher = types.DefaultHasher
+	}
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = types.DefaultKeyPrefix
+	}
+	im := &importer{store: store, opts: opts, projectIDs: make(map[projectRef]int64)}
+	if err := im.plan(ctx, doc); err != nil {
+		return nil, err
+	}
+	report := &Report{DryRun: opts.DryRun}
+	if !opts.DryRun {
+		if err := im.apply(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range im.projects {
+		report.Projects = append(report.Projects, p.change)
+	}
+	for _, t := range im.tokens {
+		report.Tokens = append(report.Tokens, t.change)
+	}
+	return report, nil
+}
+
+type projectRef struct {
+	user string
+	slug string
+}
+
+type plannedProject struct {
+	change   Change
+	src      Project
+	existing *dbo.Project
+}
+
+type plannedToken struct {
+	change   Change
+	src      Token
+	kid      types.KeyID
+	hash     []byte
+	spec     types.HashSpec
+	existing *dbo.Token
+}
+
+type importer struct {
+	store    dbo.Store
+	opts     ImportOptions
+	projects []*plannedProject
+	tokens   []*plannedToken
+	// projectIDs resolves slugs and aliases; new projects are added during apply.
+	projectIDs map[projectRef]int64
+}
+
+func (im *importer) owner(user string) string {
+	if im.opts.User != "" {
+		return im.opts.User
+	}
+	return user
+}
+
+// resolve returns action for existing row according to the strategy.
+func (im *importer) resolve(exists bool) (Op, error) {
+	if !exists {
+		return OpCreate, nil
+	}
+	switch im.opts.Conflict {
+	case Skip:
+		return OpSkip, nil
+	case Overwrite:
+		return OpUpdate, nil
+	default:
+		return "", ErrConflict
+	}
+}
+
+//nolint:cyclop,gocognit // validation is flat list of checks
+func (im *importer) plan(ctx context.Context, doc *Document) error {
+	projects, err := im.store.ListAllProjects(ctx)
+	if err != nil {
+		return fmt.Errorf("list projects: %w", err)
+	}
+	existing := make(map[projectRef]*dbo.Project)
+	for _, p := range projects {
+		existing[projectRef{p.User, p.Slug}] = p
+		im.projectIDs[projectRef{p.User, p.Slug}] = p.ID
+		for _, alias := range p.Aliases {
+			existing[projectRef{p.User, alias}] = p
+			im.projectIDs[projectRef{p.User, alias}] = p.ID
+		}
+	}
+
+	var errs []error
+	known := make(map[projectRef]bool)
+	for ref := range existing {
+		known[ref] = true
+	}
+	planned := make(map[projectRef]bool)
+	for _, p := range doc.Projects {
+		user := im.owner(p.User)
+		ref := projectRef{user, p.Slug}
+		if planned[ref] {
+			errs = append(errs, fmt.Errorf("project %q of %q: %w", p.Slug, user, errDuplicated))
+			continue
+		}
+		planned[ref] = true
+		if err := validateRules(p.Hosts, p.Paths, p.Headers); err != nil {
+			errs = append(errs, fmt.Errorf("project %q of %q: %w", p.Slug, user, err))
+			continue
+		}
+		current := existing[ref]
+		op, err := im.resolve(current != nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("project %q of %q: %w", p.Slug, user, err))
+			continue
+		}
+		for _, alias := range p.Aliases {
+			if owner := existing[projectRef{user, alias}]; owner != nil && owner != current {
+				errs = append(errs, fmt.Errorf("alias %q of project %q of %q: %w", alias, p.Slug, user, dbo.ErrSlugInUse))
+			}
+			known[projectRef{user, alias}] = true
+		}
+		known[ref] = true
+		change := Change{Op: op, User: user, Project: p.Slug}
+		if current != nil {
+			change.ID = current.ID
+		}
+		im.projects = append(im.projects, &plannedProject{change: change, src: p, existing: current})
+	}
+
+	tokens, err := im.store.ListAllTokens(ctx)
+	if err != nil {
+		return fmt.Errorf("list tokens: %w", err)
+	}
+	byKeyID := make(map[types.KeyID]*dbo.Token, len(tokens))
+	for _, t := range tokens {
+		byKeyID[*t.KeyID] = t
+	}
+	seen := make(map[types.KeyID]bool)
+	for _, t := range doc.Tokens {
+		user := im.owner(t.User)
+		pt, err := im.planToken(t, user, known)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("token %s of %q: %w", t.KeyID, user, err))
+			continue
+		}
+		if seen[pt.kid] {
+			errs = append(errs, fmt.Errorf("token %s of %q: %w", t.KeyID, user, errDuplicated))
+			continue
+		}
+		seen[pt.kid] = true
+		current := byKeyID[pt.kid]
+		if current != nil && current.User != user {
+			errs = append(errs, fmt.Errorf("token %s of %q: key ID is used by another user: %w", t.KeyID, user, ErrConflict))
+			continue
+		}
+		op, err := im.resolve(current != nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("token %s of %q: %w", t.KeyID, user, err))
+			continue
+		}
+		pt.change = Change{Op: op, User: user, KeyID: pt.kid.String()}
+		if current != nil {
+			pt.change.ID = current.ID
+		}
+		pt.existing = current
+		im.tokens = append(im.tokens, pt)
+	}
+	return errors.Join(errs...)
+}
+
+func (im *importer) planToken(t Token, user string, known map[projectRef]bool) (*plannedToken, error) {
+	kid, err := types.ParseKeyID(t.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("parse key ID: %w", err)
+	}
+	if err := t.KeyFormat.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateRules(t.Hosts, t.Paths, t.Headers); err != nil {
+		return nil, err
+	}
+	for _, slug := range append([]string{t.Project}, t.LinkedProjects...) {
+		if !known[projectRef{user, slug}] {
+			return nil, fmt.Errorf("%q: %w", slug, errUnknownSlug)
+		}
+	}
+	pt := &plannedToken{src: t, kid: kid}
+	if t.Hash == nil {
+		if t.KeyFormat == types.KeyImported {
+			return nil, errImportedNoHash
+		}
+		return pt, nil
+	}
+	if t.KeyFormat == types.KeyImported {
+		if err := types.ValidateImportedPrefix(t.KeyPrefix); err != nil {
+			return nil, err
+		}
+		if types.ImportedKeyID(t.KeyPrefix) != kid {
+			return nil, errKeyIDPrefix
+		}
+	}
+	pt.hash, err = hex.DecodeString(t.Hash.Value)
+	if err != nil {
+		return nil, fmt.Errorf("decode hash: %w", err)
+	}
+	pt.spec = types.HashSpec{Algorithm: t.Hash.Algorithm, PepperID: t.Hash.PepperID}
+	if err := im.opts.Hasher.Supports(pt.spec); err != nil && !(im.opts.AnyPepper && errors.Is(err, types.ErrUnknownPepper)) {
+		return nil, err
+	}
+	if len(pt.hash) != pt.spec.Algorithm.Size() {
+		return nil, fmt.Errorf("%d bytes of %s: %w", len(pt.hash), pt.spec.Algorithm, errHashSize)
+	}
+	return pt, nil
+}
+
+func (im *importer) apply(ctx context.Context) error {
+	for _, p := range im.projects {
+		if err := im.applyProject(ctx, p); err != nil {
+			return fmt.Errorf("project %q of %q: %w", p.src.Slug, p.change.User, err)
+		}
+	}
+	for _, t := range im.tokens {
+		if err := im.applyToken(ctx, t); err != nil {
+			return fmt.Errorf("token %s of %q: %w", t.src.KeyID, t.change.User, err)
+		}
+	}
+	return nil
+}
+
+func (im *importer) applyProject(ctx context.Context, p *plannedProject) error {
+	user := p.change.User
+	var current []string
+	switch p.change.Op {
+	case OpSkip:
+		return nil
+	case OpCreate:
+		created, err := im.store.CreateProject(ctx, dbo.CreateProjectParams{
+			User:        user,
+			Slug:        p.src.Slug,
+			Description: p.src.Description,
+			Hosts:       p.src.Hosts,
+			Paths:       p.src.Paths,
+			Headers:     p.src.Headers,
+		})
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		p.change.ID = created.ID
+	case OpUpdate:
+		_, err := im.store.UpdateProject(ctx, dbo.UpdateProjectParams{
+			User:        user,
+			ID:          p.change.ID,
+			Description: &p.src.Description,
+			Hosts:       &p.src.Hosts,
+			Paths:       &p.src.Paths,
+			Headers:     &p.src.Headers,
+		})
+		if err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		current = append([]string{p.existing.Slug}, p.existing.Aliases...)
+	}
+	im.projectIDs[projectRef{user, p.src.Slug}] = p.change.ID
+	for _, alias := range p.src.Aliases {
+		if slices.Contains(current, alias) {
+			continue
+		}
+		if err := im.store.AddProjectAlias(ctx, user, p.change.ID, alias); err != nil {
+			return fmt.Errorf("add alias: %w", err)
+		}
+		im.projectIDs[projectRef{user, alias}] = p.change.ID
+	}
+	return nil
+}
+
+func (im *importer) applyToken(ctx context.Context, t *plannedToken) error {
+	if t.change.Op == OpSkip {
+		return nil
+	}
+	user := t.change.User
+	projectID := im.projectIDs[projectRef{user, t.src.Project}]
+	linked := make([]int64, 0, len(t.src.LinkedProjects))
+	for _, slug := range t.src.LinkedProjects {
+		if id := im.projectIDs[projectRef{user, slug}]; id != projectID && !slices.Contains(linked, id) {
+			linked = append(linked, id)
+		}
+	}
+
+	if t.change.Op == OpUpdate {
+		_, err := im.store.UpdateToken(ctx, dbo.UpdateTokenParams{
+			User:             user,
+			ID:               t.change.ID,
+			Hosts:            &t.src.Hosts,
+			Paths:            &t.src.Paths,
+			Label:            &t.src.Label,
+			Headers:          &t.src.Headers,
+			Meta:             &t.src.Meta,
+			ProjectID:        &projectID,
+			LinkedProjectIDs: &linked,
+		})
+		if err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		if t.hash != nil && !bytes.Equal(t.hash, t.existing.Hash) {
+			if _, err := im.store.RehashToken(ctx, t.change.ID, t.existing.Hash, t.hash, t.spec); err != nil {
+				return fmt.Errorf("replace hash: %w", err)
+			}
+		}
+		return im.disable(ctx, t)
+	}
+
+	params := dbo.CreateTokenParams{
+		User:             user,
+		Hash:             t.hash,
+		HashSpec:         t.spec,
+		KeyID:            &t.kid,
+		KeyFormat:        t.src.KeyFormat,
+		KeyPrefix:        t.src.KeyPrefix,
+		Label:            t.src.Label,
+		Hosts:            t.src.Hosts,
+		Paths:            t.src.Paths,
+		Headers:          t.src.Headers,
+		Meta:             t.src.Meta,
+		ProjectID:        projectID,
+		LinkedProjectIDs: linked,
+	}
+	if t.hash == nil {
+		key, err := types.NewKeyFor(t.kid)
+		if err != nil {
+			return err
+		}
+		params.HashSpec = im.opts.Hasher.Preferred()
+		params.Hash, err = im.opts.Hasher.Hash(params.HashSpec, key.Payload())
+		if err != nil {
+			return fmt.Errorf("hash key: %w", err)
+		}
+		params.KeyFormat = types.KeyNative
+		params.KeyPrefix = ""
+		t.change.Key = key.Format(im.opts.KeyPrefix)
+	}
+	created, err := im.store.CreateToken(ctx, params)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	t.change.ID = created.ID
+	return im.disable(ctx, t)
+}
+
+// disable the token if it was disabled in the document. New secrets are not compromised,
+// so tokens imported without hash stay active.
+func (im *importer) disable(ctx context.Context, t *plannedToken) error {
+	if t.src.Disabled == nil || t.change.Key != "" {
+		return nil
+	}
+	at := t.src.Disabled.At
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	if _, err := im.store.DisableToken(ctx, t.change.ID, t.src.Disabled.Reason, at); err != nil {
+		return fmt.Errorf("disable: %w", err)
+	}
+	return nil
+}
+
+func validateRules(hosts, paths []string, headers types.Headers) error {
+	if _, err := types.NewAccessKey(nil, hosts, paths); err != nil {
+		return fmt.Errorf("validate rules: %w", err)
+	}
+	if err := types.ValidateHeaders(headers); err != nil {
+		return fmt.Errorf("validate headers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/dbo/open"
+	"github.com/reddec/token-login/internal/transfer"
+	"github.com/reddec/token-login/internal/types"
+)
+
+func openStore(t *testing.T, name string) dbo.Store {
+	t.Helper()
+	store, err := open.Open(context.Background(), "sqlite://"+filepath.Join(t.TempDir(), name+".db"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// seed creates default and "billing" (alias "payments") projects and a token in both of them.
+func seed(t *testing.T, store dbo.Store) (types.Key, *dbo.Token) {
+	t.Helper()
+	ctx := context.Background()
+	def, err := store.CreateProject(ctx, dbo.CreateProjectParams{User: "alice", Slug: ""})
+	require.NoError(t, err)
+	billing, err := store.CreateProject(ctx, dbo.CreateProjectParams{
+		User:    "alice",
+		Slug:    "payments",
+		Hosts:   []string{"billing.example.com"},
+		Headers: types.Headers{{Name: "X-Team", Value: "billing"}},
+	})
+	require.NoError(t, err)
+	slug := "billing"
+	_, err = store.UpdateProject(ctx, dbo.UpdateProjectParams{User: "alice", ID: billing.ID, Slug: &slug})
+	require.NoError(t, err)
+
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	kid := key.ID()
+	token, err := store.CreateToken(ctx, dbo.CreateTokenParams{
+		User:             "alice",
+		Hash:             key.Hash(),
+		HashSpec:         types.HashSpec{Algorithm: types.HashSHA3},
+		KeyID:            &kid,
+		Label:            "ci",
+		Paths:            []string{"/api/**"},
+		Meta:             types.Meta{"team": "core"},
+		ProjectID:        billing.ID,
+		LinkedProjectIDs: []int64{def.ID},
+	})
+	require.NoError(t, err)
+	return key, token
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, "src")
+	key, _ := seed(t, src)
+
+	doc, err := transfer.Export(ctx, src, transfer.ExportOptions{Hashes: true})
+	require.NoError(t, err)
+	require.Len(t, doc.Projects, 2)
+	require.Len(t, doc.Tokens, 1)
+	assert.Equal(t, "billing", doc.Tokens[0].Project)
+	assert.Equal(t, []string{""}, doc.Tokens[0].LinkedProjects)
+	require.NotNil(t, doc.Tokens[0].Hash)
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.Encode(&buf, transfer.YAML))
+	decoded, err := transfer.Decode(&buf, transfer.YAML)
+	require.NoError(t, err)
+
+	dst := openStore(t, "dst")
+	report, err := transfer.Import(ctx, dst, decoded, transfer.ImportOptions{})
+	require.NoError(t, err)
+	require.Len(t, report.Tokens, 1)
+	assert.Equal(t, transfer.OpCreate, report.Tokens[0].Op)
+	assert.Empty(t, report.Tokens[0].Key, "hash is restored, no new key")
+
+	restored, err := dst.FindTokenByKeyID(ctx, key.ID())
+	require.NoError(t, err)
+	assert.Equal(t, "alice", restored.User)
+	assert.Equal(t, "ci", restored.Label)
+	assert.Equal(t, "billing", restored.ProjectSlug)
+	assert.Equal(t, []string{"payments"}, restored.ProjectAliases)
+	assert.Equal(t, []string{"billing.example.com"}, restored.ProjectHosts)
+	assert.Equal(t, types.Meta{"team": "core"}, restored.Meta)
+	require.Len(t, restored.LinkedProjects, 1)
+	assert.Empty(t, restored.LinkedProjects[0].Slug)
+	assert.True(t, types.DefaultHasher.Verify(restored.HashSpec(), key.Payload(), restored.Hash))
+
+	t.Run("fail on conflict keeps store untouched", func(t *testing.T) {
+		changed := *decoded
+		changed.Tokens = []transfer.Token{decoded.Tokens[0]}
+		changed.Tokens[0].Label = "changed"
+		_, err := transfer.Import(ctx, dst, &changed, transfer.ImportOptions{})
+		require.ErrorIs(t, err, transfer.ErrConflict)
+
+		token, err := dst.FindTokenByKeyID(ctx, key.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "ci", token.Label)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		report, err := transfer.Import(ctx, dst, decoded, transfer.ImportOptions{Conflict: transfer.Skip})
+		require.NoError(t, err)
+		for _, c := range append(report.Projects, report.Tokens...) {
+			assert.Equal(t, transfer.OpSkip, c.Op)
+		}
+	})
+
+	t.Run("dry-run overwrite", func(t *testing.T) {
+		changed := *decoded
+		changed.Tokens = []transfer.Token{decoded.Tokens[0]}
+		changed.Tokens[0].Label = "changed"
+		report, err := transfer.Import(ctx, dst, &changed, transfer.ImportOptions{Conflict: transfer.Overwrite, DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, transfer.OpUpdate, report.Tokens[0].Op)
+
+		token, err := dst.FindTokenByKeyID(ctx, key.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "ci", token.Label)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		changed := *decoded
+		changed.Tokens = []transfer.Token{decoded.Tokens[0]}
+		changed.Tokens[0].Label = "changed"
+		changed.Tokens[0].Project = ""
+		changed.Tokens[0].LinkedProjects = nil
+		_, err := transfer.Import(ctx, dst, &changed, transfer.ImportOptions{Conflict: transfer.Overwrite})
+		require.NoError(t, err)
+
+		token, err := dst.FindTokenByKeyID(ctx, key.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "changed", token.Label)
+		assert.Empty(t, token.ProjectSlug)
+		assert.Empty(t, token.LinkedProjects)
+		assert.True(t, types.DefaultHasher.Verify(token.HashSpec(), key.Payload(), token.Hash))
+	})
+}
+
+func TestExportDisabled(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, "src")
+	key, token := seed(t, src)
+	disabledAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	_, err := src.DisableToken(ctx, token.ID, "leaked", disabledAt)
+	require.NoError(t, err)
+
+	doc, err := transfer.Export(ctx, src, transfer.ExportOptions{Hashes: true})
+	require.NoError(t, err)
+	require.NotNil(t, doc.Tokens[0].Disabled)
+	assert.Equal(t, "leaked", doc.Tokens[0].Disabled.Reason)
+	assert.True(t, disabledAt.Equal(doc.Tokens[0].Disabled.At))
+
+	dst := openStore(t, "dst")
+	_, err = transfer.Import(ctx, dst, doc, transfer.ImportOptions{})
+	require.NoError(t, err)
+	restored, err := dst.FindTokenByKeyID(ctx, key.ID())
+	require.NoError(t, err)
+	assert.True(t, restored.Disabled())
+	assert.Equal(t, "leaked", restored.DisabledReason)
+	require.NotNil(t, restored.DisabledAt)
+	assert.True(t, disabledAt.Equal(*restored.DisabledAt), "disable time is kept")
+
+	// new secret is not compromised
+	doc.Tokens[0].Hash = nil
+	fresh := openStore(t, "fresh")
+	_, err = transfer.Import(ctx, fresh, doc, transfer.ImportOptions{})
+	require.NoError(t, err)
+	restored, err = fresh.FindTokenByKeyID(ctx, key.ID())
+	require.NoError(t, err)
+	assert.False(t, restored.Disabled())
+}
+
+func TestImportWithoutHashes(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, "src")
+	key, _ := seed(t, src)
+
+	doc, err := transfer.Export(ctx, src, transfer.ExportOptions{User: "alice"})
+	require.NoError(t, err)
+	assert.Nil(t, doc.Tokens[0].Hash)
+
+	dst := openStore(t, "dst")
+	report, err := transfer.Import(ctx, dst, doc, transfer.ImportOptions{User: "bob"})
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Tokens[0].Key)
+
+	issued, err := types.ParseKey(report.Tokens[0].Key)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID(), issued.ID(), "key ID is kept")
+	assert.NotEqual(t, key, issued)
+
+	token, err := dst.FindTokenByKeyID(ctx, key.ID())
+	require.NoError(t, err)
+	assert.Equal(t, "bob", token.User)
+	assert.True(t, types.DefaultHasher.Verify(token.HashSpec(), issued.Payload(), token.Hash))
+}
+
+func TestImportValidation(t *testing.T) {
+	ctx := context.Background()
+	dst := openStore(t, "dst")
+
+	doc := &transfer.Document{
+		Version:  transfer.Version,
+		Projects: []transfer.Project{{User: "alice", Slug: "web"}},
+		Tokens: []transfer.Token{
+			{User: "alice", KeyID: types.ImportedKeyID("sk_live").String(), Project: "web"},
+			{User: "alice", KeyID: "AAAAAAAAAAAAA", Project: "unknown"},
+		},
+	}
+	_, err := transfer.Import(ctx, dst, doc, transfer.ImportOptions{})
+	require.Error(t, err)
+
+	projects, err := dst.ListAllProjects(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, projects, "nothing is written for invalid documents")
+
+	_, err = transfer.Import(ctx, dst, &transfer.Document{Version: 2}, transfer.ImportOptions{})
+	require.ErrorIs(t, err, transfer.ErrVersion)
+}
+
+func TestImportHashValidation(t *testing.T) {
+	ctx := context.Background()
+	dst := openStore(t, "dst")
+	key, err := types.NewKey()
+	require.NoError(t, err)
+
+	importHash := func(hash transfer.Hash, dryRun bool) error {
+		doc := &transfer.Document{
+			Version:  transfer.Version,
+			Projects: []transfer.Project{{User: "alice", Slug: "web"}},
+			Tokens:   []transfer.Token{{User: "alice", KeyID: key.ID().String(), Project: "web", Hash: &hash}},
+		}
+		_, err := transfer.Import(ctx, dst, doc, transfer.ImportOptions{DryRun: dryRun})
+		return err
+	}
+	valid := hex.EncodeToString(key.Hash())
+	for _, dryRun := range []bool{true, false} {
+		require.ErrorIs(t, importHash(transfer.Hash{Algorithm: "md5", Value: valid}, dryRun), types.ErrUnknownHash)
+		require.ErrorIs(t, importHash(transfer.Hash{Algorithm: types.HashHMAC, PepperID: "gone", Value: valid[:64]}, dryRun), types.ErrUnknownPepper)
+		require.Error(t, importHash(transfer.Hash{Algorithm: types.HashSHA3, Value: valid[:64]}, dryRun), "truncated hash")
+	}
+	tokens, err := dst.ListAllTokens(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	require.NoError(t, importHash(transfer.Hash{Algorithm: types.HashSHA3, Value: valid}, false))
+}
+
+func TestExportUser(t *testing.T) {
+	ctx := context.Background()
+	src := openStore(t, "src")
+	key, _ := seed(t, src)
+	_, err := src.CreateProject(ctx, dbo.CreateProjectParams{User: "bob", Slug: "bob"})
+	require.NoError(t, err)
+
+	doc, err := transfer.Export(ctx, src, transfer.ExportOptions{User: "alice", Hashes: true})
+	require.NoError(t, err)
+	require.Len(t, doc.Projects, 2)
+	for _, p := range doc.Projects {
+		assert.Equal(t, "alice", p.User)
+	}
+	require.Len(t, doc.Tokens, 1)
+	assert.Equal(t, key.ID().String(), doc.Tokens[0].KeyID)
+	assert.Equal(t, []string{""}, doc.Tokens[0].LinkedProjects)
+	require.NotNil(t, doc.Tokens[0].Hash)
+	assert.Equal(t, hex.EncodeToString(key.Hash()), doc.Tokens[0].Hash.Value)
+}
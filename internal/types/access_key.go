@@ -1,14 +1,21 @@
 package types
 
 import (
-	"crypto/sha3"
-	"crypto/subtle"
 	"fmt"
 
 	"github.com/gobwas/glob"
 )
 
+// NewAccessKey creates access key for SHA3-384 hash.
 func NewAccessKey(hash []byte, hosts, paths []string) (*AccessKey, error) {
+	return NewHashedAccessKey(DefaultHasher, HashSpec{Algorithm: HashSHA3}, hash, hosts, paths)
+}
+
+// NewHashedAccessKey creates access key for hash made according to the spec.
+func NewHashedAccessKey(hasher *Hasher, spec HashSpec, hash []byte, hosts, paths []string) (*AccessKey, error) {
+	if err := hasher.Supports(spec); err != nil {
+		return nil, fmt.Errorf("check hash: %w", err)
+	}
 	if len(hosts) == 0 {
 		hosts = []string{"**"}
 	}
@@ -34,6 +41,8 @@ func NewAccessKey(hash []byte, hosts, paths []string) (*AccessKey, error) {
 	}
 
 	return &AccessKey{
+		hasher:    hasher,
+		spec:      spec,
 		hash:      hash,
 		hostGlobs: hostGlobs,
 		pathGlobs: pathGlobs,
@@ -41,12 +50,25 @@ func NewAccessKey(hash []byte, hosts, paths []string) (*AccessKey, error) {
 }
 
 type AccessKey struct {
+	hasher    *Hasher
+	spec      HashSpec
 	hash      []byte
 	hostGlobs []glob.Glob
 	pathGlobs []glob.Glob
 }
 
+// Valid reports whether the request is allowed and payload matches the hash.
 func (t *AccessKey) Valid(host, path string, payload []byte) bool {
+	return t.Allowed(host, path) && t.Matches(payload)
+}
+
+// Matches reports whether payload matches the hash.
+func (t *AccessKey) Matches(payload []byte) bool {
+	return t.hasher.Verify(t.spec, payload, t.hash)
+}
+
+// Allowed reports whether the host and path are allowed, regardless of the hash.
+func (t *AccessKey) Allowed(host, path string) bool {
 	if path == "" {
 		path = "/"
 	}
@@ -69,9 +91,5 @@ func (t *AccessKey) Valid(host, path string, payload []byte) bool {
 			break
 		}
 	}
-	if !pathMatch {
-		return false
-	}
-	hash := sha3.Sum384(payload)
-	return subtle.ConstantTimeCompare(hash[:], t.hash) == 1
+	return pathMatch
 }
@@ -1,6 +1,7 @@
 package types_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/reddec/token-login/internal/types"
@@ -120,3 +121,85 @@ type testKey struct {
 func (tk *testKey) String() string {
 	return tk.Secret.String()
 }
+
+func TestHeadersMerge(t *testing.T) {
+	base := types.Headers{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}
+	merged := base.Merge(types.Headers{{Name: "B", Value: "3"}, {Name: "C", Value: "4"}})
+	assert.Equal(t, types.Headers{{Name: "A", Value: "1"}, {Name: "B", Value: "3"}, {Name: "C", Value: "4"}}, merged)
+	assert.Equal(t, types.Headers{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}, base)
+}
+
+func TestValidateHeaders(t *testing.T) {
+	require.NoError(t, types.ValidateHeaders(types.Headers{
+		{Name: "X-Static", Value: "value"},
+		{Name: "X-Label", Value: "{{.Token.Label}}"},
+		{Name: "X-Tenant", Value: "{{.Token.Meta.tenant}}"},
+	}))
+	require.Error(t, types.ValidateHeaders(types.Headers{{Name: "X-Broken", Value: "{{.Token.Label"}}))
+	require.Error(t, types.ValidateHeaders(types.Headers{{Name: "X-Unknown", Value: "{{.Token.Unknown}}"}}))
+}
+
+func TestKeyFormat(t *testing.T) {
+	key, err := types.NewKey()
+	require.NoError(t, err)
+
+	t.Run("default prefix", func(t *testing.T) {
+		value := key.String()
+		assert.True(t, strings.HasPrefix(value, "tl_"))
+		parsed, err := types.ParseKey(value)
+		require.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	})
+
+	t.Run("custom prefix", func(t *testing.T) {
+		value := key.Format("acme")
+		assert.True(t, strings.HasPrefix(value, "acme_"))
+		parsed, err := types.ParseKey(value)
+		require.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	})
+
+	t.Run("legacy key", func(t *testing.T) {
+		raw := strings.Split(key.String(), "_")[1]
+		parsed, err := types.ParseKey(strings.ToLower(raw))
+		require.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	})
+
+	t.Run("typo is detected", func(t *testing.T) {
+		value := []byte(key.String())
+		if value[10] == 'A' {
+			value[10] = 'B'
+		} else {
+			value[10] = 'A'
+		}
+		_, err := types.ParseKey(string(value))
+		require.ErrorIs(t, err, types.ErrKeyChecksum)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := types.ParseKey("tl_" + strings.Repeat("A", 64))
+		require.Error(t, err)
+		_, err = types.ParseKey("_" + strings.Repeat("A", 64) + "_AAAAAAA")
+		require.Error(t, err)
+	})
+}
+
+func TestValidateKeyPrefix(t *testing.T) {
+	require.NoError(t, types.ValidateKeyPrefix("tl"))
+	require.NoError(t, types.ValidateKeyPrefix("acme2"))
+	require.ErrorIs(t, types.ValidateKeyPrefix(""), types.ErrKeyPrefix)
+	require.ErrorIs(t, types.ValidateKeyPrefix("Acme"), types.ErrKeyPrefix)
+	require.ErrorIs(t, types.ValidateKeyPrefix("my_app"), types.ErrKeyPrefix)
+	require.ErrorIs(t, types.ValidateKeyPrefix(strings.Repeat("a", 17)), types.ErrKeyPrefix)
+}
+
+func TestValidateImportedKey(t *testing.T) {
+	require.NoError(t, types.ValidateImportedKey("sk_live", "sk_live_secret"))
+	require.ErrorIs(t, types.ValidateImportedKey("sk", "sk_secret"), types.ErrImportedPrefix)
+	require.ErrorIs(t, types.ValidateImportedKey(strings.Repeat("a", 65), strings.Repeat("a", 70)), types.ErrImportedPrefix)
+	require.ErrorIs(t, types.ValidateImportedKey("sk_live", "sk_live"), types.ErrImportedKey)
+	require.ErrorIs(t, types.ValidateImportedKey("sk_live", "pk_live_secret"), types.ErrImportedKey)
+	require.ErrorIs(t, types.ValidateImportedKey("sk_live", "sk_live"+strings.Repeat("a", 512)), types.ErrImportedKey)
+	assert.NotEqual(t, types.ImportedKeyID("sk_live"), types.ImportedKeyID("sk_test"))
+}
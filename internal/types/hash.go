@@ -0,0 +1,197 @@
+package types
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HashAlgorithm identifies how key payload is hashed in storage.
+type HashAlgorithm string
+
+const (
+	// HashSHA3 is the original algorithm. Empty algorithm means the same.
+	HashSHA3   HashAlgorithm = "sha3-384"
+	HashSHA256 HashAlgorithm = "sha256"
+	// HashHMAC is keyed by server-side pepper, so stored hashes alone can't be used to check guessed keys.
+	HashHMAC HashAlgorithm = "hmac-sha256"
+)
+
+var (
+	ErrUnknownHash   = errors.New("unknown hash algorithm")
+	ErrPepperNeeded  = errors.New("hash algorithm requires pepper")
+	ErrUnknownPepper = errors.New("unknown pepper")
+	ErrPepperExists  = errors.New("duplicated pepper ID")
+)
+
+// DefaultHasher uses SHA3-384 and has no pepper.
+var DefaultHasher = &Hasher{preferred: HashSHA3} //nolint:gochecknoglobals
+
+// HashSpec describes how the stored hash was made. PepperID is set only for keyed algorithms.
+type HashSpec struct {
+	Algorithm HashAlgorithm
+	PepperID  string
+}
+
+// Keyed reports whether the algorithm uses pepper.
+func (alg HashAlgorithm) Keyed() bool {
+	return alg == HashHMAC
+}
+
+// Size of the hash in bytes.
+func (alg HashAlgorithm) Size() int {
+	switch alg.orDefault() {
+	case HashSHA256, HashHMAC:
+		return sha256.Size
+	default:
+		return 384 / 8 //nolint:mnd
+	}
+}
+
+// Pepper is a server-side secret for keyed hashes. ID is stored with every hash,
+// so peppers can be rotated: previous peppers are kept only to verify (and upgrade) existing hashes.
+type Pepper struct {
+	ID     string
+	Secret []byte
+}
+
+// ReadPepper reads pepper secret from the file. Surrounding whitespace is ignored.
+func ReadPepper(id, file string) (Pepper, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Pepper{}, fmt.Errorf("read pepper %q: %w", id, err)
+	}
+	secret := []byte(strings.TrimSpace(string(data)))
+	if len(secret) == 0 {
+		return Pepper{}, fmt.Errorf("read pepper %q: %w", id, ErrPepperNeeded)
+	}
+	return Pepper{ID: id, Secret: secret}, nil
+}
+
+// ReadPeppers reads all peppers from the directory: file name is pepper ID, content is secret.
+// Hidden files are skipped, which makes it compatible with Kubernetes secret mounts.
+func ReadPeppers(dir string) ([]Pepper, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read peppers dir: %w", err)
+	}
+	var out []Pepper
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.IsDir() {
+			continue
+		}
+		p, err := ReadPepper(entry.Name(), filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// NewHasher creates hasher which uses preferred algorithm (and current pepper, if keyed) for new hashes
+// and verifies hashes made by any supported algorithm with current or previous peppers.
+func NewHasher(preferred HashAlgorithm, current *Pepper, previous ...Pepper) (*Hasher, error) {
+	preferred = preferred.orDefault()
+	switch preferred {
+	case HashSHA3, HashSHA256, HashHMAC:
+	default:
+		return nil, fmt.Errorf("%q: %w", preferred, ErrUnknownHash)
+	}
+	if preferred.Keyed() && (current == nil || len(current.Secret) == 0) {
+		return nil, fmt.Errorf("%s: %w", preferred, ErrPepperNeeded)
+	}
+	h := &Hasher{preferred: preferred, peppers: make(map[string][]byte, len(previous)+1)}
+	if current != nil && len(current.Secret) > 0 {
+		h.current = current.ID
+		h.peppers[current.ID] = current.Secret
+	}
+	for _, p := range previous {
+		if secret, ok := h.peppers[p.ID]; ok {
+			if subtle.ConstantTimeCompare(secret, p.Secret) == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%q: %w", p.ID, ErrPepperExists)
+		}
+		h.peppers[p.ID] = p.Secret
+	}
+	return h, nil
+}
+
+type Hasher struct {
+	preferred HashAlgorithm
+	current   string
+	peppers   map[string][]byte
+}
+
+// Preferred spec for new hashes.
+func (h *Hasher) Preferred() HashSpec {
+	if h.preferred.Keyed() {
+		return HashSpec{Algorithm: h.preferred, PepperID: h.current}
+	}
+	return HashSpec{Algorithm: h.preferred}
+}
+
+// Outdated reports whether hash made by the spec should be upgraded to the preferred one.
+func (h *Hasher) Outdated(spec HashSpec) bool {
+	spec.Algorithm = spec.Algorithm.orDefault()
+	return spec != h.Preferred()
+}
+
+// Supports reports whether hashes made by the spec can be verified, for example
+// the pepper was not removed from configuration.
+func (h *Hasher) Supports(spec HashSpec) error {
+	switch spec.Algorithm.orDefault() {
+	case HashSHA3, HashSHA256:
+		return nil
+	case HashHMAC:
+		if _, ok := h.peppers[spec.PepperID]; !ok {
+			return fmt.Errorf("%q: %w", spec.PepperID, ErrUnknownPepper)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%q: %w", spec.Algorithm, ErrUnknownHash)
+	}
+}
+
+// Hash key payload according to the spec.
+func (h *Hasher) Hash(spec HashSpec, payload []byte) ([]byte, error) {
+	if err := h.Supports(spec); err != nil {
+		return nil, err
+	}
+	switch spec.Algorithm.orDefault() {
+	case HashSHA256:
+		s := sha256.Sum256(payload)
+		return s[:], nil
+	case HashHMAC:
+		mac := hmac.New(sha256.New, h.peppers[spec.PepperID])
+		mac.Write(payload)
+		return mac.Sum(nil), nil
+	default:
+		s := sha3.Sum384(payload)
+		return s[:], nil
+	}
+}
+
+// Verify reports in constant time whether payload matches the hash made by the spec.
+// Unsupported specs never match.
+func (h *Hasher) Verify(spec HashSpec, payload, hash []byte) bool {
+	expected, err := h.Hash(spec, payload)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(expected, hash) == 1
+}
+
+func (alg HashAlgorithm) orDefault() HashAlgorithm {
+	if alg == "" {
+		return HashSHA3
+	}
+	return alg
+}
@@ -0,0 +1,122 @@
+package types_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/types"
+)
+
+func TestHasher(t *testing.T) {
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	payload := key.Payload()
+	sha3 := types.HashSpec{Algorithm: types.HashSHA3}
+
+	hasher, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "1", Secret: []byte("pepper")})
+	require.NoError(t, err)
+	hmac := hasher.Preferred()
+	assert.Equal(t, types.HashSpec{Algorithm: types.HashHMAC, PepperID: "1"}, hmac)
+
+	for _, spec := range []types.HashSpec{sha3, {Algorithm: types.HashSHA256}, hmac} {
+		t.Run(string(spec.Algorithm), func(t *testing.T) {
+			hash, err := hasher.Hash(spec, payload)
+			require.NoError(t, err)
+			assert.True(t, hasher.Verify(spec, payload, hash))
+			assert.False(t, hasher.Verify(spec, payload[1:], hash))
+		})
+	}
+
+	t.Run("legacy hash", func(t *testing.T) {
+		assert.True(t, hasher.Verify(types.HashSpec{}, payload, key.Hash()))
+		assert.True(t, hasher.Verify(sha3, payload, key.Hash()))
+		assert.True(t, hasher.Outdated(types.HashSpec{}))
+		assert.False(t, hasher.Outdated(hmac))
+		assert.False(t, types.DefaultHasher.Outdated(types.HashSpec{}))
+	})
+
+	t.Run("pepper matters", func(t *testing.T) {
+		other, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "1", Secret: []byte("other")})
+		require.NoError(t, err)
+		hash, err := hasher.Hash(hmac, payload)
+		require.NoError(t, err)
+		assert.False(t, other.Verify(hmac, payload, hash))
+	})
+
+	t.Run("hmac without pepper", func(t *testing.T) {
+		_, err := types.NewHasher(types.HashHMAC, nil)
+		require.ErrorIs(t, err, types.ErrPepperNeeded)
+		hash, err := hasher.Hash(hmac, payload)
+		require.NoError(t, err)
+		assert.False(t, types.DefaultHasher.Verify(hmac, payload, hash))
+		require.ErrorIs(t, types.DefaultHasher.Supports(hmac), types.ErrUnknownPepper)
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		_, err := types.NewHasher("md5", nil)
+		require.ErrorIs(t, err, types.ErrUnknownHash)
+		assert.False(t, hasher.Verify(types.HashSpec{Algorithm: "md5"}, payload, key.Hash()))
+	})
+}
+
+func TestHasherPepperRotation(t *testing.T) {
+	key, err := types.NewKey()
+	require.NoError(t, err)
+	payload := key.Payload()
+
+	old := types.Pepper{ID: "2025", Secret: []byte("old secret")}
+	before, err := types.NewHasher(types.HashHMAC, &old)
+	require.NoError(t, err)
+	oldSpec := before.Preferred()
+	hash, err := before.Hash(oldSpec, payload)
+	require.NoError(t, err)
+
+	after, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "2026", Secret: []byte("new secret")}, old)
+	require.NoError(t, err)
+	assert.Equal(t, "2026", after.Preferred().PepperID)
+	assert.True(t, after.Verify(oldSpec, payload, hash))
+	assert.True(t, after.Outdated(oldSpec))
+
+	t.Run("current pepper may be listed again", func(t *testing.T) {
+		_, err := types.NewHasher(types.HashHMAC, &old, old)
+		require.NoError(t, err)
+	})
+
+	t.Run("conflicting pepper", func(t *testing.T) {
+		_, err := types.NewHasher(types.HashHMAC, &old, types.Pepper{ID: old.ID, Secret: []byte("other")})
+		require.ErrorIs(t, err, types.ErrPepperExists)
+	})
+
+	t.Run("removed pepper", func(t *testing.T) {
+		dropped, err := types.NewHasher(types.HashHMAC, &types.Pepper{ID: "2026", Secret: []byte("new secret")})
+		require.NoError(t, err)
+		assert.False(t, dropped.Verify(oldSpec, payload, hash))
+		_, err = types.NewHashedAccessKey(dropped, oldSpec, hash, nil, nil)
+		require.ErrorIs(t, err, types.ErrUnknownPepper)
+	})
+}
+
+func TestReadPeppers(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1"), []byte("first\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2"), []byte("  second  "), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("skip"), 0o600))
+
+	list, err := types.ReadPeppers(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Pepper{
+		{ID: "1", Secret: []byte("first")},
+		{ID: "2", Secret: []byte("second")},
+	}, list)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "3"), []byte("\n"), 0o600))
+	_, err = types.ReadPeppers(dir)
+	require.ErrorIs(t, err, types.ErrPepperNeeded)
+
+	_, err = types.ReadPepper("x", filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
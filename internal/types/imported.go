@@ -0,0 +1,69 @@
+package types
+
+import (
+	"crypto/sha3"
+	"errors"
+	"fmt"
+)
+
+// KeyFormat describes how a key presented by a client maps to the key ID and the hashed payload.
+type KeyFormat string
+
+const (
+	// KeyNative is a key generated by the service (see Key): the key ID is
+	// embedded into the key and only the private part is hashed.
+	KeyNative KeyFormat = "native"
+	// KeyImported is an arbitrary key from another system: the key ID is derived
+	// from the public prefix (see ImportedKeyID) and the whole key is hashed.
+	KeyImported KeyFormat = "imported"
+)
+
+const (
+	MinImportedPrefixSize = 4
+	MaxImportedPrefixSize = 64
+	MaxImportedKeySize    = 512
+)
+
+var (
+	ErrImportedPrefix = fmt.Errorf("imported key prefix should be %d-%d bytes", MinImportedPrefixSize, MaxImportedPrefixSize)
+	ErrImportedKey    = fmt.Errorf("imported key should start with its prefix, have a secret part and be up to %d bytes", MaxImportedKeySize)
+	errKeyFormat      = errors.New("unknown key format")
+)
+
+// Validate returns an error for unknown formats. Empty format is treated as native.
+func (f KeyFormat) Validate() error {
+	switch f {
+	case "", KeyNative, KeyImported:
+		return nil
+	default:
+		return fmt.Errorf("%q: %w", f, errKeyFormat)
+	}
+}
+
+// ImportedKeyID derives the key ID of an imported key from its public prefix.
+func ImportedKeyID(prefix string) KeyID {
+	sum := sha3.Sum256([]byte(prefix))
+	var kid KeyID
+	copy(kid[:], sum[:])
+	return kid
+}
+
+// ValidateImportedPrefix checks that the public prefix of an imported key is usable.
+func ValidateImportedPrefix(prefix string) error {
+	if len(prefix) < MinImportedPrefixSize || len(prefix) > MaxImportedPrefixSize {
+		return ErrImportedPrefix
+	}
+	return nil
+}
+
+// ValidateImportedKey checks that the raw imported key starts with the prefix
+// and has a non-empty secret part after it.
+func ValidateImportedKey(prefix, raw string) error {
+	if err := ValidateImportedPrefix(prefix); err != nil {
+		return err
+	}
+	if len(raw) <= len(prefix) || len(raw) > MaxImportedKeySize || raw[:len(prefix)] != prefix {
+		return ErrImportedKey
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// HeaderData is the data available in templated header values, for example
+// {{.Token.Label}}, {{.Project.Slug}} or {{.Request.Host}}.
+type HeaderData struct {
+	Token   TemplateToken
+	Project TemplateProject
+	Request TemplateRequest
+}
+
+type TemplateToken struct {
+	ID    int64
+	KeyID string
+	Label string
+	User  string
+	Meta  map[string]string
+}
+
+type TemplateProject struct {
+	ID   int64
+	Slug string
+}
+
+type TemplateRequest struct {
+	Host   string
+	Path   string
+	Method string
+}
+
+// HeaderTemplate is a header with precompiled value.
+// Values without template actions are kept as-is.
+type HeaderTemplate struct {
+	Name  string
+	value string
+	tpl   *template.Template
+}
+
+type HeaderTemplates []HeaderTemplate
+
+// CompileHeaders parses header values as text templates.
+func CompileHeaders(headers Headers) (HeaderTemplates, error) {
+	out := make(HeaderTemplates, 0, len(headers))
+	for _, h := range headers {
+		ht := HeaderTemplate{Name: h.Name, value: h.Value}
+		if strings.Contains(h.Value, "{{") {
+			tpl, err := template.New(h.Name).Option("missingkey=zero").Parse(h.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parse header %q: %w", h.Name, err)
+			}
+			ht.tpl = tpl
+		}
+		out = append(out, ht)
+	}
+	return out, nil
+}
+
+// ValidateHeaders checks that header templates can be parsed and executed,
+// which catches references to unknown fields.
+func ValidateHeaders(headers Headers) error {
+	compiled, err := CompileHeaders(headers)
+	if err != nil {
+		return err
+	}
+	_, err = compiled.Render(&HeaderData{})
+	return err
+}
+
+// Render executes templates and returns headers with final values.
+func (hts HeaderTemplates) Render(data *HeaderData) (Headers, error) {
+	out := make(Headers, 0, len(hts))
+	for _, ht := range hts {
+		value, err := ht.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Header{Name: ht.Name, Value: value})
+	}
+	return out, nil
+}
+
+func (ht *HeaderTemplate) Render(data *HeaderData) (string, error) {
+	if ht.tpl == nil {
+		return ht.value, nil
+	}
+	var buf strings.Builder
+	if err := ht.tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render header %q: %w", ht.Name, err)
+	}
+	return buf.String(), nil
+}
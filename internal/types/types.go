@@ -5,10 +5,13 @@ import (
 	"crypto/sha3"
 	"database/sql/driver"
 	"encoding/base32"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"slices"
 	"strings"
 )
 
@@ -16,12 +19,19 @@ const (
 	keyDataSize = 32 // private part
 	KeyIDSize   = 8  // public part
 	TokenSize   = KeyIDSize + keyDataSize
+	// DefaultKeyPrefix is used for formatted keys unless the instance overrides it.
+	DefaultKeyPrefix = "tl"
+	keySeparator     = "_"
+	maxKeyPrefixSize = 16
 )
 
 var (
-	ErrKeySize    = errors.New("key size invalid")
-	errCannotScan = errors.New("cannot scan into Headers")
-	errKeyIDType  = errors.New("key ID type should be text")
+	ErrKeySize      = errors.New("key size invalid")
+	ErrKeyChecksum  = errors.New("key checksum mismatch")
+	ErrKeyPrefix    = errors.New("key prefix should be 1-16 lowercase letters or digits")
+	errKeyMalformed = errors.New("key should be <prefix>_<data>_<checksum>")
+	errCannotScan   = errors.New("cannot scan into Headers")
+	errKeyIDType    = errors.New("key ID type should be text")
 )
 
 type Header struct {
@@ -49,6 +59,18 @@ func (headers Headers) Without(name string) Headers {
 	return ans
 }
 
+// Merge returns a copy of headers with overrides applied on top: headers with
+// the same name are replaced, new ones are appended.
+func (headers Headers) Merge(overrides Headers) Headers {
+	ans := make(Headers, 0, len(headers)+len(overrides))
+	for _, h := range headers {
+		if !slices.ContainsFunc(overrides, func(o Header) bool { return o.Name == h.Name }) {
+			ans = append(ans, h)
+		}
+	}
+	return append(ans, overrides...)
+}
+
 // Scan implements sql.Scanner for JSON headers stored as JSON/JSONB.
 func (headers *Headers) Scan(src any) error {
 	if src == nil {
@@ -82,6 +104,42 @@ func (headers Headers) Value() (driver.Value, error) {
 	return data, nil
 }
 
+// Meta is arbitrary key/value metadata stored as JSON object.
+type Meta map[string]string
+
+// Scan implements sql.Scanner for JSON metadata stored as JSON/JSONB.
+func (meta *Meta) Scan(src any) error {
+	if src == nil {
+		*meta = nil
+		return nil
+	}
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Meta: %w", src, errCannotScan)
+	}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return fmt.Errorf("unmarshal meta: %w", err)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer for JSON metadata.
+func (meta Meta) Value() (driver.Value, error) {
+	if meta == nil {
+		return []byte("{}"), nil
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("marshal meta: %w", err)
+	}
+	return data, nil
+}
+
 func NewKey() (Key, error) {
 	var key Key
 	if _, err := io.ReadFull(rand.Reader, key[:]); err != nil {
@@ -90,7 +148,52 @@ func NewKey() (Key, error) {
 	return key, nil
 }
 
+// NewKeyFor generates key with the given key ID, for example to issue a new secret for
+// a token restored without its hash.
+func NewKeyFor(kid KeyID) (Key, error) {
+	var key Key
+	copy(key[:], kid[:])
+	if _, err := io.ReadFull(rand.Reader, key[KeyIDSize:]); err != nil {
+		return key, fmt.Errorf("read key random data: %w", err)
+	}
+	return key, nil
+}
+
+// ParseKey parses formatted (<prefix>_<data>_<checksum>) or legacy (bare base32) key.
+// The checksum of formatted keys is verified, so typos are detected before any lookup.
+// Any prefix is accepted, so keys survive changes of the instance prefix.
 func ParseKey(value string) (key Key, err error) {
+	if !strings.Contains(value, keySeparator) {
+		return parseRawKey(value)
+	}
+	parts := strings.Split(value, keySeparator)
+	if len(parts) != 3 || parts[0] == "" {
+		return key, errKeyMalformed
+	}
+	key, err = parseRawKey(parts[1])
+	if err != nil {
+		return key, err
+	}
+	if !strings.EqualFold(parts[2], key.checksum()) {
+		return key, ErrKeyChecksum
+	}
+	return key, nil
+}
+
+// ValidateKeyPrefix checks that prefix can be used in formatted keys.
+func ValidateKeyPrefix(prefix string) error {
+	if prefix == "" || len(prefix) > maxKeyPrefixSize {
+		return ErrKeyPrefix
+	}
+	for _, c := range prefix {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return ErrKeyPrefix
+		}
+	}
+	return nil
+}
+
+func parseRawKey(value string) (key Key, err error) {
 	data, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(value))
 	if err != nil {
 		return key, fmt.Errorf("parse: %w", err)
@@ -114,10 +217,27 @@ func (rt Key) Payload() []byte {
 	return rt[KeyIDSize:]
 }
 
+// String returns key formatted with DefaultKeyPrefix.
 func (rt Key) String() string {
+	return rt.Format(DefaultKeyPrefix)
+}
+
+// Format returns key as <prefix>_<data>_<checksum>, which is recognizable by secret scanners.
+func (rt Key) Format(prefix string) string {
+	return prefix + keySeparator + rt.raw() + keySeparator + rt.checksum()
+}
+
+func (rt Key) raw() string {
 	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(rt[:])
 }
 
+// checksum is CRC32 (IEEE) of the key, base32 encoded.
+func (rt Key) checksum() string {
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(rt[:]))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:])
+}
+
 func (rt Key) Hash() []byte {
 	s := sha3.Sum384(rt.Payload())
 	return s[:]
@@ -129,6 +249,12 @@ func (rt Key) AccessKey(hosts, paths []string) (*AccessKey, error) {
 
 type KeyID [KeyIDSize]byte
 
+// ParseKeyID parses public key ID, for example from X-Token-Hint header. Case is ignored.
+func ParseKeyID(value string) (kid KeyID, err error) {
+	err = kid.UnmarshalText([]byte(strings.ToUpper(value)))
+	return
+}
+
 func (kid *KeyID) UnmarshalText(text []byte) error {
 	str := string(text)
 	data, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(str)
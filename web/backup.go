@@ -0,0 +1,62 @@
+package web
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/reddec/token-login/internal/utils"
+)
+
+// DatabaseBackuper writes a consistent copy of the database to a file.
+type DatabaseBackuper interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// BackupHandler streams a consistent copy of the database to the allowed users.
+// The copy has all users' tokens (hashes only), so nobody is allowed by default.
+func BackupHandler(backuper DatabaseBackuper, users []string) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !slices.Contains(users, utils.GetUser(request.Context())) {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+		dir, err := os.MkdirTemp("", "token-login-backup-")
+		if err != nil {
+			slog.Error("failed create backup directory", "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "backup.sqlite")
+		if err := backuper.Backup(request.Context(), path); err != nil {
+			slog.Error("failed backup database", "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			slog.Error("failed open backup", "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+
+		name := "token-login-" + time.Now().UTC().Format("20060102T150405Z") + ".sqlite"
+		writer.Header().Set("Content-Type", "application/vnd.sqlite3")
+		writer.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		if info, err := f.Stat(); err == nil {
+			http.ServeContent(writer, request, name, info.ModTime(), f)
+			return
+		}
+		if _, err := io.Copy(writer, f); err != nil {
+			slog.Debug("failed stream backup", "error", err)
+		}
+	})
+}
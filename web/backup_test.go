@@ -0,0 +1,41 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/utils"
+	"github.com/reddec/token-login/web"
+)
+
+type fakeBackuper struct{}
+
+func (fakeBackuper) Backup(_ context.Context, path string) error {
+	return os.WriteFile(path, []byte("SQLite format 3"), 0o600)
+}
+
+func TestBackupHandler(t *testing.T) {
+	handler := web.BackupHandler(fakeBackuper{}, []string{"admin"})
+
+	get := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(utils.WithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("admin")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "SQLite format 3", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), `attachment; filename="token-login-`)
+
+	assert.Equal(t, http.StatusForbidden, get("alice").Code)
+	assert.Equal(t, http.StatusForbidden, get("").Code)
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/reddec/token-login/internal/cache"
+	"github.com/reddec/token-login/internal/types"
+)
+
+const (
+	maxLeakReportSize  = 1 << 20
+	maxLeakReportItems = 20
+	// LeakTokenType is the token type in leak reports and their responses.
+	LeakTokenType = "token_login_key"
+)
+
+// LeakReporter disables tokens of publicly exposed keys.
+type LeakReporter interface {
+	ReportLeak(ctx context.Context, key types.Key, source string) (bool, error)
+}
+
+// LeakReport is a publicly exposed key, in the format of GitHub secret scanning partner alerts.
+type LeakReport struct {
+	Token  string `json:"token"`
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
+// LeakLabel is a verdict for reported key: true_positive for known keys (which are now disabled)
+// and false_positive for everything else.
+type LeakLabel struct {
+	TokenRaw  string `json:"token_raw"`
+	TokenType string `json:"token_type"`
+	Label     string `json:"label"`
+}
+
+// LeakReportHandler accepts a JSON array of LeakReport and disables tokens of matched keys.
+// The handler is intentionally unauthenticated: anyone who found a key can revoke it,
+// and nothing is revealed except whether the key was valid.
+// Keys are looked up in the cache first, so only keys of known tokens reach the database.
+func LeakReportHandler(state *cache.Cache, reporter LeakReporter) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var reports []LeakReport
+		if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxLeakReportSize)).Decode(&reports); err != nil {
+			slog.Debug("failed decode leak report", "error", err)
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(reports) > maxLeakReportItems {
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		labels := make([]LeakLabel, 0, len(reports))
+		for _, report := range reports {
+			label := LeakLabel{TokenRaw: report.Token, TokenType: report.Type, Label: "false_positive"}
+			if label.TokenType == "" {
+				label.TokenType = LeakTokenType
+			}
+			if key, ok := knownKey(state, report.Token); ok {
+				found, err := reporter.ReportLeak(request.Context(), key, leakSource(report))
+				if err != nil {
+					slog.Error("failed process leak report", "key", key.ID(), "error", err)
+					writer.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if found {
+					slog.Warn("leaked key reported", "key", key.ID(), "source", report.Source, "url", report.URL)
+					label.Label = "true_positive"
+				}
+			}
+			labels = append(labels, label)
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(labels)
+	})
+}
+
+func leakSource(report LeakReport) string {
+	return strings.TrimSpace(strings.Join([]string{report.Source, report.URL}, " "))
+}
+
+// knownKey parses the key and reports whether it belongs to a cached token.
+func knownKey(state *cache.Cache, raw string) (types.Key, bool) {
+	key, err := types.ParseKey(strings.TrimSpace(raw))
+	if err != nil {
+		return key, false
+	}
+	_, ok := state.FindByKey(key)
+	return key, ok
+}
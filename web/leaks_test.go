@@ -0,0 +1,110 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reddec/token-login/internal/cache"
+	"github.com/reddec/token-login/internal/dbo"
+	"github.com/reddec/token-login/internal/types"
+	"github.com/reddec/token-login/web"
+)
+
+type fakeReporter struct {
+	known   types.Key
+	sources []string
+	err     error
+}
+
+func (f *fakeReporter) ReportLeak(_ context.Context, key types.Key, source string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	f.sources = append(f.sources, source)
+	return key == f.known, nil
+}
+
+// cacheOf returns cache with tokens of the keys.
+func cacheOf(t *testing.T, keys ...types.Key) *cache.Cache {
+	t.Helper()
+	state := make(cache.State)
+	for i, key := range keys {
+		kid := key.ID()
+		token, err := cache.NewToken(&dbo.Token{ID: int64(i + 1), User: "testuser", KeyID: &kid, Hash: key.Hash()}, types.DefaultHasher)
+		require.NoError(t, err)
+		state[kid] = append(state[kid], token)
+	}
+	c := cache.New(nil)
+	c.Set(state)
+	return c
+}
+
+func TestLeakReportHandler(t *testing.T) {
+	known, err := types.NewKey()
+	require.NoError(t, err)
+	unknown, err := types.NewKey()
+	require.NoError(t, err)
+
+	reporter := &fakeReporter{known: known}
+	handler := web.LeakReportHandler(cacheOf(t, known), reporter)
+
+	body, err := json.Marshal([]web.LeakReport{
+		{Token: known.String(), Type: "token_login_key", URL: "https://github.com/acme/app/blob/main/.env", Source: "content"},
+		{Token: unknown.String(), Type: "token_login_key"},
+		{Token: "garbage"},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var labels []web.LeakLabel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &labels))
+	require.Len(t, labels, 3)
+	assert.Equal(t, web.LeakLabel{TokenRaw: known.String(), TokenType: "token_login_key", Label: "true_positive"}, labels[0])
+	assert.Equal(t, "false_positive", labels[1].Label)
+	assert.Equal(t, web.LeakLabel{TokenRaw: "garbage", TokenType: web.LeakTokenType, Label: "false_positive"}, labels[2])
+	assert.Equal(t, []string{"content https://github.com/acme/app/blob/main/.env"}, reporter.sources, "unknown keys don't reach the reporter")
+}
+
+func TestLeakReportHandlerRejects(t *testing.T) {
+	handler := web.LeakReportHandler(cacheOf(t), &fakeReporter{})
+
+	t.Run("method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{")))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("too many items", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := "[" + strings.Repeat(`{"token":"x"},`, 20) + `{"token":"x"}]`
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("reporter failure", func(t *testing.T) {
+		key, err := types.NewKey()
+		require.NoError(t, err)
+		handler := web.LeakReportHandler(cacheOf(t, key), &fakeReporter{err: errors.New("db down")})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"token":"`+key.String()+`"}]`)))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -14,6 +14,7 @@ const (
 	URLHeader           = `X-Forwarded-Uri`
 	TokenHeader         = `X-Token`
 	HostHeader          = "X-Forwarded-Host"
+	MethodHeader        = "X-Forwarded-Method"
 	TokenQuery          = `token`
 	ProjectQuery        = `project`
 	AuthUserHeader      = `X-User`
@@ -35,16 +36,15 @@ func AuthHandler(state *cache.Cache, accessLog chan<- Hit) http.Handler {
 		}
 		rawKey := getToken(request, requestURL)
 		host := getHost(request)
-		key, err := types.ParseKey(rawKey)
-		if err != nil {
-			slog.Debug("failed parse key", "error", err)
+		token, payload, found := findToken(state, rawKey)
+		if !found {
 			writer.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		kid := *token.DBToken.KeyID
 
-		token, found := state.FindByKey(key.ID())
-		if !found {
-			slog.Debug("token not found", "key", key.ID())
+		if token.DBToken.Disabled() {
+			slog.Debug("token disabled", "key", kid)
 			writer.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -53,21 +53,47 @@ func AuthHandler(state *cache.Cache, accessLog chan<- Hit) http.Handler {
 		// NOTE: project filtering is done in-memory after cache lookup.
 		// For large deployments with many projects, consider pushing this
 		// filter to the DB/cache layer to avoid loading all tokens.
-		if token.DBToken.ProjectSlug != projectSlug {
-			slog.Debug("project mismatch", "key", key.ID(), "expected", projectSlug, "actual", token.DBToken.ProjectSlug)
+		project, ok := token.DBToken.MatchProject(projectSlug)
+		if !ok {
+			slog.Debug("project mismatch", "key", kid, "expected", projectSlug, "actual", token.DBToken.ProjectSlug)
 			writer.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		if ok := token.AccessKey.Valid(host, requestURL.Path, key.Payload()); !ok {
-			slog.Debug("access key invalid", "key", key.ID())
+		// hash is already verified by the cache lookup
+		if ok := token.AccessKey.Allowed(host, requestURL.Path); !ok {
+			slog.Debug("access key invalid", "key", kid)
 			writer.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		state.ScheduleRehash(token, payload)
+		injected, err := token.Headers.Render(&types.HeaderData{
+			Token: types.TemplateToken{
+				ID:    token.DBToken.ID,
+				KeyID: kid.String(),
+				Label: token.DBToken.Label,
+				User:  token.DBToken.User,
+				Meta:  token.DBToken.Meta,
+			},
+			Project: types.TemplateProject{
+				ID:   project.ID,
+				Slug: project.Slug,
+			},
+			Request: types.TemplateRequest{
+				Host:   host,
+				Path:   requestURL.Path,
+				Method: request.Header.Get(MethodHeader),
+			},
+		})
+		if err != nil {
+			slog.Warn("failed render headers", "key", kid, "error", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		headers := writer.Header()
 		headers.Set(AuthUserHeader, token.DBToken.User)
-		headers.Set(AuthTokenHintHeader, key.ID().String())
-		for _, header := range token.DBToken.Headers {
+		headers.Set(AuthTokenHintHeader, kid.String())
+		for _, header := range injected {
 			headers.Set(header.Name, header.Value)
 		}
 		writer.WriteHeader(http.StatusNoContent)
@@ -78,6 +104,24 @@ func AuthHandler(state *cache.Cache, accessLog chan<- Hit) http.Handler {
 	})
 }
 
+// findToken looks up the token by native key first, then by imported key.
+// It returns the hashed part of the key along with the token.
+func findToken(state *cache.Cache, rawKey string) (*cache.Token, []byte, bool) {
+	key, err := types.ParseKey(rawKey)
+	if err == nil {
+		if token, found := state.FindByKey(key); found {
+			return token, key.Payload(), true
+		}
+		slog.Debug("token not found", "key", key.ID())
+	} else {
+		slog.Debug("failed parse key", "error", err)
+	}
+	if token, found := state.FindImported(rawKey); found {
+		return token, []byte(rawKey), true
+	}
+	return nil, nil, false
+}
+
 func getToken(req *http.Request, sourceURL *url.URL) string {
 	if apiKey := req.Header.Get(TokenHeader); apiKey != "" {
 		return apiKey
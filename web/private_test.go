@@ -30,25 +30,24 @@ func setupToken(t *testing.T, host, path string, headers types.Headers, projectS
 	if path == "" {
 		paths = nil
 	}
-	ak, err := types.NewAccessKey(key.Hash(), hosts, paths)
-	require.NoError(t, err)
-
 	dbToken := &dbo.Token{
 		ID:          1,
 		User:        "testuser",
+		Label:       "test-label",
 		KeyID:       func() *types.KeyID { k := key.ID(); return &k }(),
+		Hash:        key.Hash(),
 		Hosts:       hosts,
 		Paths:       paths,
 		Headers:     headers,
+		ProjectID:   7,
 		ProjectSlug: projectSlug,
 	}
+	token, err := cache.NewToken(dbToken, types.DefaultHasher)
+	require.NoError(t, err)
 
 	c := cache.New(nil)
 	c.Set(cache.State{
-		key.ID(): {
-			AccessKey: ak,
-			DBToken:   dbToken,
-		},
+		key.ID(): {token},
 	})
 
 	accessLog := make(chan web.Hit, 1)
@@ -271,6 +270,64 @@ func TestAuthHandlerExplicitProjectMatch(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 }
 
+func TestAuthHandlerProjectAlias(t *testing.T) {
+	// Token project was renamed from "legacy" to "myapp", request uses old slug → match
+	c, rawKey, accessLog := setupToken(t, "", "", nil, "myapp")
+	key, err := types.ParseKey(rawKey)
+	require.NoError(t, err)
+	token, ok := c.FindByKey(key)
+	require.True(t, ok)
+	token.DBToken.ProjectAliases = []string{"legacy"}
+
+	handler := web.AuthHandler(c, accessLog)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(web.URLHeader, "/api/test?project=legacy")
+	req.Header.Set(web.TokenHeader, rawKey)
+	req.Header.Set(web.HostHeader, "example.com")
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestAuthHandlerLinkedProject(t *testing.T) {
+	// Token belongs to "myapp" and is additionally valid for "shared" (renamed from "legacy")
+	c, rawKey, accessLog := setupToken(t, "", "", nil, "myapp")
+	key, err := types.ParseKey(rawKey)
+	require.NoError(t, err)
+	token, ok := c.FindByKey(key)
+	require.True(t, ok)
+	token.DBToken.LinkedProjects = []dbo.ProjectRef{{ID: 42, Slug: "shared", Aliases: []string{"legacy"}}}
+
+	handler := web.AuthHandler(c, accessLog)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	for project, status := range map[string]int{
+		"myapp":  http.StatusNoContent,
+		"shared": http.StatusNoContent,
+		"legacy": http.StatusNoContent,
+		"other":  http.StatusUnauthorized,
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set(web.URLHeader, "/api/test?project="+project)
+		req.Header.Set(web.TokenHeader, rawKey)
+		req.Header.Set(web.HostHeader, "example.com")
+
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, project)
+	}
+}
+
 func TestAuthHandlerProjectMismatch(t *testing.T) {
 	// Token belongs to default project, request specifies ?project=myapp → mismatch
 	c, rawKey, accessLog := setupToken(t, "", "", nil, "")
@@ -318,6 +375,43 @@ func TestAuthHandlerCustomHeaders(t *testing.T) {
 	assert.Equal(t, "another-value", resp.Header.Get("X-Another"))
 }
 
+func TestAuthHandlerTemplatedHeaders(t *testing.T) {
+	headers := types.Headers{
+		{Name: "X-Token-Label", Value: "{{.Token.Label}}"},
+		{Name: "X-Token-ID", Value: "token-{{.Token.ID}}"},
+		{Name: "X-Project", Value: "{{.Project.ID}}:{{.Project.Slug}}"},
+		{Name: "X-Host", Value: "{{.Request.Host}}{{.Request.Path}}"},
+		{Name: "X-Tenant", Value: "{{.Token.Meta.tenant}}"},
+	}
+	c, rawKey, accessLog := setupToken(t, "", "", headers, "myapp")
+	key, err := types.ParseKey(rawKey)
+	require.NoError(t, err)
+	token, ok := c.FindByKey(key)
+	require.True(t, ok)
+	token.DBToken.Meta = types.Meta{"tenant": "acme"}
+
+	handler := web.AuthHandler(c, accessLog)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(web.URLHeader, "/api/test?project=myapp")
+	req.Header.Set(web.TokenHeader, rawKey)
+	req.Header.Set(web.HostHeader, "example.com")
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "test-label", resp.Header.Get("X-Token-Label"))
+	assert.Equal(t, "token-1", resp.Header.Get("X-Token-ID"))
+	assert.Equal(t, "7:myapp", resp.Header.Get("X-Project"))
+	assert.Equal(t, "example.com/api/test", resp.Header.Get("X-Host"))
+	assert.Equal(t, "acme", resp.Header.Get("X-Tenant"))
+}
+
 func TestAuthHandlerAccessLogOverflow(t *testing.T) {
 	// Create a channel with buffer size 0 to test non-blocking send
 	c, rawKey, _ := setupToken(t, "", "", nil, "")
@@ -341,3 +435,112 @@ func TestAuthHandlerAccessLogOverflow(t *testing.T) {
 
 	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 }
+
+func TestAuthHandlerKeyIDCollision(t *testing.T) {
+	first, err := types.NewKey()
+	require.NoError(t, err)
+	// same key ID, different payload
+	second := first
+	second[len(second)-1] ^= 0xFF
+
+	newToken := func(id int64, user string, key types.Key) *cache.Token {
+		kid := key.ID()
+		token, err := cache.NewToken(&dbo.Token{
+			ID:    id,
+			User:  user,
+			KeyID: &kid,
+			Hash:  key.Hash(),
+		}, types.DefaultHasher)
+		require.NoError(t, err)
+		return token
+	}
+
+	c := cache.New(nil)
+	c.Set(cache.State{
+		first.ID(): {newToken(1, "alice", first), newToken(2, "bob", second)},
+	})
+	accessLog := make(chan web.Hit, 1)
+	srv := httptest.NewServer(web.AuthHandler(c, accessLog))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		key  types.Key
+		user string
+		id   int64
+	}{{first, "alice", 1}, {second, "bob", 2}} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set(web.URLHeader, "/api/test")
+		req.Header.Set(web.TokenHeader, tc.key.String())
+
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, tc.user, resp.Header.Get(web.AuthUserHeader))
+		hit := <-accessLog
+		assert.Equal(t, tc.id, hit.ID)
+	}
+
+	c.Drop(1)
+	token, ok := c.FindByKey(second)
+	require.True(t, ok)
+	assert.Equal(t, "bob", token.DBToken.User)
+	_, ok = c.FindByKey(first)
+	assert.False(t, ok)
+}
+
+func TestAuthHandlerImportedKey(t *testing.T) {
+	newToken := func(id int64, prefix, raw string) *cache.Token {
+		kid := types.ImportedKeyID(prefix)
+		hash, err := types.DefaultHasher.Hash(types.HashSpec{Algorithm: types.HashSHA3}, []byte(raw))
+		require.NoError(t, err)
+		token, err := cache.NewToken(&dbo.Token{
+			ID:        id,
+			User:      "legacy",
+			KeyID:     &kid,
+			Hash:      hash,
+			HashAlg:   types.HashSHA3,
+			KeyFormat: types.KeyImported,
+			KeyPrefix: prefix,
+		}, types.DefaultHasher)
+		require.NoError(t, err)
+		return token
+	}
+
+	c := cache.New(nil)
+	short := newToken(1, "sk_live_abc", "sk_live_abcSECRET")
+	long := newToken(2, "pk-0123456789", "pk-0123456789.other-secret")
+	c.Set(cache.State{
+		types.ImportedKeyID("sk_live_abc"):   {short},
+		types.ImportedKeyID("pk-0123456789"): {long},
+	})
+	accessLog := make(chan web.Hit, 1)
+	srv := httptest.NewServer(web.AuthHandler(c, accessLog))
+	defer srv.Close()
+
+	check := func(rawKey string, expected int) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set(web.URLHeader, "/api/test")
+		req.Header.Set(web.TokenHeader, rawKey)
+
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, expected, resp.StatusCode, rawKey)
+		return resp
+	}
+
+	resp := check("sk_live_abcSECRET", http.StatusNoContent)
+	assert.Equal(t, types.ImportedKeyID("sk_live_abc").String(), resp.Header.Get(web.AuthTokenHintHeader))
+	assert.Equal(t, int64(1), (<-accessLog).ID)
+
+	check("pk-0123456789.other-secret", http.StatusNoContent)
+	assert.Equal(t, int64(2), (<-accessLog).ID)
+
+	check("sk_live_abcWRONG", http.StatusUnauthorized)
+	check("sk_live_abc", http.StatusUnauthorized)
+	check("pk-0123456789.other-secret!", http.StatusUnauthorized)
+}